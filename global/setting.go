@@ -12,6 +12,7 @@ var (
 	DatabaseSetting *setting.DatabaseSetting
 	JWTSetting      *setting.JWTSettingS
 	EmailSetting    *setting.EmailSettingS
+	S3Setting       *setting.S3SettingS
 )
 
 // 日志配置对象
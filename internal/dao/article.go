@@ -0,0 +1,211 @@
+package dao
+
+import (
+	"github.com/go-programming-tour/blog-service/internal/model"
+	"github.com/go-programming-tour/blog-service/pkg/app"
+)
+
+// 文章的入参，字段较多时用结构体代替参数列表
+type Article struct {
+	ID            uint32
+	Title         string
+	Slug          string
+	Desc          string
+	Content       string
+	CoverImageUrl string
+	CreatedBy     string
+	ModifiedBy    string
+	State         uint8
+}
+
+// 返回 Article 的数量，tagID 大于 0 时只统计关联了该标签的文章，createdBy 不为空时只统计该用户创建的文章
+func (d *Dao) CountArticle(title string, state uint8, tagID uint32, createdBy string) (int, error) {
+	article := model.Article{Title: title, State: state, Common: &model.Common{CreatedBy: createdBy}}
+	if tagID > 0 {
+		return article.CountByTagID(d.engine, tagID)
+	}
+
+	return article.Count(d.engine)
+}
+
+// 返回 Article 的分页数据，tagID 大于 0 时只返回关联了该标签的文章，createdBy 不为空时只返回该用户创建的文章
+func (d *Dao) GetArticleList(title string, state uint8, tagID uint32, createdBy string, page, pageSize int) ([]*model.Article, error) {
+	article := model.Article{Title: title, State: state, Common: &model.Common{CreatedBy: createdBy}}
+
+	pageOffset := app.GetPageOffset(page, pageSize)
+	if tagID > 0 {
+		return article.ListByTagID(d.engine, tagID, pageOffset, pageSize)
+	}
+
+	return article.List(d.engine, pageOffset, pageSize)
+}
+
+// 返回最新发布的 limit 篇 Article，tagID 大于 0 时只返回关联了该标签的文章
+func (d *Dao) GetLatestArticleList(tagID uint32, limit int) ([]*model.Article, error) {
+	article := model.Article{State: model.ArticleStatePublished}
+
+	return article.ListLatest(d.engine, tagID, limit)
+}
+
+// 返回某个 id 的 Article
+func (d *Dao) GetArticle(id uint32, state uint8) (*model.Article, error) {
+	article := model.Article{
+		State:  state,
+		Common: &model.Common{ID: id},
+	}
+
+	return article.Get(d.engine)
+}
+
+// 返回某个别名的 Article
+func (d *Dao) GetArticleBySlug(slug string, state uint8) (*model.Article, error) {
+	article := model.Article{Slug: slug, State: state}
+
+	return article.GetBySlug(d.engine)
+}
+
+// 返回别名是否已被 excludeID 以外的 Article 使用
+func (d *Dao) ArticleSlugExists(slug string, excludeID uint32) (bool, error) {
+	article := model.Article{Slug: slug}
+
+	return article.SlugExists(d.engine, excludeID)
+}
+
+// 按 ID 顺序分批返回还没有别名的 Article
+func (d *Dao) GetArticleListWithoutSlug(afterID uint32, limit int) ([]*model.Article, error) {
+	article := model.Article{}
+
+	return article.ListWithoutSlug(d.engine, afterID, limit)
+}
+
+// 返回某个 id 的 Article，不限制状态
+func (d *Dao) GetArticleByID(id uint32) (*model.Article, error) {
+	article := model.Article{Common: &model.Common{ID: id}}
+
+	return article.GetByID(d.engine)
+}
+
+// 锁定某个 id 的 Article 直到事务结束，只能在事务中调用
+func (d *Dao) LockArticle(id uint32) (*model.Article, error) {
+	article := model.Article{Common: &model.Common{ID: id}}
+
+	return article.Lock(d.engine)
+}
+
+// 按 ID 顺序分批返回文章的封面和内容
+func (d *Dao) GetArticleListForScan(afterID uint32, limit int) ([]*model.Article, error) {
+	article := model.Article{}
+
+	return article.ListForScan(d.engine, afterID, limit)
+}
+
+// 按 ID 顺序分批返回已发布文章的标题、简述和内容
+func (d *Dao) GetArticleListForIndex(afterID uint32, limit int) ([]*model.Article, error) {
+	article := model.Article{}
+
+	return article.ListForIndex(d.engine, afterID, limit)
+}
+
+// 创建新的 Article
+func (d *Dao) CreateArticle(param *Article) (*model.Article, error) {
+	article := model.Article{
+		Title:         param.Title,
+		Slug:          param.Slug,
+		Desc:          param.Desc,
+		Content:       param.Content,
+		CoverImageUrl: param.CoverImageUrl,
+		State:         param.State,
+		Common:        &model.Common{CreatedBy: param.CreatedBy},
+	}
+	if err := article.Create(d.engine); err != nil {
+		return nil, err
+	}
+
+	return &article, nil
+}
+
+// 修改 Article 的信息，只更新非空字段，状态只能通过 UpdateArticleState 修改
+func (d *Dao) UpdateArticle(param *Article) error {
+	article := model.Article{
+		Common: &model.Common{ID: param.ID},
+	}
+
+	values := map[string]interface{}{
+		"modified_by": param.ModifiedBy,
+	}
+	if param.Title != "" {
+		values["title"] = param.Title
+	}
+	if param.Desc != "" {
+		values["desc"] = param.Desc
+	}
+	if param.Content != "" {
+		values["content"] = param.Content
+	}
+	if param.CoverImageUrl != "" {
+		values["cover_image_url"] = param.CoverImageUrl
+	}
+
+	return article.Update(d.engine, values)
+}
+
+// 只修改 Article 的别名，不更新 modified_on 和 modified_by
+// 随标题修改别名时由同一事务中的 UpdateArticle 更新修改时间，为已有文章生成别名不算作修改文章
+func (d *Dao) UpdateArticleSlug(id uint32, slug string) error {
+	article := model.Article{Common: &model.Common{ID: id}}
+
+	return article.UpdateColumns(d.engine, map[string]interface{}{"slug": slug})
+}
+
+// 使用历史版本覆盖 Article 的标题、简述、内容和封面，空字段同样会被覆盖
+func (d *Dao) RestoreArticle(param *Article) error {
+	article := model.Article{
+		Common: &model.Common{ID: param.ID},
+	}
+	values := map[string]interface{}{
+		"title":           param.Title,
+		"desc":            param.Desc,
+		"content":         param.Content,
+		"cover_image_url": param.CoverImageUrl,
+		"modified_by":     param.ModifiedBy,
+	}
+
+	return article.Update(d.engine, values)
+}
+
+// 仅当 Article 仍处于 from 状态时修改为 to，同时更新发布时间，返回是否修改成功
+func (d *Dao) UpdateArticleState(id uint32, from, to uint8, publishOn uint32, modifiedBy string) (bool, error) {
+	article := model.Article{State: from, Common: &model.Common{ID: id}}
+	values := map[string]interface{}{
+		"state":       to,
+		"publish_on":  publishOn,
+		"modified_by": modifiedBy,
+	}
+
+	return article.UpdateIfState(d.engine, values)
+}
+
+// 为审核中的 Article 设置定时发布的时间，返回是否设置成功
+func (d *Dao) ScheduleArticle(id uint32, publishOn uint32, modifiedBy string) (bool, error) {
+	article := model.Article{State: model.ArticleStateInReview, Common: &model.Common{ID: id}}
+	values := map[string]interface{}{
+		"publish_on":  publishOn,
+		"modified_by": modifiedBy,
+	}
+
+	return article.UpdateIfState(d.engine, values)
+}
+
+// 返回发布时间已到的审核中 Article
+func (d *Dao) GetDueArticleList(now uint32, limit int) ([]*model.Article, error) {
+	article := model.Article{}
+
+	return article.ListDue(d.engine, now, limit)
+}
+
+// 删除某个 id 的 Article
+func (d *Dao) DeleteArticle(id uint32) error {
+	article := model.Article{Common: &model.Common{ID: id}}
+
+	return article.Delete(d.engine)
+}
@@ -0,0 +1,59 @@
+package dao
+
+import "github.com/go-programming-tour/blog-service/internal/model"
+
+// 文章历史版本的入参
+type ArticleRevision struct {
+	ArticleID     uint32
+	Revision      uint32
+	Title         string
+	Desc          string
+	Content       string
+	CoverImageUrl string
+	RestoredFrom  uint32
+	CreatedBy     string
+	CreatedOn     uint32 // 为 0 时使用当前时间
+}
+
+func (d *Dao) CreateArticleRevision(param *ArticleRevision) error {
+	revision := model.ArticleRevision{
+		ArticleID:     param.ArticleID,
+		Revision:      param.Revision,
+		Title:         param.Title,
+		Desc:          param.Desc,
+		Content:       param.Content,
+		CoverImageUrl: param.CoverImageUrl,
+		RestoredFrom:  param.RestoredFrom,
+		Common:        &model.Common{CreatedBy: param.CreatedBy, CreatedOn: param.CreatedOn},
+	}
+
+	return revision.Create(d.engine)
+}
+
+// 返回文章的全部历史版本，不包含内容
+func (d *Dao) GetArticleRevisionList(articleID uint32) ([]*model.ArticleRevision, error) {
+	revision := model.ArticleRevision{ArticleID: articleID}
+
+	return revision.ListByArticleID(d.engine)
+}
+
+// 返回文章的指定版本，不存在时返回 nil
+func (d *Dao) GetArticleRevision(articleID, revisionNo uint32) (*model.ArticleRevision, error) {
+	revision := model.ArticleRevision{ArticleID: articleID, Revision: revisionNo}
+
+	return revision.GetByRevision(d.engine)
+}
+
+// 返回文章的最新版本，没有历史版本时返回 nil
+func (d *Dao) GetLatestArticleRevision(articleID uint32) (*model.ArticleRevision, error) {
+	revision := model.ArticleRevision{ArticleID: articleID}
+
+	return revision.GetLatest(d.engine)
+}
+
+// 按 ID 顺序分批返回历史版本的封面和内容
+func (d *Dao) GetArticleRevisionListForScan(afterID uint32, limit int) ([]*model.ArticleRevision, error) {
+	revision := model.ArticleRevision{}
+
+	return revision.ListForScan(d.engine, afterID, limit)
+}
@@ -0,0 +1,28 @@
+package dao
+
+import "github.com/go-programming-tour/blog-service/internal/model"
+
+// 返回旧别名的记录，不存在时返回 nil
+func (d *Dao) GetArticleSlug(slug string) (*model.ArticleSlug, error) {
+	articleSlug := model.ArticleSlug{Slug: slug}
+
+	return articleSlug.Get(d.engine)
+}
+
+// 记录文章的旧别名
+func (d *Dao) CreateArticleSlug(slug string, articleID uint32, createdBy string) error {
+	articleSlug := model.ArticleSlug{
+		Slug:      slug,
+		ArticleID: articleID,
+		Common:    &model.Common{CreatedBy: createdBy},
+	}
+
+	return articleSlug.Create(d.engine)
+}
+
+// 删除旧别名的记录
+func (d *Dao) DeleteArticleSlug(slug string) error {
+	articleSlug := model.ArticleSlug{Slug: slug}
+
+	return articleSlug.Delete(d.engine)
+}
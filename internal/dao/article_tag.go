@@ -0,0 +1,42 @@
+package dao
+
+import "github.com/go-programming-tour/blog-service/internal/model"
+
+// 返回一组文章的标签关联
+func (d *Dao) GetArticleTagListByAIDs(articleIDs []uint32) ([]*model.ArticleTag, error) {
+	articleTag := model.ArticleTag{}
+
+	return articleTag.ListByArticleIDs(d.engine, articleIDs)
+}
+
+// 返回每个标签关联的已发布文章数量
+func (d *Dao) CountArticleByTagIDs(tagIDs []uint32) (map[uint32]int, error) {
+	articleTag := model.ArticleTag{}
+
+	return articleTag.CountArticleByTagIDs(d.engine, tagIDs)
+}
+
+// 新增文章与标签的关联
+func (d *Dao) CreateArticleTag(articleID, tagID uint32, createdBy string) error {
+	articleTag := model.ArticleTag{
+		ArticleID: articleID,
+		TagID:     tagID,
+		Common:    &model.Common{CreatedBy: createdBy},
+	}
+
+	return articleTag.Create(d.engine)
+}
+
+// 删除文章下指定标签的关联，tagIDs 为空时删除全部
+func (d *Dao) DeleteArticleTag(articleID uint32, tagIDs []uint32) error {
+	articleTag := model.ArticleTag{ArticleID: articleID}
+
+	return articleTag.DeleteByArticleID(d.engine, tagIDs)
+}
+
+// 删除某个标签下的全部关联
+func (d *Dao) DeleteArticleTagByTagID(tagID uint32) error {
+	articleTag := model.ArticleTag{TagID: tagID}
+
+	return articleTag.DeleteByTagID(d.engine)
+}
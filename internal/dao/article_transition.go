@@ -0,0 +1,35 @@
+package dao
+
+import "github.com/go-programming-tour/blog-service/internal/model"
+
+// 文章状态变更记录的入参
+type ArticleTransition struct {
+	ArticleID  uint32
+	FromState  uint8
+	ToState    uint8
+	OperatorID uint32
+	Comment    string
+	PublishOn  uint32
+	CreatedBy  string
+}
+
+func (d *Dao) CreateArticleTransition(param *ArticleTransition) error {
+	transition := model.ArticleTransition{
+		ArticleID:  param.ArticleID,
+		FromState:  param.FromState,
+		ToState:    param.ToState,
+		OperatorID: param.OperatorID,
+		Comment:    param.Comment,
+		PublishOn:  param.PublishOn,
+		Common:     &model.Common{CreatedBy: param.CreatedBy},
+	}
+
+	return transition.Create(d.engine)
+}
+
+// 按时间顺序返回文章的全部状态变更记录
+func (d *Dao) GetArticleTransitionList(articleID uint32) ([]*model.ArticleTransition, error) {
+	transition := model.ArticleTransition{ArticleID: articleID}
+
+	return transition.ListByArticleID(d.engine)
+}
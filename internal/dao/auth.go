@@ -1,12 +0,0 @@
-package dao
-
-import "github.com/go-programming-tour/blog-service/internal/model"
-
-func (d *Dao) GetAuth(appKey, appSecret string) (model.Auth, error) {
-	auth := model.Auth{
-		AppKey:    appKey,
-		AppSecret: appSecret,
-	}
-
-	return auth.Get(d.engine)
-}
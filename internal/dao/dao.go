@@ -11,3 +11,19 @@ type Dao struct {
 func New(engine *gorm.DB) *Dao {
 	return &Dao{engine: engine}
 }
+
+// 在同一个事务中执行 fn，fn 返回错误时回滚
+func (d *Dao) Transaction(fn func(tx *Dao) error) error {
+	return d.engine.Transaction(func(db *gorm.DB) error {
+		return fn(New(db))
+	})
+}
+
+// 数据库中的布尔字段使用 0 和 1 保存
+func boolToUint8(b bool) uint8 {
+	if b {
+		return 1
+	}
+
+	return 0
+}
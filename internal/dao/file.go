@@ -0,0 +1,98 @@
+package dao
+
+import "github.com/go-programming-tour/blog-service/internal/model"
+
+// 文件的入参
+type File struct {
+	Hash           string
+	Path           string
+	OriginalName   string
+	MimeType       string
+	Size           int64
+	DerivativeSize int64 // 衍生图的总大小，与文件一起计入用量
+	UploaderID     uint32
+	Private        bool
+	CreatedBy      string
+}
+
+// 返回上传者自己的指定内容哈希的公开或私有 File，不存在时返回 nil
+func (d *Dao) GetFileByHash(hash string, private bool, uploaderID uint32) (*model.File, error) {
+	file := model.File{Hash: hash, Private: boolToUint8(private), UploaderID: uploaderID}
+
+	return file.GetByHash(d.engine)
+}
+
+// 返回任意上传者的指定内容哈希的公开或私有 File，不存在时返回 nil
+func (d *Dao) GetStoredFileByHash(hash string, private bool) (*model.File, error) {
+	file := model.File{Hash: hash, Private: boolToUint8(private)}
+
+	return file.GetStoredByHash(d.engine)
+}
+
+// 返回指定 ID 的 File，不存在时返回 nil
+func (d *Dao) GetFile(id uint32) (*model.File, error) {
+	file := model.File{Common: &model.Common{ID: id}}
+
+	return file.Get(d.engine)
+}
+
+// 创建新的 File，引用计数由回收任务统计
+func (d *Dao) CreateFile(param *File) (*model.File, error) {
+	file := model.File{
+		Hash:           param.Hash,
+		Path:           param.Path,
+		OriginalName:   param.OriginalName,
+		MimeType:       param.MimeType,
+		Size:           param.Size,
+		DerivativeSize: param.DerivativeSize,
+		UploaderID:     param.UploaderID,
+		Private:        boolToUint8(param.Private),
+		Common:         &model.Common{CreatedBy: param.CreatedBy},
+	}
+	if err := file.Create(d.engine); err != nil {
+		return nil, err
+	}
+
+	return &file, nil
+}
+
+// 更新 File 的引用计数和未被引用的时间
+func (d *Dao) UpdateFileRefCount(id, refCount, orphanedOn uint32) error {
+	file := model.File{Common: &model.Common{ID: id}}
+
+	return file.UpdateRefCount(d.engine, refCount, orphanedOn)
+}
+
+// 按 ID 顺序分批返回 File
+func (d *Dao) GetFileListAfterID(afterID uint32, limit int) ([]*model.File, error) {
+	file := model.File{}
+
+	return file.ListAfterID(d.engine, afterID, limit)
+}
+
+func (d *Dao) UpdateFileOrphanedOn(id, orphanedOn uint32) error {
+	file := model.File{Common: &model.Common{ID: id}}
+
+	return file.UpdateOrphanedOn(d.engine, orphanedOn)
+}
+
+// 返回与指定 File 共用存储内容的其他 File 数量
+func (d *Dao) CountOtherFilesByPath(id uint32, path string) (int, error) {
+	file := model.File{Path: path, Common: &model.Common{ID: id}}
+
+	return file.CountOthersByPath(d.engine)
+}
+
+// 锁定共用存储内容的全部 File 直到事务结束，只能在事务中调用
+func (d *Dao) LockFilesByPath(path string) ([]*model.File, error) {
+	file := model.File{Path: path}
+
+	return file.LockByPath(d.engine)
+}
+
+// 删除 File，返回是否由本次调用删除
+func (d *Dao) DeleteFile(id uint32) (bool, error) {
+	file := model.File{Common: &model.Common{ID: id}}
+
+	return file.Delete(d.engine)
+}
@@ -0,0 +1,20 @@
+package dao
+
+import (
+	"time"
+
+	"github.com/go-programming-tour/blog-service/internal/model"
+)
+
+// 获取或续期租约，返回是否持有租约
+func (d *Dao) AcquireLease(name, holder string, ttl time.Duration) (bool, error) {
+	lease := model.Lease{Name: name, Holder: holder, Common: &model.Common{CreatedBy: holder}}
+
+	return lease.Acquire(d.engine, ttl)
+}
+
+func (d *Dao) ReleaseLease(name, holder string) error {
+	lease := model.Lease{Name: name, Holder: holder}
+
+	return lease.Release(d.engine)
+}
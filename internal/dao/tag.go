@@ -21,6 +21,23 @@ func (d *Dao) GetTagList(name string, state uint8, page int, pageSize int) ([]*m
 	return tag.List(d.engine, pageOffset, pageSize)
 }
 
+// 返回某个 id 的 Tag
+func (d *Dao) GetTag(id uint32, state uint8) (*model.Tag, error) {
+	tag := model.Tag{
+		State:  state,
+		Common: &model.Common{ID: id},
+	}
+
+	return tag.Get(d.engine)
+}
+
+// 返回指定 id 集合的 Tag
+func (d *Dao) GetTagListByIDs(ids []uint32) ([]*model.Tag, error) {
+	tag := model.Tag{}
+
+	return tag.ListByIDs(d.engine, ids)
+}
+
 // 创建新的 Tag
 func (d *Dao) CreateTag(name string, state uint8, cratedBy string) error {
 	tag := model.Tag{
@@ -39,8 +56,8 @@ func (d *Dao) UpdateTag(id uint32, name string, state uint8, modifiedBy string)
 	}
 
 	values := map[string]interface{}{
-		"state":      state,
-		"modifiedBy": modifiedBy,
+		"state":       state,
+		"modified_by": modifiedBy,
 	}
 	if name != "" {
 		values["name"] = name
@@ -0,0 +1,54 @@
+package dao
+
+import "github.com/go-programming-tour/blog-service/internal/model"
+
+// 保存刷新令牌，tokenHash 为令牌的摘要
+func (d *Dao) CreateRefreshToken(userID uint32, familyID, tokenHash string, expiresOn uint32) error {
+	token := model.RefreshToken{
+		UserID:    userID,
+		FamilyID:  familyID,
+		TokenHash: tokenHash,
+		ExpiresOn: expiresOn,
+	}
+
+	return token.Create(d.engine)
+}
+
+// 返回指定摘要的刷新令牌，不存在时返回 nil
+func (d *Dao) GetRefreshToken(tokenHash string) (*model.RefreshToken, error) {
+	token := model.RefreshToken{TokenHash: tokenHash}
+
+	return token.GetByHash(d.engine)
+}
+
+// 将刷新令牌标记为已使用，返回是否标记成功
+func (d *Dao) MarkRefreshTokenUsed(id, usedOn uint32) (bool, error) {
+	token := model.RefreshToken{Common: &model.Common{ID: id}}
+
+	return token.MarkUsed(d.engine, usedOn)
+}
+
+// 吊销同一个 family 的全部刷新令牌
+func (d *Dao) RevokeRefreshTokenFamily(familyID string, revokedOn uint32) error {
+	token := model.RefreshToken{FamilyID: familyID}
+
+	return token.RevokeFamily(d.engine, revokedOn)
+}
+
+// 将访问令牌加入吊销列表
+func (d *Dao) CreateRevokedToken(jti string, expiresOn uint32, createdBy string) error {
+	token := model.RevokedToken{
+		JTI:       jti,
+		ExpiresOn: expiresOn,
+		Common:    &model.Common{CreatedBy: createdBy},
+	}
+
+	return token.Create(d.engine)
+}
+
+// 返回访问令牌是否已被吊销
+func (d *Dao) IsTokenRevoked(jti string) (bool, error) {
+	token := model.RevokedToken{JTI: jti}
+
+	return token.Exist(d.engine)
+}
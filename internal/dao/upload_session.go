@@ -0,0 +1,108 @@
+package dao
+
+import "github.com/go-programming-tour/blog-service/internal/model"
+
+// 上传会话的入参
+type UploadSession struct {
+	UploadID     string
+	FileType     int
+	FileName     string
+	Size         int64
+	ChunkSize    int64
+	Hash         string
+	UploaderID   uint32
+	ExpiresOn    uint32
+	Private      bool
+	ReservedSize int64
+	CreatedBy    string
+}
+
+func (d *Dao) CreateUploadSession(param *UploadSession) (*model.UploadSession, error) {
+	session := model.UploadSession{
+		UploadID:     param.UploadID,
+		FileType:     param.FileType,
+		FileName:     param.FileName,
+		Size:         param.Size,
+		ChunkSize:    param.ChunkSize,
+		Hash:         param.Hash,
+		UploaderID:   param.UploaderID,
+		ExpiresOn:    param.ExpiresOn,
+		Private:      boolToUint8(param.Private),
+		State:        model.UploadSessionUploading,
+		ReservedSize: param.ReservedSize,
+		Common:       &model.Common{CreatedBy: param.CreatedBy},
+	}
+	if err := session.Create(d.engine); err != nil {
+		return nil, err
+	}
+
+	return &session, nil
+}
+
+// 返回指定对外 ID 的上传会话，不存在时返回 nil
+func (d *Dao) GetUploadSession(uploadID string) (*model.UploadSession, error) {
+	session := model.UploadSession{UploadID: uploadID}
+
+	return session.GetByUploadID(d.engine)
+}
+
+// 锁定上传会话直到事务结束，不存在时返回 nil，只能在事务中调用
+func (d *Dao) LockUploadSession(id uint32) (*model.UploadSession, error) {
+	session := model.UploadSession{Common: &model.Common{ID: id}}
+
+	return session.Lock(d.engine)
+}
+
+// 返回在 now 之前过期的上传会话，每次最多 limit 条
+func (d *Dao) GetExpiredUploadSessionList(now uint32, limit int) ([]*model.UploadSession, error) {
+	session := model.UploadSession{}
+
+	return session.ListExpired(d.engine, now, limit)
+}
+
+func (d *Dao) CompleteUploadSession(id, fileID uint32) error {
+	session := model.UploadSession{Common: &model.Common{ID: id}}
+
+	return session.Complete(d.engine, fileID)
+}
+
+// 清除会话的预留大小，返回是否由本次调用清除
+func (d *Dao) ClearUploadSessionReservation(id uint32) (bool, error) {
+	session := model.UploadSession{Common: &model.Common{ID: id}}
+
+	return session.ClearReservation(d.engine)
+}
+
+func (d *Dao) DeleteUploadSession(id uint32) error {
+	session := model.UploadSession{Common: &model.Common{ID: id}}
+
+	return session.Delete(d.engine)
+}
+
+// 保存已接收的分片，同一位置重复上传时替换原有记录
+func (d *Dao) SaveUploadChunk(sessionID uint32, start, size int64, hash, createdBy string) error {
+	chunk := model.UploadChunk{
+		SessionID: sessionID,
+		Start:     start,
+		Size:      size,
+		Hash:      hash,
+		Common:    &model.Common{CreatedBy: createdBy},
+	}
+	if err := chunk.DeleteByStart(d.engine); err != nil {
+		return err
+	}
+
+	return chunk.Create(d.engine)
+}
+
+func (d *Dao) GetUploadChunkList(sessionID uint32) ([]*model.UploadChunk, error) {
+	chunk := model.UploadChunk{SessionID: sessionID}
+
+	return chunk.ListBySessionID(d.engine)
+}
+
+func (d *Dao) DeleteUploadChunks(sessionID uint32) error {
+	chunk := model.UploadChunk{SessionID: sessionID}
+
+	return chunk.DeleteBySessionID(d.engine)
+}
@@ -0,0 +1,37 @@
+package dao
+
+import "github.com/go-programming-tour/blog-service/internal/model"
+
+// 用量的变化量，为负时表示释放
+type UsageDelta struct {
+	Size       int64
+	Files      int
+	StagedSize int64
+}
+
+// 返回归属方的用量，还没有上传过文件时返回 nil
+func (d *Dao) GetUploadUsage(owner string) (*model.UploadUsage, error) {
+	usage := model.UploadUsage{Owner: owner}
+
+	return usage.GetByOwner(d.engine)
+}
+
+// 按增量更新归属方的用量，增加后超过配额时不更新并返回 false，配额为 0 时不限制
+func (d *Dao) AddUploadUsage(owner string, delta UsageDelta, maxSize int64, maxFiles int) (bool, error) {
+	usage := model.UploadUsage{Owner: owner, Size: delta.Size, Files: delta.Files, StagedSize: delta.StagedSize}
+	added, err := usage.Add(d.engine, maxSize, maxFiles)
+	if err != nil || added {
+		return added, err
+	}
+
+	// 第一次上传时还没有计数
+	existing, err := usage.GetByOwner(d.engine)
+	if err != nil || existing != nil {
+		return false, err
+	}
+	if err := usage.CreateIfNotExist(d.engine); err != nil {
+		return false, err
+	}
+
+	return usage.Add(d.engine, maxSize, maxFiles)
+}
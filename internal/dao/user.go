@@ -0,0 +1,52 @@
+package dao
+
+import "github.com/go-programming-tour/blog-service/internal/model"
+
+// 返回指定用户名的 User
+func (d *Dao) GetUserByUsername(username string) (*model.User, error) {
+	user := model.User{Username: username}
+
+	return user.GetByUsername(d.engine)
+}
+
+// 返回指定 id 的 User
+func (d *Dao) GetUser(id uint32) (*model.User, error) {
+	user := model.User{Common: &model.Common{ID: id}}
+
+	return user.Get(d.engine)
+}
+
+// 返回指定角色的 User 数量
+func (d *Dao) CountUserByRole(role string) (int, error) {
+	user := model.User{Role: role}
+
+	return user.CountByRole(d.engine)
+}
+
+// 创建新的 User，password 为哈希后的密码
+func (d *Dao) CreateUser(username, password, nickname, role string) (*model.User, error) {
+	user := model.User{
+		Username: username,
+		Password: password,
+		Nickname: nickname,
+		Role:     role,
+		State:    1,
+		Common:   &model.Common{CreatedBy: username},
+	}
+	if err := user.Create(d.engine); err != nil {
+		return nil, err
+	}
+
+	return &user, nil
+}
+
+// 修改 User 的角色
+func (d *Dao) UpdateUserRole(id uint32, role, modifiedBy string) error {
+	user := model.User{Common: &model.Common{ID: id}}
+	values := map[string]interface{}{
+		"role":        role,
+		"modified_by": modifiedBy,
+	}
+
+	return user.Update(d.engine, values)
+}
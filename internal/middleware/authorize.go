@@ -0,0 +1,34 @@
+package middleware
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/go-programming-tour/blog-service/pkg/app"
+	"github.com/go-programming-tour/blog-service/pkg/errcode"
+)
+
+// 只允许指定角色访问，需要放在 JWT 中间件之后
+func Authorize(roles ...string) gin.HandlerFunc {
+	allowed := make(map[string]bool, len(roles))
+	for _, role := range roles {
+		allowed[role] = true
+	}
+
+	return func(c *gin.Context) {
+		ecode := errcode.Success
+		claims, ok := app.ClaimsFromContext(c.Request.Context())
+		if !ok {
+			ecode = errcode.UnauthorizedTokenError
+		} else if !allowed[claims.Role] {
+			ecode = errcode.Forbidden
+		}
+
+		if ecode != errcode.Success {
+			response := app.NewResponse(c)
+			response.ToErrorResponse(ecode)
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
@@ -1,36 +1,22 @@
 package middleware
 
 import (
+	"time"
+
 	"github.com/dgrijalva/jwt-go"
 	"github.com/gin-gonic/gin"
+	"github.com/go-programming-tour/blog-service/global"
+	"github.com/go-programming-tour/blog-service/internal/dao"
 	"github.com/go-programming-tour/blog-service/pkg/app"
 	"github.com/go-programming-tour/blog-service/pkg/errcode"
 )
 
 func JWT() gin.HandlerFunc {
 	return func(c *gin.Context) {
-		var (
-			token string
-			ecode = errcode.Success
-		)
-
-		if s, exist := c.GetQuery("token"); exist {
-			token = s
-		} else {
-			token = c.GetHeader("token")
-		}
-
-		if token == "" {
-			ecode = errcode.InvalidParams
-		} else {
-			if _, err := app.ParseToken(token); err != nil {
-				switch err.(*jwt.ValidationError).Errors {
-				case jwt.ValidationErrorExpired:
-					ecode = errcode.UnauthorizedTokenTimeout
-				default:
-					ecode = errcode.UnauthorizedTokenError
-				}
-			}
+		token := getToken(c)
+		ecode := errcode.InvalidParams
+		if token != "" {
+			ecode = authenticate(c, token)
 		}
 
 		if ecode != errcode.Success {
@@ -44,3 +30,63 @@ func JWT() gin.HandlerFunc {
 		c.Next()
 	}
 }
+
+// 携带有效令牌时写入当前用户，没有令牌或令牌无效时按未登录继续处理
+// 只从 header 中读取令牌，用于地址会被分享或写入日志和 Referer 的路由，避免令牌随地址泄露
+func OptionalJWT() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if token := c.GetHeader("token"); token != "" {
+			authenticate(c, token)
+		}
+		c.Next()
+	}
+}
+
+// 从 query 或 header 中读取令牌
+func getToken(c *gin.Context) string {
+	if s, exist := c.GetQuery("token"); exist {
+		return s
+	}
+
+	return c.GetHeader("token")
+}
+
+// 校验令牌，通过时将当前用户写入请求上下文
+func authenticate(c *gin.Context, token string) *errcode.Error {
+	claims, err := app.ParseToken(token)
+	if err != nil {
+		switch err.(*jwt.ValidationError).Errors {
+		case jwt.ValidationErrorExpired:
+			return errcode.UnauthorizedTokenTimeout
+		default:
+			return errcode.UnauthorizedTokenError
+		}
+	}
+	if claims.Id == "" {
+		return errcode.UnauthorizedTokenError
+	}
+
+	return checkRevoked(c, claims)
+}
+
+// 检查令牌是否已被吊销，未吊销时将当前用户写入请求上下文，供 service 层使用
+// 吊销状态优先从进程内缓存读取
+func checkRevoked(c *gin.Context, claims *app.Claims) *errcode.Error {
+	cache := app.GetRevocationCache()
+	revoked, ok := cache.Get(claims.Id)
+	if !ok {
+		var err error
+		revoked, err = dao.New(global.DBEngine).IsTokenRevoked(claims.Id)
+		if err != nil {
+			global.Logger.ErrorfT("dao.IsTokenRevoked err: %v", err)
+			return errcode.ServerError
+		}
+		cache.Set(claims.Id, revoked, time.Unix(claims.ExpiresAt, 0))
+	}
+	if revoked {
+		return errcode.UnauthorizedTokenRevoked
+	}
+
+	c.Request = c.Request.WithContext(app.WithClaims(c.Request.Context(), claims))
+	return errcode.Success
+}
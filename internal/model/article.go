@@ -1,15 +1,60 @@
 package model
 
-import "github.com/go-programming-tour/blog-service/pkg/app"
+import (
+	"github.com/go-programming-tour/blog-service/pkg/app"
+	"github.com/go-programming-tour/blog-service/pkg/markdown"
+	"github.com/jinzhu/gorm"
+)
+
+// 文章状态，草稿提交审核后才能发布，已发布的文章可以归档
+// 已发布沿用原来的取值 1，兼容已有数据
+const (
+	ArticleStateDraft     uint8 = 0
+	ArticleStatePublished uint8 = 1
+	ArticleStateInReview  uint8 = 2
+	ArticleStateArchived  uint8 = 3
+)
+
+var articleStateNames = map[uint8]string{
+	ArticleStateDraft:     "draft",
+	ArticleStateInReview:  "in_review",
+	ArticleStatePublished: "published",
+	ArticleStateArchived:  "archived",
+}
+
+// 返回文章状态的名称
+func ArticleStateName(state uint8) string {
+	if name, ok := articleStateNames[state]; ok {
+		return name
+	}
+
+	return "unknown"
+}
+
+// 根据名称返回文章状态
+func ParseArticleState(name string) (uint8, bool) {
+	for state, stateName := range articleStateNames {
+		if stateName == name {
+			return state, true
+		}
+	}
+
+	return 0, false
+}
 
 // 文章结构体
 type Article struct {
 	*Common
-	Title         string `json:"title"`
-	Desc          string `json:"desc"`
-	Content       string `json:"content"`
-	CoverImageUrl string `json:"cover_image_url"`
-	State         uint8  `json:"state"`
+	Title         string              `json:"title"`
+	Slug          string              `json:"slug"` // URL 中使用的别名，唯一索引
+	Desc          string              `json:"desc"`
+	Content       string              `json:"content"`
+	ContentHTML   string              `json:"content_html" gorm:"-"` // 内容渲染后过滤过的 HTML
+	TOC           []*markdown.Heading `json:"toc" gorm:"-"`          // 内容的标题目录
+	CoverImageUrl string              `json:"cover_image_url"`
+	State         uint8               `json:"state"`
+	PublishOn     uint32              `json:"publish_on"` // 发布时间，审核中的文章设置了未来的时间时到期自动发布
+	Tags          []*Tag              `json:"tags" gorm:"-"`
 }
 
 // swagger结构体
@@ -21,3 +66,235 @@ type ArticleSwagger struct {
 func (a *Article) TableName() string {
 	return "blog_article"
 }
+
+// 返回指定条件的文章数量
+func (a *Article) Count(db *gorm.DB) (int, error) {
+	var count int
+	if a.Title != "" {
+		db = db.Where("title = ?", a.Title)
+	}
+	if a.Common != nil && a.CreatedBy != "" {
+		db = db.Where("created_by = ?", a.CreatedBy)
+	}
+	db = db.Where("state = ?", a.State)
+
+	if err := db.Model(&Article{}).Where("is_del = ?", 0).Count(&count).Error; err != nil {
+		return 0, err
+	}
+
+	return count, nil
+}
+
+// 返回分页查询的文章集合
+func (a *Article) List(db *gorm.DB, pageOffset, pageSize int) ([]*Article, error) {
+	var articles []*Article
+	if pageOffset >= 0 && pageSize > 0 {
+		db = db.Offset(pageOffset).Limit(pageSize)
+	}
+
+	if a.Title != "" {
+		db = db.Where("title = ?", a.Title)
+	}
+	if a.Common != nil && a.CreatedBy != "" {
+		db = db.Where("created_by = ?", a.CreatedBy)
+	}
+	db = db.Where("state = ?", a.State)
+	if err := db.Where("is_del = ?", 0).Find(&articles).Error; err != nil {
+		return nil, err
+	}
+
+	return articles, nil
+}
+
+// 返回最新发布的文章，tagID 大于 0 时只返回关联了该标签的文章
+// 早期发布的文章没有发布时间，按 ID 排序
+func (a *Article) ListLatest(db *gorm.DB, tagID uint32, limit int) ([]*Article, error) {
+	var articles []*Article
+	if tagID > 0 {
+		db = a.joinArticleTag(db, tagID).Select("a.*").Order("a.publish_on DESC, a.id DESC")
+	} else {
+		db = db.Where("state = ? AND is_del = ?", a.State, 0).Order("publish_on DESC, id DESC")
+	}
+	if err := db.Limit(limit).Find(&articles).Error; err != nil {
+		return nil, err
+	}
+
+	return articles, nil
+}
+
+// 返回关联了指定标签的文章数量
+func (a *Article) CountByTagID(db *gorm.DB, tagID uint32) (int, error) {
+	var count int
+	db = a.joinArticleTag(db, tagID)
+	if err := db.Count(&count).Error; err != nil {
+		return 0, err
+	}
+
+	return count, nil
+}
+
+// 返回关联了指定标签的文章分页集合
+func (a *Article) ListByTagID(db *gorm.DB, tagID uint32, pageOffset, pageSize int) ([]*Article, error) {
+	var articles []*Article
+	if pageOffset >= 0 && pageSize > 0 {
+		db = db.Offset(pageOffset).Limit(pageSize)
+	}
+
+	db = a.joinArticleTag(db, tagID)
+	if err := db.Select("a.*").Find(&articles).Error; err != nil {
+		return nil, err
+	}
+
+	return articles, nil
+}
+
+// 通过 blog_article_tag 关联查询文章的公共条件
+func (a *Article) joinArticleTag(db *gorm.DB, tagID uint32) *gorm.DB {
+	db = db.Table(a.TableName()+" AS a").
+		Joins("INNER JOIN blog_article_tag AS at ON at.article_id = a.id AND at.is_del = 0").
+		Where("at.tag_id = ? AND a.state = ? AND a.is_del = ?", tagID, a.State, 0)
+	if a.Title != "" {
+		db = db.Where("a.title = ?", a.Title)
+	}
+	if a.Common != nil && a.CreatedBy != "" {
+		db = db.Where("a.created_by = ?", a.CreatedBy)
+	}
+
+	return db
+}
+
+// 返回指定 id 的文章
+func (a *Article) Get(db *gorm.DB) (*Article, error) {
+	var article Article
+	db = db.Where("id = ? AND state = ? AND is_del = ?", a.Common.ID, a.State, 0)
+	if err := db.First(&article).Error; err != nil {
+		return nil, err
+	}
+
+	return &article, nil
+}
+
+// 返回指定别名的文章
+func (a *Article) GetBySlug(db *gorm.DB) (*Article, error) {
+	var article Article
+	db = db.Where("slug = ? AND state = ? AND is_del = ?", a.Slug, a.State, 0)
+	if err := db.First(&article).Error; err != nil {
+		return nil, err
+	}
+
+	return &article, nil
+}
+
+// 返回别名是否已被 excludeID 以外的文章使用，已删除的文章仍然占用唯一索引
+func (a *Article) SlugExists(db *gorm.DB, excludeID uint32) (bool, error) {
+	var count int
+	err := db.Model(&Article{}).Where("slug = ? AND id <> ?", a.Slug, excludeID).Count(&count).Error
+	if err != nil {
+		return false, err
+	}
+
+	return count > 0, nil
+}
+
+// 返回指定 id 的文章，不限制状态
+func (a *Article) GetByID(db *gorm.DB) (*Article, error) {
+	var article Article
+	if err := db.Where("id = ? AND is_del = ?", a.Common.ID, 0).First(&article).Error; err != nil {
+		return nil, err
+	}
+
+	return &article, nil
+}
+
+// 在事务中锁定指定 id 的文章并返回，同一篇文章的修改和版本号分配在锁内串行执行
+func (a *Article) Lock(db *gorm.DB) (*Article, error) {
+	var article Article
+	err := db.Set("gorm:query_option", "FOR UPDATE").
+		Where("id = ? AND is_del = ?", a.Common.ID, 0).First(&article).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return &article, nil
+}
+
+// 按 ID 顺序分批返回未删除文章的封面和内容，用于查找被引用的上传文件
+func (a *Article) ListForScan(db *gorm.DB, afterID uint32, limit int) ([]*Article, error) {
+	var articles []*Article
+	err := db.Select("id, cover_image_url, content").
+		Where("id > ? AND is_del = ?", afterID, 0).
+		Order("id").Limit(limit).Find(&articles).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return articles, nil
+}
+
+// 按 ID 顺序分批返回已发布文章的标题、简述和内容，用于建立搜索索引
+func (a *Article) ListForIndex(db *gorm.DB, afterID uint32, limit int) ([]*Article, error) {
+	var articles []*Article
+	err := db.Select("id, title, `desc`, content").
+		Where("id > ? AND state = ? AND is_del = ?", afterID, ArticleStatePublished, 0).
+		Order("id").Limit(limit).Find(&articles).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return articles, nil
+}
+
+// 按 ID 顺序分批返回还没有别名的未删除文章，用于为已有文章生成别名
+func (a *Article) ListWithoutSlug(db *gorm.DB, afterID uint32, limit int) ([]*Article, error) {
+	var articles []*Article
+	err := db.Select("id, title, slug").
+		Where("id > ? AND (slug IS NULL OR slug = ?) AND is_del = ?", afterID, "", 0).
+		Order("id").Limit(limit).Find(&articles).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return articles, nil
+}
+
+// 返回发布时间已到的审核中文章
+func (a *Article) ListDue(db *gorm.DB, now uint32, limit int) ([]*Article, error) {
+	var articles []*Article
+	err := db.Where("state = ? AND publish_on > ? AND publish_on <= ? AND is_del = ?", ArticleStateInReview, 0, now, 0).
+		Order("publish_on, id").Limit(limit).Find(&articles).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return articles, nil
+}
+
+func (a *Article) Create(db *gorm.DB) error {
+	return db.Create(a).Error
+}
+
+func (a *Article) Update(db *gorm.DB, values interface{}) error {
+	if err := db.Model(&Article{}).Where("id = ? AND is_del = ?", a.Common.ID, 0).Updates(values).Error; err != nil {
+		return err
+	}
+	return nil
+}
+
+// 只更新指定的列，不触发更新回调，modified_on 保持不变
+func (a *Article) UpdateColumns(db *gorm.DB, values interface{}) error {
+	return db.Model(&Article{}).Where("id = ? AND is_del = ?", a.Common.ID, 0).UpdateColumns(values).Error
+}
+
+// 仅当文章仍处于 a.State 状态时更新，返回是否更新成功，避免并发的状态变更互相覆盖
+func (a *Article) UpdateIfState(db *gorm.DB, values interface{}) (bool, error) {
+	db = db.Model(&Article{}).Where("id = ? AND state = ? AND is_del = ?", a.Common.ID, a.State, 0).Updates(values)
+	if db.Error != nil {
+		return false, db.Error
+	}
+
+	return db.RowsAffected > 0, nil
+}
+
+func (a *Article) Delete(db *gorm.DB) error {
+	return db.Where("id = ? AND is_del = ?", a.Common.ID, 0).Delete(a).Error
+}
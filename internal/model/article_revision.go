@@ -0,0 +1,80 @@
+package model
+
+import "github.com/jinzhu/gorm"
+
+// 文章的历史版本，每次修改标题、简述、内容或封面后保存一份快照
+// created_by 为产生该版本的修改者
+type ArticleRevision struct {
+	*Common
+	ArticleID     uint32 `json:"article_id"`
+	Revision      uint32 `json:"revision"` // 同一篇文章内从 1 开始递增
+	Title         string `json:"title"`
+	Desc          string `json:"desc"`
+	Content       string `json:"content,omitempty"`
+	CoverImageUrl string `json:"cover_image_url"`
+	RestoredFrom  uint32 `json:"restored_from"` // 由哪个版本恢复而来，为 0 表示普通修改
+}
+
+func (a *ArticleRevision) TableName() string {
+	return "blog_article_revision"
+}
+
+// 按版本号返回文章的全部历史版本，不包含内容
+func (a *ArticleRevision) ListByArticleID(db *gorm.DB) ([]*ArticleRevision, error) {
+	var revisions []*ArticleRevision
+	err := db.Select("id, article_id, revision, title, `desc`, cover_image_url, restored_from, created_by, created_on").
+		Where("article_id = ? AND is_del = ?", a.ArticleID, 0).
+		Order("revision").Find(&revisions).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return revisions, nil
+}
+
+// 返回文章的指定版本，不存在时返回 nil
+func (a *ArticleRevision) GetByRevision(db *gorm.DB) (*ArticleRevision, error) {
+	var revision ArticleRevision
+	err := db.Where("article_id = ? AND revision = ? AND is_del = ?", a.ArticleID, a.Revision, 0).First(&revision).Error
+	if err == gorm.ErrRecordNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &revision, nil
+}
+
+// 返回文章的最新版本，没有历史版本时返回 nil
+func (a *ArticleRevision) GetLatest(db *gorm.DB) (*ArticleRevision, error) {
+	var revision ArticleRevision
+	err := db.Where("article_id = ? AND is_del = ?", a.ArticleID, 0).Order("revision DESC").First(&revision).Error
+	if err == gorm.ErrRecordNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &revision, nil
+}
+
+// 按 ID 顺序分批返回未删除文章的历史版本的封面和内容，用于查找被引用的上传文件
+func (a *ArticleRevision) ListForScan(db *gorm.DB, afterID uint32, limit int) ([]*ArticleRevision, error) {
+	var revisions []*ArticleRevision
+	err := db.Table(a.TableName()+" AS r").
+		Select("r.id, r.cover_image_url, r.content").
+		Joins("INNER JOIN blog_article AS a ON a.id = r.article_id AND a.is_del = 0").
+		Where("r.id > ? AND r.is_del = ?", afterID, 0).
+		Order("r.id").Limit(limit).Find(&revisions).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return revisions, nil
+}
+
+func (a *ArticleRevision) Create(db *gorm.DB) error {
+	return db.Create(a).Error
+}
@@ -0,0 +1,37 @@
+package model
+
+import "github.com/jinzhu/gorm"
+
+// 文章修改别名前使用过的别名，访问旧别名时重定向到当前别名
+type ArticleSlug struct {
+	*Common
+	Slug      string `json:"slug"` // 唯一索引
+	ArticleID uint32 `json:"article_id"`
+}
+
+func (a *ArticleSlug) TableName() string {
+	return "blog_article_slug"
+}
+
+// 返回指定的旧别名，不存在时返回 nil
+func (a *ArticleSlug) Get(db *gorm.DB) (*ArticleSlug, error) {
+	var slug ArticleSlug
+	err := db.Where("slug = ?", a.Slug).First(&slug).Error
+	if err == gorm.ErrRecordNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &slug, nil
+}
+
+func (a *ArticleSlug) Create(db *gorm.DB) error {
+	return db.Create(a).Error
+}
+
+// 文章重新使用旧别名时直接删除记录，释放唯一索引
+func (a *ArticleSlug) Delete(db *gorm.DB) error {
+	return db.Unscoped().Where("slug = ?", a.Slug).Delete(&ArticleSlug{}).Error
+}
@@ -1,11 +1,67 @@
 package model
 
+import "github.com/jinzhu/gorm"
+
 type ArticleTag struct {
 	*Common
-	TageID    uint32 `json:"tag_id"`
+	TagID     uint32 `json:"tag_id"`
 	ArticleID uint32 `json:"article_id"`
 }
 
 func (a *ArticleTag) TableName() string {
 	return "blog_article_tag"
 }
+
+// 返回一组文章关联的所有标签关系
+func (a *ArticleTag) ListByArticleIDs(db *gorm.DB, articleIDs []uint32) ([]*ArticleTag, error) {
+	var articleTags []*ArticleTag
+	err := db.Where("article_id IN (?) AND is_del = ?", articleIDs, 0).Find(&articleTags).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return articleTags, nil
+}
+
+// 统计一组标签各自关联的已发布文章数量
+func (a *ArticleTag) CountArticleByTagIDs(db *gorm.DB, tagIDs []uint32) (map[uint32]int, error) {
+	var results []struct {
+		TagID uint32
+		Count int
+	}
+	err := db.Table(a.TableName()+" AS at").
+		Select("at.tag_id, COUNT(DISTINCT at.article_id) AS count").
+		Joins("INNER JOIN blog_article AS a ON a.id = at.article_id").
+		Where("at.tag_id IN (?) AND at.is_del = ? AND a.state = ? AND a.is_del = ?", tagIDs, 0, ArticleStatePublished, 0).
+		Group("at.tag_id").
+		Scan(&results).Error
+	if err != nil {
+		return nil, err
+	}
+
+	counts := make(map[uint32]int, len(results))
+	for _, r := range results {
+		counts[r.TagID] = r.Count
+	}
+
+	return counts, nil
+}
+
+func (a *ArticleTag) Create(db *gorm.DB) error {
+	return db.Create(a).Error
+}
+
+// 软删除文章下指定标签的关联，tagIDs 为空时删除文章的全部关联
+func (a *ArticleTag) DeleteByArticleID(db *gorm.DB, tagIDs []uint32) error {
+	db = db.Where("article_id = ? AND is_del = ?", a.ArticleID, 0)
+	if len(tagIDs) > 0 {
+		db = db.Where("tag_id IN (?)", tagIDs)
+	}
+
+	return db.Delete(&ArticleTag{}).Error
+}
+
+// 软删除标签的全部关联
+func (a *ArticleTag) DeleteByTagID(db *gorm.DB) error {
+	return db.Where("tag_id = ? AND is_del = ?", a.TagID, 0).Delete(&ArticleTag{}).Error
+}
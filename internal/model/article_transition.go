@@ -0,0 +1,33 @@
+package model
+
+import "github.com/jinzhu/gorm"
+
+// 文章状态变更记录，created_by 和 created_on 为操作人和操作时间
+type ArticleTransition struct {
+	*Common
+	ArticleID  uint32 `json:"article_id"`
+	FromState  uint8  `json:"from_state"`
+	ToState    uint8  `json:"to_state"`
+	OperatorID uint32 `json:"operator_id"`
+	Comment    string `json:"comment"`    // 审核意见等说明
+	PublishOn  uint32 `json:"publish_on"` // 设置定时发布时为计划的发布时间，此时状态不变
+}
+
+func (a *ArticleTransition) TableName() string {
+	return "blog_article_transition"
+}
+
+// 按时间顺序返回文章的全部状态变更
+func (a *ArticleTransition) ListByArticleID(db *gorm.DB) ([]*ArticleTransition, error) {
+	var transitions []*ArticleTransition
+	err := db.Where("article_id = ? AND is_del = ?", a.ArticleID, 0).Order("id").Find(&transitions).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return transitions, nil
+}
+
+func (a *ArticleTransition) Create(db *gorm.DB) error {
+	return db.Create(a).Error
+}
@@ -1,16 +1,21 @@
 package model
 
 import (
+	"errors"
 	"fmt"
 	"time"
 
 	"github.com/go-programming-tour/blog-service/global"
 	"github.com/go-programming-tour/blog-service/pkg/setting"
+	"github.com/go-sql-driver/mysql"
 	"github.com/jinzhu/gorm"
 
 	_ "github.com/jinzhu/gorm/dialects/mysql"
 )
 
+// MySQL 违反唯一索引时的错误码
+const mysqlErrDuplicateEntry = 1062
+
 type Common struct {
 	ID         uint32 `gorm:"primary_key" json:"id"`
 	CreatedBy  string `json:"created_by"`
@@ -131,3 +136,9 @@ func addExtraSpaceIfExist(str string) string {
 
 	return ""
 }
+
+// 判断错误是否由违反唯一索引引起
+func IsDuplicateKeyError(err error) bool {
+	var mysqlErr *mysql.MySQLError
+	return errors.As(err, &mysqlErr) && mysqlErr.Number == mysqlErrDuplicateEntry
+}
@@ -0,0 +1,124 @@
+package model
+
+import "github.com/jinzhu/gorm"
+
+// 上传文件的元信息，文件按内容的 SHA-256 保存，相同内容在存储中只有一份
+// 每个上传者各有一条记录，记录自己的原始文件名，相同内容的记录共用同一个 Path
+type File struct {
+	*Common
+	Hash           string `json:"hash"`
+	Path           string `json:"path"` // 在存储后端中的文件名
+	OriginalName   string `json:"original_name"`
+	MimeType       string `json:"mime_type"`
+	Size           int64  `json:"size"`
+	DerivativeSize int64  `json:"derivative_size"` // 衍生图的总大小，与文件一起计入用量
+	RefCount       uint32 `json:"ref_count"`       // 引用文件的文章和历史版本数量，由回收任务统计
+	UploaderID     uint32 `json:"uploader_id"`
+	OrphanedOn     uint32 `json:"orphaned_on"` // 首次发现没有被文章引用的时间，为 0 表示正在使用
+	Private        uint8  `json:"private"`     // 为 1 时只能通过签名地址访问
+}
+
+func (f *File) TableName() string {
+	return "blog_file"
+}
+
+// 根据内容哈希、是否私有和上传者返回文件，不存在时返回 nil
+func (f *File) GetByHash(db *gorm.DB) (*File, error) {
+	var file File
+	err := db.Where("hash = ? AND private = ? AND uploader_id = ? AND is_del = ?", f.Hash, f.Private, f.UploaderID, 0).
+		First(&file).Error
+	if err == gorm.ErrRecordNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &file, nil
+}
+
+// 返回任意一个上传者的相同内容的文件，用于复用存储中已有的内容，不存在时返回 nil
+// 相同内容的公开文件和私有文件分别保存
+func (f *File) GetStoredByHash(db *gorm.DB) (*File, error) {
+	var file File
+	err := db.Where("hash = ? AND private = ? AND is_del = ?", f.Hash, f.Private, 0).Order("id").First(&file).Error
+	if err == gorm.ErrRecordNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &file, nil
+}
+
+// 根据 ID 返回文件，不存在时返回 nil
+func (f *File) Get(db *gorm.DB) (*File, error) {
+	var file File
+	err := db.Where("id = ? AND is_del = ?", f.Common.ID, 0).First(&file).Error
+	if err == gorm.ErrRecordNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &file, nil
+}
+
+// 按 ID 顺序分批返回文件
+func (f *File) ListAfterID(db *gorm.DB, afterID uint32, limit int) ([]*File, error) {
+	var files []*File
+	err := db.Where("id > ? AND is_del = ?", afterID, 0).Order("id").Limit(limit).Find(&files).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return files, nil
+}
+
+// 返回共用同一存储内容的其他未删除文件数量
+func (f *File) CountOthersByPath(db *gorm.DB) (int, error) {
+	var count int
+	err := db.Model(&File{}).Where("path = ? AND id <> ? AND is_del = ?", f.Path, f.Common.ID, 0).Count(&count).Error
+	if err != nil {
+		return 0, err
+	}
+
+	return count, nil
+}
+
+// 在事务中锁定共用同一存储内容的全部未删除文件，回收存储内容和复用存储内容在锁内串行执行
+func (f *File) LockByPath(db *gorm.DB) ([]*File, error) {
+	var files []*File
+	err := db.Set("gorm:query_option", "FOR UPDATE").
+		Where("path = ? AND is_del = ?", f.Path, 0).Order("id").Find(&files).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return files, nil
+}
+
+func (f *File) Create(db *gorm.DB) error {
+	return db.Create(f).Error
+}
+
+// 更新引用计数和未被引用的时间，不修改 modified_on
+func (f *File) UpdateRefCount(db *gorm.DB, refCount, orphanedOn uint32) error {
+	return db.Model(&File{}).Where("id = ? AND is_del = ?", f.Common.ID, 0).
+		UpdateColumns(map[string]interface{}{"ref_count": refCount, "orphaned_on": orphanedOn}).Error
+}
+
+// 记录或清除文件未被引用的时间
+func (f *File) UpdateOrphanedOn(db *gorm.DB, orphanedOn uint32) error {
+	return db.Model(&File{}).Where("id = ? AND is_del = ?", f.Common.ID, 0).
+		UpdateColumn("orphaned_on", orphanedOn).Error
+}
+
+// 软删除文件，返回是否由本次调用删除，唯一索引包含 deleted_on，相同内容再次上传时可以重新创建记录
+func (f *File) Delete(db *gorm.DB) (bool, error) {
+	result := db.Where("id = ? AND is_del = ?", f.Common.ID, 0).Delete(&File{})
+
+	return result.RowsAffected > 0, result.Error
+}
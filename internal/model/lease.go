@@ -0,0 +1,54 @@
+package model
+
+import (
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+// 后台任务的租约，多个实例同时运行时只有持有租约的实例执行任务
+type Lease struct {
+	*Common
+	Name      string `json:"name"` // 唯一索引
+	Holder    string `json:"holder"`
+	ExpiresOn uint32 `json:"expires_on"`
+}
+
+func (l *Lease) TableName() string {
+	return "blog_lease"
+}
+
+// 获取或续期租约，租约由其他实例持有且未过期时返回 false
+func (l *Lease) Acquire(db *gorm.DB, ttl time.Duration) (bool, error) {
+	now := time.Now()
+	expiresOn := uint32(now.Add(ttl).Unix())
+	result := db.Model(&Lease{}).
+		Where("name = ? AND (holder = ? OR expires_on < ?) AND is_del = ?", l.Name, l.Holder, now.Unix(), 0).
+		Updates(map[string]interface{}{"holder": l.Holder, "expires_on": expiresOn})
+	if result.Error != nil {
+		return false, result.Error
+	}
+	if result.RowsAffected > 0 {
+		return true, nil
+	}
+
+	var lease Lease
+	err := db.Where("name = ? AND is_del = ?", l.Name, 0).First(&lease).Error
+	if err == gorm.ErrRecordNotFound {
+		// 第一次使用时创建租约，并发创建时唯一索引保证只有一个实例成功
+		l.ExpiresOn = expiresOn
+		return db.Create(l).Error == nil, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	// 同一秒内续期时没有字段发生变化，影响行数为 0
+	return lease.Holder == l.Holder && int64(lease.ExpiresOn) >= now.Unix(), nil
+}
+
+// 释放自己持有的租约，其他实例可以立即获取
+func (l *Lease) Release(db *gorm.DB) error {
+	return db.Model(&Lease{}).Where("name = ? AND holder = ? AND is_del = ?", l.Name, l.Holder, 0).
+		UpdateColumn("expires_on", 0).Error
+}
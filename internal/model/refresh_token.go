@@ -0,0 +1,57 @@
+package model
+
+import "github.com/jinzhu/gorm"
+
+// 刷新令牌，只保存令牌的哈希值
+// 同一次登录轮换出的令牌属于同一个 family，复用旧令牌时整个 family 失效
+type RefreshToken struct {
+	*Common
+	UserID    uint32 `json:"user_id"`
+	FamilyID  string `json:"family_id"`
+	TokenHash string `json:"-"`
+	ExpiresOn uint32 `json:"expires_on"`
+	UsedOn    uint32 `json:"used_on"`    // 被轮换的时间，非 0 表示已使用
+	RevokedOn uint32 `json:"revoked_on"` // 被吊销的时间，非 0 表示已失效
+}
+
+func (r *RefreshToken) TableName() string {
+	return "blog_refresh_token"
+}
+
+// 根据令牌哈希返回刷新令牌
+func (r *RefreshToken) GetByHash(db *gorm.DB) (*RefreshToken, error) {
+	var token RefreshToken
+	err := db.Where("token_hash = ? AND is_del = ?", r.TokenHash, 0).First(&token).Error
+	if err == gorm.ErrRecordNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &token, nil
+}
+
+func (r *RefreshToken) Create(db *gorm.DB) error {
+	return db.Create(r).Error
+}
+
+// 将未使用的令牌标记为已使用，返回是否标记成功
+// 并发轮换同一个令牌时只有一个请求能够成功
+func (r *RefreshToken) MarkUsed(db *gorm.DB, usedOn uint32) (bool, error) {
+	db = db.Model(&RefreshToken{}).
+		Where("id = ? AND used_on = ? AND revoked_on = ?", r.Common.ID, 0, 0).
+		Updates(map[string]interface{}{"used_on": usedOn})
+	if db.Error != nil {
+		return false, db.Error
+	}
+
+	return db.RowsAffected == 1, nil
+}
+
+// 吊销 family 下的全部令牌
+func (r *RefreshToken) RevokeFamily(db *gorm.DB, revokedOn uint32) error {
+	return db.Model(&RefreshToken{}).
+		Where("family_id = ? AND revoked_on = ?", r.FamilyID, 0).
+		Updates(map[string]interface{}{"revoked_on": revokedOn}).Error
+}
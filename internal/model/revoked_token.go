@@ -0,0 +1,30 @@
+package model
+
+import "github.com/jinzhu/gorm"
+
+// 已吊销的访问令牌，以 JWT ID 为键
+// ExpiresOn 为令牌本身的过期时间，过期后的记录可以清理
+type RevokedToken struct {
+	*Common
+	JTI       string `json:"jti" gorm:"column:jti"`
+	ExpiresOn uint32 `json:"expires_on"`
+}
+
+func (r *RevokedToken) TableName() string {
+	return "blog_revoked_token"
+}
+
+// 返回 jti 是否已被吊销
+func (r *RevokedToken) Exist(db *gorm.DB) (bool, error) {
+	var count int
+	err := db.Model(&RevokedToken{}).Where("jti = ? AND is_del = ?", r.JTI, 0).Count(&count).Error
+	if err != nil {
+		return false, err
+	}
+
+	return count > 0, nil
+}
+
+func (r *RevokedToken) Create(db *gorm.DB) error {
+	return db.Create(r).Error
+}
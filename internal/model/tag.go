@@ -5,10 +5,17 @@ import (
 	"github.com/jinzhu/gorm"
 )
 
+// 标签状态，公开接口只返回启用的标签
+const (
+	TagStateDisabled uint8 = 0
+	TagStateEnabled  uint8 = 1
+)
+
 type Tag struct {
-	*Common        // 匿名结构体
-	Name    string `json:"name"`
-	State   uint8  `json:"state"`
+	*Common             // 匿名结构体
+	Name         string `json:"name"`
+	State        uint8  `json:"state"`
+	ArticleCount *int   `json:"article_count,omitempty" gorm:"-"` // 已发布文章数，按需填充
 }
 
 // swagger结构体
@@ -56,6 +63,27 @@ func (t *Tag) List(db *gorm.DB, pageOffset, pageSize int) ([]*Tag, error) {
 	return tags, nil
 }
 
+// 返回指定 id 的标签
+func (t *Tag) Get(db *gorm.DB) (*Tag, error) {
+	var tag Tag
+	db = db.Where("id = ? AND state = ? AND is_del = ?", t.Common.ID, t.State, 0)
+	if err := db.First(&tag).Error; err != nil {
+		return nil, err
+	}
+
+	return &tag, nil
+}
+
+// 返回指定 id 集合中未删除的标签
+func (t *Tag) ListByIDs(db *gorm.DB, ids []uint32) ([]*Tag, error) {
+	var tags []*Tag
+	if err := db.Where("id IN (?) AND is_del = ?", ids, 0).Find(&tags).Error; err != nil {
+		return nil, err
+	}
+
+	return tags, nil
+}
+
 func (t *Tag) Create(db *gorm.DB) error {
 	return db.Create(&t).Error
 }
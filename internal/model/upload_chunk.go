@@ -0,0 +1,42 @@
+package model
+
+import "github.com/jinzhu/gorm"
+
+// 已接收的上传分片，分片内容保存在存储中
+type UploadChunk struct {
+	*Common
+	SessionID uint32 `json:"session_id"`
+	Start     int64  `json:"start"` // 分片在文件中的起始偏移
+	Size      int64  `json:"size"`
+	Hash      string `json:"hash"` // 分片内容的 SHA-256
+}
+
+func (u *UploadChunk) TableName() string {
+	return "blog_upload_chunk"
+}
+
+// 返回会话已接收的全部分片，按起始偏移排序
+func (u *UploadChunk) ListBySessionID(db *gorm.DB) ([]*UploadChunk, error) {
+	var chunks []*UploadChunk
+	err := db.Where("session_id = ? AND is_del = ?", u.SessionID, 0).Order("start, id").Find(&chunks).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return chunks, nil
+}
+
+func (u *UploadChunk) Create(db *gorm.DB) error {
+	return db.Create(u).Error
+}
+
+// 删除会话在指定偏移的分片，(session_id, start) 唯一，被替换的记录直接删除
+func (u *UploadChunk) DeleteByStart(db *gorm.DB) error {
+	return db.Unscoped().Where("session_id = ? AND start = ?", u.SessionID, u.Start).
+		Delete(&UploadChunk{}).Error
+}
+
+// 软删除会话的全部分片
+func (u *UploadChunk) DeleteBySessionID(db *gorm.DB) error {
+	return db.Where("session_id = ? AND is_del = ?", u.SessionID, 0).Delete(&UploadChunk{}).Error
+}
@@ -0,0 +1,93 @@
+package model
+
+import "github.com/jinzhu/gorm"
+
+// 分片上传会话的状态
+const (
+	UploadSessionUploading uint8 = iota
+	UploadSessionCompleted
+)
+
+// 分片上传会话，分片全部接收后合并为一个文件
+type UploadSession struct {
+	*Common
+	UploadID   string `json:"upload_id"` // 对外使用的随机 ID
+	FileType   int    `json:"file_type"`
+	FileName   string `json:"file_name"`
+	Size       int64  `json:"size"`
+	ChunkSize  int64  `json:"chunk_size"` // 按序号上传时每个分片的大小，tus 上传时为 0
+	Hash       string `json:"hash"`       // 客户端提供的整个文件的 SHA-256，为空时不校验
+	UploaderID uint32 `json:"uploader_id"`
+	ExpiresOn  uint32 `json:"expires_on"`
+	FileID     uint32 `json:"file_id"` // 合并完成后生成的文件
+	Private    uint8  `json:"private"` // 合并后的文件是否为私有文件
+	State      uint8  `json:"state"`
+	// 创建会话时在用量中预留的大小，完成、放弃或过期时释放后为 0
+	ReservedSize int64 `json:"reserved_size"`
+}
+
+func (u *UploadSession) TableName() string {
+	return "blog_upload_session"
+}
+
+// 根据对外 ID 返回上传会话，不存在时返回 nil
+func (u *UploadSession) GetByUploadID(db *gorm.DB) (*UploadSession, error) {
+	var session UploadSession
+	err := db.Where("upload_id = ? AND is_del = ?", u.UploadID, 0).First(&session).Error
+	if err == gorm.ErrRecordNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &session, nil
+}
+
+// 在事务中锁定上传会话，同一会话的分片写入在锁内串行执行，不存在时返回 nil
+func (u *UploadSession) Lock(db *gorm.DB) (*UploadSession, error) {
+	var session UploadSession
+	err := db.Set("gorm:query_option", "FOR UPDATE").
+		Where("id = ? AND is_del = ?", u.Common.ID, 0).First(&session).Error
+	if err == gorm.ErrRecordNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &session, nil
+}
+
+// 返回在 now 之前过期的上传会话
+func (u *UploadSession) ListExpired(db *gorm.DB, now uint32, limit int) ([]*UploadSession, error) {
+	var sessions []*UploadSession
+	err := db.Where("expires_on < ? AND is_del = ?", now, 0).Order("id").Limit(limit).Find(&sessions).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return sessions, nil
+}
+
+func (u *UploadSession) Create(db *gorm.DB) error {
+	return db.Create(u).Error
+}
+
+// 将会话标记为已完成并记录生成的文件
+func (u *UploadSession) Complete(db *gorm.DB, fileID uint32) error {
+	return db.Model(&UploadSession{}).Where("id = ? AND is_del = ?", u.Common.ID, 0).
+		Updates(map[string]interface{}{"file_id": fileID, "state": UploadSessionCompleted}).Error
+}
+
+// 清除会话的预留大小，返回是否由本次调用清除，保证预留只被释放一次
+func (u *UploadSession) ClearReservation(db *gorm.DB) (bool, error) {
+	result := db.Model(&UploadSession{}).Where("id = ? AND reserved_size > ?", u.Common.ID, 0).
+		UpdateColumn("reserved_size", 0)
+
+	return result.RowsAffected > 0, result.Error
+}
+
+func (u *UploadSession) Delete(db *gorm.DB) error {
+	return db.Where("id = ? AND is_del = ?", u.Common.ID, 0).Delete(u).Error
+}
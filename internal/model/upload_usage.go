@@ -0,0 +1,67 @@
+package model
+
+import (
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+// 上传用量的归属方，每个用户分别计数，另有整个服务的合计
+const (
+	UsageOwnerUser  = "user:"
+	UsageOwnerTotal = "total"
+)
+
+// 上传用量的计数，与文件记录和上传会话在同一个事务中更新，检查配额时不需要统计文件表
+type UploadUsage struct {
+	*Common
+	Owner      string `json:"owner"` // user:{用户 ID} 或 total
+	Size       int64  `json:"size"`  // 已保存文件及其衍生图的总大小
+	Files      int    `json:"files"`
+	StagedSize int64  `json:"staged_size"` // 未完成的分片上传预留的大小
+}
+
+func (u *UploadUsage) TableName() string {
+	return "blog_upload_usage"
+}
+
+// 根据归属方返回用量，还没有上传过文件时返回 nil
+func (u *UploadUsage) GetByOwner(db *gorm.DB) (*UploadUsage, error) {
+	var usage UploadUsage
+	err := db.Where("owner = ? AND is_del = ?", u.Owner, 0).First(&usage).Error
+	if err == gorm.ErrRecordNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &usage, nil
+}
+
+// 归属方的计数不存在时创建，owner 上的唯一索引保证并发创建时只有一条
+func (u *UploadUsage) CreateIfNotExist(db *gorm.DB) error {
+	now := time.Now().Unix()
+
+	return db.Exec("INSERT IGNORE INTO blog_upload_usage (owner, created_on, modified_on) VALUES (?, ?, ?)",
+		u.Owner, now, now).Error
+}
+
+// 按增量更新用量，增量为正时只有更新后不超过配额才会更新，配额为 0 时不限制
+// 返回是否更新，计数不存在或超过配额时返回 false
+func (u *UploadUsage) Add(db *gorm.DB, maxSize int64, maxFiles int) (bool, error) {
+	db = db.Model(&UploadUsage{}).Where("owner = ? AND is_del = ?", u.Owner, 0)
+	if added := u.Size + u.StagedSize; maxSize > 0 && added > 0 {
+		db = db.Where("size + staged_size + ? <= ?", added, maxSize)
+	}
+	if maxFiles > 0 && u.Files > 0 {
+		db = db.Where("files + ? <= ?", u.Files, maxFiles)
+	}
+	result := db.UpdateColumns(map[string]interface{}{
+		"size":        gorm.Expr("GREATEST(size + ?, 0)", u.Size),
+		"files":       gorm.Expr("GREATEST(files + ?, 0)", u.Files),
+		"staged_size": gorm.Expr("GREATEST(staged_size + ?, 0)", u.StagedSize),
+	})
+
+	return result.RowsAffected > 0, result.Error
+}
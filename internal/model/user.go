@@ -0,0 +1,73 @@
+package model
+
+import "github.com/jinzhu/gorm"
+
+// 用户角色
+const (
+	UserRoleAdmin  = "admin"  // 管理员，拥有全部权限
+	UserRoleEditor = "editor" // 编辑，可以管理标签和所有文章
+	UserRoleAuthor = "author" // 作者，只能管理自己的文章
+	UserRoleReader = "reader" // 读者，只读
+)
+
+// 用户结构体，密码只保存哈希值
+type User struct {
+	*Common
+	Username string `json:"username"`
+	Password string `json:"-"`
+	Nickname string `json:"nickname"`
+	Role     string `json:"role"`
+	State    uint8  `json:"state"`
+}
+
+func (u *User) TableName() string {
+	return "blog_user"
+}
+
+// 根据用户名返回未删除的用户，用户不存在时返回 nil
+func (u *User) GetByUsername(db *gorm.DB) (*User, error) {
+	var user User
+	db = db.Where("username = ? AND is_del = ?", u.Username, 0)
+	err := db.First(&user).Error
+	if err == gorm.ErrRecordNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &user, nil
+}
+
+// 返回指定 id 的未删除用户，用户不存在时返回 nil
+func (u *User) Get(db *gorm.DB) (*User, error) {
+	var user User
+	err := db.Where("id = ? AND is_del = ?", u.Common.ID, 0).First(&user).Error
+	if err == gorm.ErrRecordNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &user, nil
+}
+
+// 返回指定角色的未删除用户数量
+func (u *User) CountByRole(db *gorm.DB) (int, error) {
+	var count int
+	err := db.Model(&User{}).Where("role = ? AND is_del = ?", u.Role, 0).Count(&count).Error
+	if err != nil {
+		return 0, err
+	}
+
+	return count, nil
+}
+
+func (u *User) Create(db *gorm.DB) error {
+	return db.Create(u).Error
+}
+
+func (u *User) Update(db *gorm.DB, values interface{}) error {
+	return db.Model(&User{}).Where("id = ? AND is_del = ?", u.Common.ID, 0).Updates(values).Error
+}
@@ -8,9 +8,16 @@ import (
 	"github.com/go-programming-tour/blog-service/pkg/errcode"
 )
 
-// 生成 Token
+// @Summary 用户登录，生成 Token
+// @Produce  json
+// @Param username body string true "用户名"
+// @Param password body string true "密码"
+// @Success 200 {object} service.TokenPair "成功"
+// @Failure 400 {object} errcode.Error "请求错误"
+// @Failure 401 {object} errcode.Error "鉴权失败"
+// @Router /auth [post]
 func GetAuth(c *gin.Context) {
-	param := service.AuthRequest{}
+	param := service.LoginRequest{}
 	response := app.NewResponse(c)
 	valid, errs := app.BindAndValid(c, &param)
 	if !valid {
@@ -20,19 +27,113 @@ func GetAuth(c *gin.Context) {
 	}
 
 	svc := service.New(c.Request.Context())
-	if err := svc.CheckAuth(&param); err != nil {
+	user, err := svc.CheckAuth(&param)
+	if err != nil {
 		global.Logger.ErrorfT("svc.CheckAuth err: %v", err)
 		response.ToErrorResponse(errcode.UnauthorizedAuthNotExist)
 		return
 	}
-	token, err := app.GenerateToken(param.AppKey, param.AppSecret)
+	tokens, err := svc.IssueTokens(user, "")
 	if err != nil {
-		global.Logger.ErrorfT("app.GenerateToken err: %v", err)
+		global.Logger.ErrorfT("svc.IssueTokens err: %v", err)
 		response.ToErrorResponse(errcode.UnauthorizedTokenGenerate)
 		return
 	}
 
-	response.ToResponse(gin.H{
-		"token": token,
-	})
+	response.ToResponse(tokens)
+}
+
+// @Summary 轮换刷新令牌
+// @Produce  json
+// @Param refresh_token body string true "刷新令牌"
+// @Success 200 {object} service.TokenPair "成功"
+// @Failure 400 {object} errcode.Error "请求错误"
+// @Failure 401 {object} errcode.Error "鉴权失败"
+// @Router /auth/refresh [post]
+func RefreshToken(c *gin.Context) {
+	param := service.RefreshTokenRequest{}
+	response := app.NewResponse(c)
+	valid, errs := app.BindAndValid(c, &param)
+	if !valid {
+		global.Logger.ErrorfT("app.BindAndValid errs: %v", errs)
+		response.ToErrorResponse(errcode.InvalidParams.WithDetails(errs.Errors()...))
+		return
+	}
+
+	svc := service.New(c.Request.Context())
+	tokens, err := svc.RefreshToken(&param)
+	if err == service.ErrRefreshTokenInvalid || err == service.ErrRefreshTokenReused {
+		global.Logger.ErrorfT("svc.RefreshToken err: %v", err)
+		response.ToErrorResponse(errcode.UnauthorizedRefreshToken)
+		return
+	}
+	if err != nil {
+		global.Logger.ErrorfT("svc.RefreshToken err: %v", err)
+		response.ToErrorResponse(errcode.UnauthorizedTokenGenerate)
+		return
+	}
+
+	response.ToResponse(tokens)
+}
+
+// @Summary 退出登录，吊销当前令牌
+// @Produce  json
+// @Param token header string true "访问令牌"
+// @Param refresh_token body string false "刷新令牌"
+// @Success 200 {string} string "成功"
+// @Failure 401 {object} errcode.Error "鉴权失败"
+// @Failure 500 {object} errcode.Error "内部错误"
+// @Router /auth/logout [post]
+func Logout(c *gin.Context) {
+	param := service.LogoutRequest{}
+	response := app.NewResponse(c)
+	valid, errs := app.BindAndValid(c, &param)
+	if !valid {
+		global.Logger.ErrorfT("app.BindAndValid errs: %v", errs)
+		response.ToErrorResponse(errcode.InvalidParams.WithDetails(errs.Errors()...))
+		return
+	}
+
+	svc := service.New(c.Request.Context())
+	if err := svc.Logout(&param); err != nil {
+		global.Logger.ErrorfT("svc.Logout err: %v", err)
+		response.ToErrorResponse(errcode.ServerError)
+		return
+	}
+
+	response.ToResponse(gin.H{})
+}
+
+// @Summary 注册用户
+// @Produce  json
+// @Param username body string true "用户名" minlength(3) maxlength(32)
+// @Param password body string true "密码" minlength(8) maxlength(72)
+// @Param nickname body string false "昵称" maxlength(100)
+// @Success 200 {object} model.User "成功"
+// @Failure 400 {object} errcode.Error "请求错误"
+// @Failure 500 {object} errcode.Error "内部错误"
+// @Router /auth/register [post]
+func Register(c *gin.Context) {
+	param := service.RegisterRequest{}
+	response := app.NewResponse(c)
+	valid, errs := app.BindAndValid(c, &param)
+	if !valid {
+		global.Logger.ErrorfT("app.BindAndValid errs: %v", errs)
+		response.ToErrorResponse(errcode.InvalidParams.WithDetails(errs.Errors()...))
+		return
+	}
+
+	svc := service.New(c.Request.Context())
+	user, err := svc.Register(&param)
+	if err == service.ErrUserExist {
+		response.ToErrorResponse(errcode.ErrorUserExist)
+		return
+	}
+	if err != nil {
+		global.Logger.ErrorfT("svc.Register err: %v", err)
+		response.ToErrorResponse(errcode.ErrorRegisterUserFail)
+		return
+	}
+
+	response.ToResponse(user)
 }
@@ -0,0 +1,92 @@
+package api
+
+import (
+	"bytes"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-programming-tour/blog-service/global"
+	"github.com/go-programming-tour/blog-service/internal/service"
+	"github.com/go-programming-tour/blog-service/pkg/app"
+	"github.com/go-programming-tour/blog-service/pkg/convert"
+	"github.com/go-programming-tour/blog-service/pkg/errcode"
+)
+
+// @Summary 最新发布文章的 RSS 2.0 订阅源
+// @Produce  xml
+// @Success 200 {string} string "订阅源"
+// @Success 304 {string} string "If-None-Match 或 If-Modified-Since 与当前订阅源一致"
+// @Failure 429 {object} errcode.Error "请求过多"
+// @Failure 500 {object} errcode.Error "内部错误"
+// @Router /feed.rss [get]
+func RSSFeed(c *gin.Context) {
+	articleFeed(c, service.FeedFormatRSS)
+}
+
+// @Summary 最新发布文章的 Atom 订阅源
+// @Produce  xml
+// @Success 200 {string} string "订阅源"
+// @Success 304 {string} string "If-None-Match 或 If-Modified-Since 与当前订阅源一致"
+// @Failure 429 {object} errcode.Error "请求过多"
+// @Failure 500 {object} errcode.Error "内部错误"
+// @Router /feed.atom [get]
+func AtomFeed(c *gin.Context) {
+	articleFeed(c, service.FeedFormatAtom)
+}
+
+// @Summary 标签下最新发布文章的 Atom 订阅源
+// @Produce  xml
+// @Param id path int true "标签 ID"
+// @Success 200 {string} string "订阅源"
+// @Success 304 {string} string "If-None-Match 或 If-Modified-Since 与当前订阅源一致"
+// @Failure 400 {object} errcode.Error "请求错误"
+// @Failure 404 {object} errcode.Error "标签不存在或未启用"
+// @Failure 429 {object} errcode.Error "请求过多"
+// @Failure 500 {object} errcode.Error "内部错误"
+// @Router /tags/{id}/feed.atom [get]
+func TagAtomFeed(c *gin.Context) {
+	idStr := convert.StrTo(c.Param("id"))
+	param := service.TagFeedRequest{ID: idStr.MustUInt32()}
+	response := app.NewResponse(c)
+	valid, errs := app.BindAndValid(c, &param)
+	if !valid {
+		global.Logger.ErrorfT("app.BindAndValid fail. errs: %v", errs)
+		response.ToErrorResponse(errcode.InvalidParams.WithDetails(errs.Errors()...))
+		return
+	}
+
+	svc := service.New(c.Request.Context())
+	result, err := svc.GetTagFeed(&param, service.FeedFormatAtom)
+	if err == service.ErrTagNotFound {
+		response.ToErrorResponse(errcode.NotFound)
+		return
+	}
+	if err != nil {
+		global.Logger.ErrorfT("svc.GetTagFeed err: %v", err)
+		response.ToErrorResponse(errcode.ErrorGetFeedFail)
+		return
+	}
+
+	serveFeed(c, result)
+}
+
+func articleFeed(c *gin.Context, format string) {
+	svc := service.New(c.Request.Context())
+	result, err := svc.GetArticleFeed(format)
+	if err != nil {
+		global.Logger.ErrorfT("svc.GetArticleFeed err: %v", err)
+		app.NewResponse(c).ToErrorResponse(errcode.ErrorGetFeedFail)
+		return
+	}
+
+	serveFeed(c, result)
+}
+
+// 由 http.ServeContent 根据 ETag 和 Last-Modified 处理条件请求，内容未变化时返回 304
+func serveFeed(c *gin.Context, result *service.FeedResult) {
+	c.Header("Content-Type", result.ContentType)
+	c.Header("ETag", result.ETag)
+	c.Header("Cache-Control", "public, max-age="+strconv.Itoa(int(global.AppSetting.FeedCacheTTL.Seconds())))
+	http.ServeContent(c.Writer, c.Request, "", result.LastModified, bytes.NewReader(result.Body))
+}
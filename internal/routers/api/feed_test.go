@@ -0,0 +1,87 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-programming-tour/blog-service/global"
+	"github.com/go-programming-tour/blog-service/internal/service"
+	"github.com/go-programming-tour/blog-service/pkg/feed"
+	"github.com/go-programming-tour/blog-service/pkg/setting"
+)
+
+// 订阅源未变化时条件请求返回 304，不输出内容
+func TestServeFeedConditionalGet(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	global.AppSetting = &setting.AppSetting{FeedCacheTTL: 5 * time.Minute}
+	lastModified := time.Date(2021, 3, 5, 0, 0, 0, 0, time.UTC)
+	result := &service.FeedResult{
+		Body:         []byte("<rss></rss>"),
+		ContentType:  feed.RSSContentType,
+		ETag:         `"abc"`,
+		LastModified: lastModified,
+	}
+	// 通过 engine 处理请求，gin 在处理结束时才写出 304 状态码
+	router := gin.New()
+	router.GET("/feed.rss", func(c *gin.Context) {
+		serveFeed(c, result)
+	})
+
+	tests := []struct {
+		name   string
+		header map[string]string
+		want   int
+	}{
+		{"no condition", nil, http.StatusOK},
+		{"matching etag", map[string]string{"If-None-Match": `"abc"`}, http.StatusNotModified},
+		{"etag in list", map[string]string{"If-None-Match": `"x", "abc"`}, http.StatusNotModified},
+		{"other etag", map[string]string{"If-None-Match": `"x"`}, http.StatusOK},
+		{"not modified since", map[string]string{"If-Modified-Since": lastModified.Format(http.TimeFormat)}, http.StatusNotModified},
+		{"later since", map[string]string{"If-Modified-Since": lastModified.Add(time.Hour).Format(http.TimeFormat)}, http.StatusNotModified},
+		{"modified since", map[string]string{"If-Modified-Since": lastModified.Add(-time.Second).Format(http.TimeFormat)}, http.StatusOK},
+		// 同时带有两者时以 If-None-Match 为准
+		{
+			"other etag overrides since",
+			map[string]string{"If-None-Match": `"x"`, "If-Modified-Since": lastModified.Format(http.TimeFormat)},
+			http.StatusOK,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodGet, "/feed.rss", nil)
+			for k, v := range tt.header {
+				req.Header.Set(k, v)
+			}
+			router.ServeHTTP(w, req)
+
+			if w.Code != tt.want {
+				t.Fatalf("status = %d, want %d", w.Code, tt.want)
+			}
+			if got := w.Header().Get("ETag"); got != `"abc"` {
+				t.Errorf("ETag = %q", got)
+			}
+			if got := w.Header().Get("Cache-Control"); got != "public, max-age=300" {
+				t.Errorf("Cache-Control = %q", got)
+			}
+			if tt.want == http.StatusNotModified {
+				if w.Body.Len() != 0 {
+					t.Errorf("body = %q, want empty", w.Body.String())
+				}
+				return
+			}
+			if got := w.Header().Get("Content-Type"); got != feed.RSSContentType {
+				t.Errorf("Content-Type = %q", got)
+			}
+			if got := w.Header().Get("Last-Modified"); got != lastModified.Format(http.TimeFormat) {
+				t.Errorf("Last-Modified = %q", got)
+			}
+			if w.Body.String() != "<rss></rss>" {
+				t.Errorf("body = %q", w.Body.String())
+			}
+		})
+	}
+}
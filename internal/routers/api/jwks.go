@@ -0,0 +1,17 @@
+package api
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/go-programming-tour/blog-service/pkg/app"
+)
+
+// @Summary 获取验证 Token 的公钥集合
+// @Produce  json
+// @Success 200 {array} app.JWK "成功"
+// @Router /.well-known/jwks.json [get]
+func GetJWKS(c *gin.Context) {
+	c.Header("Cache-Control", "public, max-age=300")
+	app.NewResponse(c).ToResponse(gin.H{
+		"keys": app.GetJWKS(),
+	})
+}
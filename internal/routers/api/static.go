@@ -0,0 +1,98 @@
+package api
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-programming-tour/blog-service/global"
+	"github.com/go-programming-tour/blog-service/pkg/app"
+	"github.com/go-programming-tour/blog-service/pkg/errcode"
+	"github.com/go-programming-tour/blog-service/pkg/upload"
+)
+
+// @Summary 访问上传的文件，私有文件需要签名地址
+// @Param name path string true "文件名"
+// @Param expires query int false "签名地址的过期时间，私有文件必填"
+// @Param uid query int false "签名地址绑定的用户，需要在 header 中携带该用户的 token"
+// @Param token header string false "绑定用户的签名地址必填，不接受 query 中的 token"
+// @Param sig query string false "签名，私有文件必填"
+// @Success 200 {string} string "文件内容"
+// @Failure 403 {object} errcode.Error "签名无效或已过期"
+// @Failure 404 {object} errcode.Error "文件不存在"
+// @Router /static/{name} [get]
+func ServeFile(c *gin.Context) {
+	response := app.NewResponse(c)
+	name := strings.TrimPrefix(path.Clean("/"+c.Param("name")), "/")
+	if name == "" || upload.IsInternal(name) {
+		response.ToErrorResponse(errcode.NotFound)
+		return
+	}
+
+	cacheControl := "public, max-age=31536000, immutable"
+	if upload.IsPrivate(name) {
+		userID, err := upload.VerifySignature(name, c.Query("expires"), c.Query("uid"), c.Query("sig"))
+		if err != nil {
+			response.ToErrorResponse(errcode.ErrorFileSignatureInvalid)
+			return
+		}
+		// 签名已经绑定用户，令牌只从 header 读取，不会随地址出现在日志和 Referer 中
+		if userID != 0 {
+			claims, ok := app.ClaimsFromContext(c.Request.Context())
+			if !ok || claims.UserID != userID {
+				response.ToErrorResponse(errcode.Forbidden)
+				return
+			}
+		}
+		cacheControl = "private, no-store"
+	}
+
+	storage := upload.GetStorage()
+	info, err := storage.Stat(c.Request.Context(), name)
+	if err != nil {
+		response.ToErrorResponse(storageError(name, err))
+		return
+	}
+	r, err := storage.Get(c.Request.Context(), name)
+	if err != nil {
+		response.ToErrorResponse(storageError(name, err))
+		return
+	}
+	defer r.Close()
+
+	c.Header("Cache-Control", cacheControl)
+	c.Header("X-Content-Type-Options", "nosniff")
+	if info.ContentType != "" {
+		c.Header("Content-Type", info.ContentType)
+	}
+	serveObject(c, info, r)
+}
+
+func storageError(name string, err error) *errcode.Error {
+	if errors.Is(err, upload.ErrObjectNotExist) {
+		return errcode.NotFound
+	}
+	global.Logger.ErrorfT("storage.Get %s err: %v", name, err)
+
+	return errcode.ServerError
+}
+
+// 本地文件支持 Range 和条件请求，其余存储直接输出内容
+func serveObject(c *gin.Context, info *upload.ObjectInfo, r io.Reader) {
+	if rs, ok := r.(io.ReadSeeker); ok {
+		http.ServeContent(c.Writer, c.Request, "", info.ModTime, rs)
+		return
+	}
+
+	if info.Size >= 0 {
+		c.Header("Content-Length", strconv.FormatInt(info.Size, 10))
+	}
+	c.Status(http.StatusOK)
+	if c.Request.Method != http.MethodHead {
+		io.Copy(c.Writer, r)
+	}
+}
@@ -0,0 +1,198 @@
+package api
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-programming-tour/blog-service/global"
+	"github.com/go-programming-tour/blog-service/internal/service"
+	"github.com/go-programming-tour/blog-service/pkg/app"
+	"github.com/go-programming-tour/blog-service/pkg/convert"
+	"github.com/go-programming-tour/blog-service/pkg/errcode"
+	"github.com/go-programming-tour/blog-service/pkg/upload"
+)
+
+// 兼容 tus 1.0.0 协议的断点续传接口，与分片上传共用上传会话
+// 合并完成后可以通过 GET /upload/sessions/{id} 获取生成的文件 ID
+const (
+	tusVersion     = "1.0.0"
+	tusExtensions  = "creation,termination,expiration"
+	tusContentType = "application/offset+octet-stream"
+)
+
+// @Summary tus 协议的服务端能力
+// @Success 204 {string} string "成功"
+// @Router /upload/tus [options]
+func (u *UploadHandler) TusOptions(c *gin.Context) {
+	c.Header("Tus-Resumable", tusVersion)
+	c.Header("Tus-Version", tusVersion)
+	c.Header("Tus-Extension", tusExtensions)
+	c.Header("Tus-Max-Size", strconv.FormatInt(upload.GetMaxSizeOfAll(), 10))
+	c.Status(http.StatusNoContent)
+}
+
+// @Summary 创建 tus 上传
+// @Param Upload-Length header int true "文件大小，单位字节"
+// @Param Upload-Metadata header string true "文件元信息：filename 必填，type 为文件类型，sha256 为整个文件的校验和，private 为 true 时上传为私有文件"
+// @Success 201 {string} string "成功，Location 头部为上传地址"
+// @Failure 400 {object} errcode.Error "请求错误"
+// @Failure 413 {object} errcode.Error "文件过大"
+// @Router /upload/tus [post]
+func (u *UploadHandler) TusCreate(c *gin.Context) {
+	if !checkTusResumable(c) {
+		return
+	}
+	response := app.NewResponse(c)
+	size, err := strconv.ParseInt(c.GetHeader("Upload-Length"), 10, 64)
+	if err != nil || size <= 0 {
+		response.ToErrorResponse(errcode.InvalidParams.WithDetails("Upload-Length is required"))
+		return
+	}
+	metadata := parseTusMetadata(c.GetHeader("Upload-Metadata"))
+	name := metadata["filename"]
+	if name == "" {
+		response.ToErrorResponse(errcode.InvalidParams.WithDetails("filename is required in Upload-Metadata"))
+		return
+	}
+	fileType := upload.GetFileTypeByExt(name)
+	if typeStr := convert.StrTo(metadata["type"]); typeStr != "" {
+		fileType = upload.FileType(typeStr.MustInt())
+	}
+	hash := metadata["sha256"]
+	if _, err := hex.DecodeString(hash); err != nil || (hash != "" && len(hash) != 64) {
+		response.ToErrorResponse(errcode.InvalidParams.WithDetails("sha256 must be a hex encoded SHA-256"))
+		return
+	}
+
+	private, _ := strconv.ParseBool(metadata["private"])
+
+	svc := service.New(c.Request.Context())
+	session, err := svc.CreateTusUploadSession(fileType, name, size, hash, private)
+	if err != nil {
+		global.Logger.ErrorfT("svc.CreateTusUploadSession err: %v", err)
+		response.ToErrorResponse(uploadError(err))
+		return
+	}
+
+	c.Header("Location", "/upload/tus/"+session.UploadID)
+	c.Header("Upload-Expires", tusExpires(session.ExpiresOn))
+	c.Status(http.StatusCreated)
+}
+
+// @Summary 查询 tus 上传的偏移量
+// @Param id path string true "上传会话 ID"
+// @Success 200 {string} string "成功，Upload-Offset 头部为已接收的字节数"
+// @Failure 404 {string} string "会话不存在或已过期"
+// @Router /upload/tus/{id} [head]
+func (u *UploadHandler) TusHead(c *gin.Context) {
+	if !checkTusResumable(c) {
+		return
+	}
+	c.Header("Cache-Control", "no-store")
+	svc := service.New(c.Request.Context())
+	session, err := svc.GetUploadSession(c.Param("id"))
+	if err != nil {
+		global.Logger.ErrorfT("svc.GetUploadSession err: %v", err)
+		c.Status(uploadError(err).StatusCode())
+		return
+	}
+
+	c.Header("Upload-Offset", strconv.FormatInt(session.Offset, 10))
+	c.Header("Upload-Length", strconv.FormatInt(session.Size, 10))
+	c.Header("Upload-Expires", tusExpires(session.ExpiresOn))
+	c.Status(http.StatusOK)
+}
+
+// @Summary 在 tus 上传的偏移处追加数据，接收完全部数据后自动合并
+// @Accept  application/offset+octet-stream
+// @Param id path string true "上传会话 ID"
+// @Param Upload-Offset header int true "本次数据的起始偏移"
+// @Success 204 {string} string "成功，Upload-Offset 头部为新的偏移"
+// @Failure 404 {object} errcode.Error "会话不存在或已过期"
+// @Failure 409 {object} errcode.Error "偏移量不一致"
+// @Failure 415 {string} string "Content-Type 不正确"
+// @Router /upload/tus/{id} [patch]
+func (u *UploadHandler) TusPatch(c *gin.Context) {
+	if !checkTusResumable(c) {
+		return
+	}
+	if c.ContentType() != tusContentType {
+		c.Status(http.StatusUnsupportedMediaType)
+		return
+	}
+	response := app.NewResponse(c)
+	offset, err := strconv.ParseInt(c.GetHeader("Upload-Offset"), 10, 64)
+	if err != nil || offset < 0 {
+		response.ToErrorResponse(errcode.InvalidParams.WithDetails("Upload-Offset is required"))
+		return
+	}
+
+	svc := service.New(c.Request.Context())
+	offset, err = svc.AppendUploadChunk(c.Param("id"), offset, c.Request.Body)
+	if err != nil {
+		global.Logger.ErrorfT("svc.AppendUploadChunk err: %v", err)
+		response.ToErrorResponse(uploadError(err))
+		return
+	}
+
+	c.Header("Upload-Offset", strconv.FormatInt(offset, 10))
+	c.Status(http.StatusNoContent)
+}
+
+// @Summary 终止 tus 上传，删除已接收的数据
+// @Param id path string true "上传会话 ID"
+// @Success 204 {string} string "成功"
+// @Failure 404 {object} errcode.Error "会话不存在或已过期"
+// @Router /upload/tus/{id} [delete]
+func (u *UploadHandler) TusDelete(c *gin.Context) {
+	if !checkTusResumable(c) {
+		return
+	}
+	svc := service.New(c.Request.Context())
+	if err := svc.DeleteUploadSession(c.Param("id")); err != nil {
+		global.Logger.ErrorfT("svc.DeleteUploadSession err: %v", err)
+		app.NewResponse(c).ToErrorResponse(uploadError(err))
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// 每个响应都需要带上 Tus-Resumable，客户端的协议版本不受支持时返回 412
+func checkTusResumable(c *gin.Context) bool {
+	c.Header("Tus-Resumable", tusVersion)
+	if c.GetHeader("Tus-Resumable") != tusVersion {
+		c.Header("Tus-Version", tusVersion)
+		c.Status(http.StatusPreconditionFailed)
+		return false
+	}
+
+	return true
+}
+
+// 解析 Upload-Metadata：以逗号分隔的键值对，值为 base64 编码
+func parseTusMetadata(header string) map[string]string {
+	metadata := make(map[string]string)
+	for _, pair := range strings.Split(header, ",") {
+		fields := strings.Fields(pair)
+		if len(fields) == 0 {
+			continue
+		}
+		var value []byte
+		if len(fields) > 1 {
+			value, _ = base64.StdEncoding.DecodeString(fields[1])
+		}
+		metadata[fields[0]] = string(value)
+	}
+
+	return metadata
+}
+
+func tusExpires(expiresOn uint32) string {
+	return time.Unix(int64(expiresOn), 0).UTC().Format(http.TimeFormat)
+}
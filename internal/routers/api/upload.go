@@ -1,6 +1,13 @@
 package api
 
 import (
+	"errors"
+	"io"
+	"io/ioutil"
+	"mime/multipart"
+	"net/http"
+	"strconv"
+
 	"github.com/gin-gonic/gin"
 	"github.com/go-programming-tour/blog-service/global"
 	"github.com/go-programming-tour/blog-service/internal/service"
@@ -17,30 +24,147 @@ func NewUploadHandler() *UploadHandler {
 	return &UploadHandler{}
 }
 
+// @Summary 上传文件
+// @Accept  multipart/form-data
+// @Produce  json
+// @Param token header string true "访问令牌"
+// @Param type formData int true "文件类型：1 图片，2 文档，3 音频，4 视频。需要放在 file 之前，也可以通过 query 传递" Enums(1, 2, 3, 4)
+// @Param private formData bool false "是否为私有文件，私有文件只能通过签名地址访问。需要放在 file 之前，也可以通过 query 传递"
+// @Param file formData file true "文件"
+// @Success 200 {string} string "成功，图片会去除 EXIF 等元数据，并在 file_derivatives 中返回缩略图等衍生图的地址"
+// @Failure 400 {object} errcode.Error "请求错误"
+// @Failure 401 {object} errcode.Error "未登录"
+// @Failure 403 {object} errcode.Error "超出存储配额"
+// @Failure 413 {object} errcode.Error "文件过大"
+// @Failure 500 {object} errcode.Error "内部错误"
+// @Router /upload/file [post]
 func (u *UploadHandler) UploadFile(c *gin.Context) {
 	response := app.NewResponse(c)
-	file, fileHeader, err := c.Request.FormFile("file")
+	typeStr := convert.StrTo(c.Query("type"))
+	fileType := upload.FileType(typeStr.MustInt())
+	private, _ := strconv.ParseBool(c.Query("private"))
+
+	if typeStr != "" && !upload.CheckFileType(fileType) {
+		response.ToErrorResponse(errcode.ErrorUploadTypeInvalid)
+		return
+	}
+
+	// 在读取请求体之前拒绝明显超限的请求，类型放在表单中时按最大的类型限制
+	maxBodySize := upload.GetMaxBodySize(fileType)
+	if c.Request.ContentLength > maxBodySize {
+		response.ToErrorResponse(errcode.ErrorUploadFileTooLarge)
+		return
+	}
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxBodySize)
+
+	reader, err := c.Request.MultipartReader()
 	if err != nil {
-		response.ToErrorResponse(errcode.InvalidParams.WithDetails((err.Error())))
+		response.ToErrorResponse(errcode.InvalidParams.WithDetails(err.Error()))
 		return
 	}
 
-	tmp := convert.StrTo(c.PostForm("type"))
-	fileType := tmp.MustInt()
+	// 逐个读取表单字段，文件部分直接流式写入存储
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			response.ToErrorResponse(errcode.InvalidParams.WithDetails("file is required"))
+			return
+		}
+		if err != nil {
+			response.ToErrorResponse(uploadError(err))
+			return
+		}
+
+		switch part.FormName() {
+		case "type":
+			value, err := ioutil.ReadAll(io.LimitReader(part, 16))
+			if err != nil {
+				response.ToErrorResponse(uploadError(err))
+				return
+			}
+			typeStr = convert.StrTo(value)
+			fileType = upload.FileType(typeStr.MustInt())
+		case "private":
+			value, err := ioutil.ReadAll(io.LimitReader(part, 16))
+			if err != nil {
+				response.ToErrorResponse(uploadError(err))
+				return
+			}
+			private, _ = strconv.ParseBool(string(value))
+		case "file":
+			u.saveFile(c, response, fileType, private, part)
+			return
+		}
+		part.Close()
+	}
+}
 
-	if fileHeader == nil || fileType <= 0 {
+func (u *UploadHandler) saveFile(c *gin.Context, response *app.Response, fileType upload.FileType, private bool, part *multipart.Part) {
+	defer part.Close()
+	if !upload.CheckFileType(fileType) {
+		response.ToErrorResponse(errcode.ErrorUploadTypeInvalid)
+		return
+	}
+	if part.FileName() == "" {
 		response.ToErrorResponse(errcode.InvalidParams)
+		return
+	}
+	if maxSize := upload.GetMaxBodySize(fileType); c.Request.ContentLength > maxSize {
+		response.ToErrorResponse(errcode.ErrorUploadFileTooLarge)
+		return
 	}
 
 	svc := service.New(c.Request.Context())
-	fileInfo, err := svc.UploadFile(upload.FileType(fileType), file, fileHeader)
+	fileInfo, err := svc.UploadFile(fileType, part.FileName(), private, part)
 	if err != nil {
-		global.Logger.Errorf(c, "svc.UploadFile err: %v", err)
-		response.ToErrorResponse(errcode.ErrorUploadFileFail.WithDetails(err.Error()))
+		global.Logger.ErrorfT("svc.UploadFile err: %v", err)
+		response.ToErrorResponse(uploadError(err))
 		return
 	}
 
-	response.ToResponse(gin.H{
-		"file_access_url": fileInfo.AccessUrl,
-	})
+	response.ToResponse(fileInfoResponse(fileInfo))
+}
+
+// 上传完成后返回的文件信息
+func fileInfoResponse(fileInfo *service.FileInfo) gin.H {
+	return gin.H{
+		"file_id":          fileInfo.ID,
+		"file_access_url":  fileInfo.AccessUrl,
+		"file_name":        fileInfo.OriginalName,
+		"file_mime_type":   fileInfo.MimeType,
+		"file_size":        fileInfo.Size,
+		"file_hash":        fileInfo.Hash,
+		"file_derivatives": fileInfo.Derivatives,
+		"file_private":     fileInfo.Private,
+	}
+}
+
+// 将上传过程中的错误转换为错误码
+func uploadError(err error) *errcode.Error {
+	switch {
+	case upload.IsTooLarge(err):
+		return errcode.ErrorUploadFileTooLarge
+	case errors.Is(err, upload.ErrFileTypeMismatch):
+		return errcode.ErrorUploadFileMismatch
+	case errors.Is(err, upload.ErrImageInvalid):
+		return errcode.ErrorUploadImageInvalid
+	case errors.Is(err, upload.ErrImageDimension):
+		return errcode.ErrorUploadImageTooBig
+	case errors.Is(err, upload.ErrFileTypeUnsupported):
+		return errcode.ErrorUploadTypeInvalid
+	case errors.Is(err, service.ErrUploadSessionNotFound):
+		return errcode.ErrorUploadSessionNotFound
+	case errors.Is(err, service.ErrUploadChunkInvalid), errors.Is(err, upload.ErrChunkCorrupted):
+		return errcode.ErrorUploadChunkInvalid
+	case errors.Is(err, service.ErrUploadOffsetMismatch):
+		return errcode.ErrorUploadOffsetMismatch
+	case errors.Is(err, service.ErrUploadIncomplete):
+		return errcode.ErrorUploadIncomplete
+	case errors.Is(err, upload.ErrChecksumMismatch):
+		return errcode.ErrorUploadChecksumMismatch
+	case errors.Is(err, upload.ErrQuotaExceeded):
+		return errcode.ErrorUploadQuotaExceeded
+	}
+
+	return errcode.ErrorUploadFileFail.WithDetails(err.Error())
 }
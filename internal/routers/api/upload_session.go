@@ -0,0 +1,133 @@
+package api
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/go-programming-tour/blog-service/global"
+	"github.com/go-programming-tour/blog-service/internal/service"
+	"github.com/go-programming-tour/blog-service/pkg/app"
+	"github.com/go-programming-tour/blog-service/pkg/convert"
+	"github.com/go-programming-tour/blog-service/pkg/errcode"
+)
+
+// @Summary 创建分片上传会话
+// @Produce  json
+// @Param type body int true "文件类型：1 图片，2 文档，3 音频，4 视频" Enums(1, 2, 3, 4)
+// @Param file_name body string true "文件名"
+// @Param size body int true "文件大小，单位字节"
+// @Param chunk_size body int false "分片大小，单位字节，默认使用配置中的大小"
+// @Param hash body string false "整个文件的 SHA-256，合并时校验"
+// @Success 200 {object} service.UploadSessionInfo "成功"
+// @Failure 400 {object} errcode.Error "请求错误"
+// @Failure 413 {object} errcode.Error "文件过大"
+// @Failure 500 {object} errcode.Error "内部错误"
+// @Router /upload/sessions [post]
+func (u *UploadHandler) CreateSession(c *gin.Context) {
+	param := service.CreateUploadSessionRequest{}
+	response := app.NewResponse(c)
+	valid, errs := app.BindAndValid(c, &param)
+	if !valid {
+		global.Logger.ErrorfT("app.BindAndValid errs: %v", errs)
+		response.ToErrorResponse(errcode.InvalidParams.WithDetails(errs.Errors()...))
+		return
+	}
+
+	svc := service.New(c.Request.Context())
+	session, err := svc.CreateUploadSession(&param)
+	if err != nil {
+		global.Logger.ErrorfT("svc.CreateUploadSession err: %v", err)
+		response.ToErrorResponse(uploadError(err))
+		return
+	}
+
+	response.ToResponse(session)
+}
+
+// @Summary 查询分片上传会话已接收的分片
+// @Produce  json
+// @Param id path string true "上传会话 ID"
+// @Success 200 {object} service.UploadSessionInfo "成功"
+// @Failure 404 {object} errcode.Error "会话不存在或已过期"
+// @Failure 500 {object} errcode.Error "内部错误"
+// @Router /upload/sessions/{id} [get]
+func (u *UploadHandler) GetSession(c *gin.Context) {
+	response := app.NewResponse(c)
+	svc := service.New(c.Request.Context())
+	session, err := svc.GetUploadSession(c.Param("id"))
+	if err != nil {
+		global.Logger.ErrorfT("svc.GetUploadSession err: %v", err)
+		response.ToErrorResponse(uploadError(err))
+		return
+	}
+
+	response.ToResponse(session)
+}
+
+// @Summary 上传指定序号的分片，请求体为分片的原始内容
+// @Accept  application/octet-stream
+// @Produce  json
+// @Param id path string true "上传会话 ID"
+// @Param index path int true "分片序号，从 0 开始"
+// @Success 200 {object} service.UploadSessionInfo "成功"
+// @Failure 400 {object} errcode.Error "请求错误"
+// @Failure 404 {object} errcode.Error "会话不存在或已过期"
+// @Failure 500 {object} errcode.Error "内部错误"
+// @Router /upload/sessions/{id}/chunks/{index} [put]
+func (u *UploadHandler) UploadChunk(c *gin.Context) {
+	response := app.NewResponse(c)
+	indexStr := convert.StrTo(c.Param("index"))
+	index, err := indexStr.Int64()
+	if err != nil {
+		response.ToErrorResponse(errcode.InvalidParams.WithDetails(err.Error()))
+		return
+	}
+
+	svc := service.New(c.Request.Context())
+	session, err := svc.UploadChunk(c.Param("id"), index, c.Request.Body)
+	if err != nil {
+		global.Logger.ErrorfT("svc.UploadChunk err: %v", err)
+		response.ToErrorResponse(uploadError(err))
+		return
+	}
+
+	response.ToResponse(session)
+}
+
+// @Summary 合并全部分片，完成上传
+// @Produce  json
+// @Param id path string true "上传会话 ID"
+// @Success 200 {string} string "成功，返回内容与上传文件相同"
+// @Failure 400 {object} errcode.Error "分片不完整或校验失败"
+// @Failure 404 {object} errcode.Error "会话不存在或已过期"
+// @Failure 500 {object} errcode.Error "内部错误"
+// @Router /upload/sessions/{id}/complete [post]
+func (u *UploadHandler) CompleteSession(c *gin.Context) {
+	response := app.NewResponse(c)
+	svc := service.New(c.Request.Context())
+	fileInfo, err := svc.CompleteUploadSession(c.Param("id"))
+	if err != nil {
+		global.Logger.ErrorfT("svc.CompleteUploadSession err: %v", err)
+		response.ToErrorResponse(uploadError(err))
+		return
+	}
+
+	response.ToResponse(fileInfoResponse(fileInfo))
+}
+
+// @Summary 取消分片上传，删除已接收的分片
+// @Produce  json
+// @Param id path string true "上传会话 ID"
+// @Success 200 {string} string "成功"
+// @Failure 404 {object} errcode.Error "会话不存在或已过期"
+// @Failure 500 {object} errcode.Error "内部错误"
+// @Router /upload/sessions/{id} [delete]
+func (u *UploadHandler) DeleteSession(c *gin.Context) {
+	response := app.NewResponse(c)
+	svc := service.New(c.Request.Context())
+	if err := svc.DeleteUploadSession(c.Param("id")); err != nil {
+		global.Logger.ErrorfT("svc.DeleteUploadSession err: %v", err)
+		response.ToErrorResponse(uploadError(err))
+		return
+	}
+
+	response.ToResponse(gin.H{})
+}
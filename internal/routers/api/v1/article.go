@@ -1,8 +1,15 @@
 package v1
 
 import (
+	"errors"
+	"net/http"
+	"net/url"
+
 	"github.com/gin-gonic/gin"
+	"github.com/go-programming-tour/blog-service/global"
+	"github.com/go-programming-tour/blog-service/internal/service"
 	"github.com/go-programming-tour/blog-service/pkg/app"
+	"github.com/go-programming-tour/blog-service/pkg/convert"
 	"github.com/go-programming-tour/blog-service/pkg/errcode"
 )
 
@@ -16,59 +23,218 @@ func NewArticleHandler() *ArticleHandler {
 // @Summary 获取单篇文章
 // @Produce  json
 // @Param id path int true "文章ID"
+// @Param state query int false "状态：0 草稿，1 已发布，2 审核中，3 已归档，读者只能查看已发布的文章" Enums(0, 1, 2, 3) default(1)
 // @Success 200 {object} model.Article "请求成功"
 // @Failure 400 {object} errcode.Error "请求错误"
+// @Failure 403 {object} errcode.Error "没有权限"
+// @Failure 404 {object} errcode.Error "文章不存在"
 // @Failure 500 {object} errcode.Error "内部错误"
 // @Router /api/v1/articles/{id} [get]
 func (a *ArticleHandler) Get(c *gin.Context) {
-	app.NewResponse(c).ToErrorResponse(errcode.ServerError)
-	return
+	idStr := convert.StrTo(c.Param("id"))
+	param := service.ArticleRequest{ID: idStr.MustUInt32()}
+	response := app.NewResponse(c)
+	valid, errs := app.BindAndValid(c, &param)
+	if !valid {
+		global.Logger.ErrorfT("app.BindAndValid fail. errs: %v", errs)
+		response.ToErrorResponse(errcode.InvalidParams.WithDetails(errs.Errors()...))
+		return
+	}
+
+	svc := service.New(c.Request.Context())
+	article, err := svc.GetArticle(&param)
+	if err == service.ErrPermissionDenied {
+		response.ToErrorResponse(errcode.Forbidden)
+		return
+	}
+	if err == service.ErrArticleNotFound {
+		response.ToErrorResponse(errcode.NotFound)
+		return
+	}
+	if err != nil {
+		global.Logger.ErrorfT("svc.GetArticle err: %v", err)
+		response.ToErrorResponse(errcode.ErrorGetArticleFail)
+		return
+	}
+
+	response.ToResponse(article)
+}
+
+// @Summary 按别名获取单篇文章
+// @Produce  json
+// @Param slug path string true "文章别名"
+// @Param state query int false "状态：0 草稿，1 已发布，2 审核中，3 已归档，读者只能查看已发布的文章" Enums(0, 1, 2, 3) default(1)
+// @Success 200 {object} model.Article "请求成功"
+// @Success 301 {string} string "访问的是旧别名，重定向到文章当前的别名"
+// @Failure 400 {object} errcode.Error "请求错误"
+// @Failure 403 {object} errcode.Error "没有权限"
+// @Failure 404 {object} errcode.Error "文章不存在"
+// @Failure 500 {object} errcode.Error "内部错误"
+// @Router /api/v1/articles/by-slug/{slug} [get]
+func (a *ArticleHandler) GetBySlug(c *gin.Context) {
+	param := service.ArticleSlugRequest{Slug: c.Param("slug")}
+	response := app.NewResponse(c)
+	valid, errs := app.BindAndValid(c, &param)
+	if !valid {
+		global.Logger.ErrorfT("app.BindAndValid fail. errs: %v", errs)
+		response.ToErrorResponse(errcode.InvalidParams.WithDetails(errs.Errors()...))
+		return
+	}
+
+	svc := service.New(c.Request.Context())
+	article, err := svc.GetArticleBySlug(&param)
+	var moved *service.ArticleSlugMoved
+	if errors.As(err, &moved) {
+		location := "/api/v1/articles/by-slug/" + url.PathEscape(moved.Slug)
+		if c.Request.URL.RawQuery != "" {
+			location += "?" + c.Request.URL.RawQuery
+		}
+		c.Redirect(http.StatusMovedPermanently, location)
+		return
+	}
+	if err == service.ErrPermissionDenied {
+		response.ToErrorResponse(errcode.Forbidden)
+		return
+	}
+	if err == service.ErrArticleNotFound {
+		response.ToErrorResponse(errcode.NotFound)
+		return
+	}
+	if err != nil {
+		global.Logger.ErrorfT("svc.GetArticleBySlug err: %v", err)
+		response.ToErrorResponse(errcode.ErrorGetArticleFail)
+		return
+	}
+
+	response.ToResponse(article)
 }
 
 // @Summary 获取多篇文章
 // @Produce  json
-// @Param name query string false "文章名称"
+// @Param title query string false "文章标题" maxlength(100)
 // @Param tag_id query int false "标签ID"
-// @Param state query int false "状态"
+// @Param state query int false "状态：0 草稿，1 已发布，2 审核中，3 已归档，读者只能查看已发布的文章" Enums(0, 1, 2, 3) default(1)
 // @Param page query int false "页码"
 // @Param page_size query int false "每页数量"
 // @Success 200 {object} model.ArticleSwagger "请求成功"
 // @Failure 400 {object} errcode.Error "请求错误"
+// @Failure 403 {object} errcode.Error "没有权限"
 // @Failure 500 {object} errcode.Error "内部错误"
-// @Router /api/v1/articles/ [get]
+// @Router /api/v1/articles [get]
 func (a *ArticleHandler) List(c *gin.Context) {
+	param := service.ArticleListRequest{}
+	response := app.NewResponse(c)
+	valid, errs := app.BindAndValid(c, &param)
+	if !valid {
+		global.Logger.ErrorfT("app.BindAndValid fail. errs: %v", errs)
+		response.ToErrorResponse(errcode.InvalidParams.WithDetails(errs.Errors()...))
+		return
+	}
+
+	svc := service.New(c.Request.Context())
+	pager := app.Pager{Page: app.GetPage(c), PageSize: app.GetPageSize(c)}
+	totalRows, err := svc.CountArticle(&param)
+	if err == service.ErrPermissionDenied {
+		response.ToErrorResponse(errcode.Forbidden)
+		return
+	}
+	if err != nil {
+		global.Logger.ErrorfT("svc.CountArticle err: %v", err)
+		response.ToErrorResponse(errcode.ErrorCountArticleFail)
+		return
+	}
+	articles, err := svc.GetArticleList(&param, &pager)
+	if err != nil {
+		global.Logger.ErrorfT("svc.GetArticleList err: %v", err)
+		response.ToErrorResponse(errcode.ErrorGetArticleListFail)
+		return
+	}
+
+	response.ToResponseList(articles, totalRows)
 }
 
 // @Summary 新增文章
 // @Produce  json
-// @Param tag_id body string false "标签ID"
-// @Param title body string true "文章名称"
-// @Param desc body string false "文章简述"
+// @Param title body string true "文章标题" minlength(2) maxlength(100)
+// @Param slug body string false "文章别名，为空时由标题生成，中文标题使用标题的短哈希，与其他文章重复时追加数字后缀" maxlength(100)
+// @Param desc body string false "文章简述" maxlength(255)
 // @Param cover_image_url body string true "封面图片地址"
 // @Param content body string true "文章内容"
-// @Param created_by body int true "创建者"
-// @Param state body int false "状态
+// @Param tag_ids body []int false "标签ID列表"
+// @Param state body int false "状态：0 草稿，2 直接提交审核" Enums(0, 2) default(0)
 // @Success 200 {object} model.Article "请求成功"
 // @Failure 400 {object} errcode.Error "请求错误"
 // @Failure 500 {object} errcode.Error "内部错误"
 // @Router /api/v1/articles [post]
 func (a *ArticleHandler) Create(c *gin.Context) {
+	param := service.CreateArticleRequest{}
+	response := app.NewResponse(c)
+	valid, errs := app.BindAndValid(c, &param)
+	if !valid {
+		global.Logger.ErrorfT("app.BindAndValid fail. errs: %v", errs)
+		response.ToErrorResponse(errcode.InvalidParams.WithDetails(errs.Errors()...))
+		return
+	}
+
+	svc := service.New(c.Request.Context())
+	article, err := svc.CreateArticle(&param)
+	if err != nil {
+		global.Logger.ErrorfT("svc.CreateArticle err: %v", err)
+		response.ToErrorResponse(errcode.ErrorCreateArticleFail)
+		return
+	}
+
+	response.ToResponse(article)
 }
 
 // @Summary 更新文章
 // @Produce  json
-// @Param tag_id body string false "标签ID"
-// @Param title body string false "文章标题"
-// @Param desc body string false "文章简述"
+// @Param id path int true "文章ID"
+// @Param title body string false "文章标题" maxlength(100)
+// @Param slug body string false "文章别名，为空且修改了标题时由新标题重新生成，原来的别名会重定向到新别名" maxlength(100)
+// @Param desc body string false "文章简述" maxlength(255)
 // @Param cover_image_url body string false "封面图片地址"
 // @Param content body string false "文章内容"
-// @Param modified_by body string true "修改者"
-// @Success 200 {object} model.Article "请求成功"
+// @Param tag_ids body []int false "标签ID列表，传入时覆盖原有标签"
+// @Success 200 {string} string "请求成功"
 // @Failure 400 {object} errcode.Error "请求错误"
+// @Failure 403 {object} errcode.Error "没有权限"
+// @Failure 404 {object} errcode.Error "文章不存在"
+// @Failure 409 {object} errcode.Error "作者只能修改草稿状态的文章"
 // @Failure 500 {object} errcode.Error "内部错误"
 // @Router /api/v1/articles/{id} [put]
 func (a *ArticleHandler) Update(c *gin.Context) {
+	idStr := convert.StrTo(c.Param("id"))
+	param := service.UpdateArticleRequest{ID: idStr.MustUInt32()}
+	response := app.NewResponse(c)
+	valid, errs := app.BindAndValid(c, &param)
+	if !valid {
+		global.Logger.ErrorfT("app.BindAndValid fail. errs: %v", errs)
+		response.ToErrorResponse(errcode.InvalidParams.WithDetails(errs.Errors()...))
+		return
+	}
 
+	svc := service.New(c.Request.Context())
+	err := svc.UpdateArticle(&param)
+	if err == service.ErrPermissionDenied {
+		response.ToErrorResponse(errcode.Forbidden)
+		return
+	}
+	if err == service.ErrArticleNotEditable {
+		response.ToErrorResponse(errcode.ErrorArticleNotEditable)
+		return
+	}
+	if err == service.ErrArticleNotFound {
+		response.ToErrorResponse(errcode.NotFound)
+		return
+	}
+	if err != nil {
+		global.Logger.ErrorfT("svc.UpdateArticle err: %v", err)
+		response.ToErrorResponse(errcode.ErrorUpdateArticleFail)
+		return
+	}
+
+	response.ToResponse(gin.H{})
 }
 
 // @Summary 删除文章
@@ -76,7 +242,309 @@ func (a *ArticleHandler) Update(c *gin.Context) {
 // @Param id path int true "文章ID"
 // @Success 200 {string} string "请求成功"
 // @Failure 400 {object} errcode.Error "请求错误"
+// @Failure 403 {object} errcode.Error "没有权限"
 // @Failure 500 {object} errcode.Error "内部错误"
-// @Router /api/v1/tags/{id} [delete]
+// @Router /api/v1/articles/{id} [delete]
 func (a *ArticleHandler) Delete(c *gin.Context) {
+	idStr := convert.StrTo(c.Param("id"))
+	param := service.DeleteArticleRequest{ID: idStr.MustUInt32()}
+	response := app.NewResponse(c)
+	valid, errs := app.BindAndValid(c, &param)
+	if !valid {
+		global.Logger.ErrorfT("app.BindAndValid fail. errs: %v", errs)
+		response.ToErrorResponse(errcode.InvalidParams.WithDetails(errs.Errors()...))
+		return
+	}
+
+	svc := service.New(c.Request.Context())
+	err := svc.DeleteArticle(&param)
+	if err == service.ErrPermissionDenied {
+		response.ToErrorResponse(errcode.Forbidden)
+		return
+	}
+	if err != nil {
+		global.Logger.ErrorfT("svc.DeleteArticle err: %v", err)
+		response.ToErrorResponse(errcode.ErrorDeleteArticleFail)
+		return
+	}
+
+	response.ToResponse(gin.H{})
+}
+
+// @Summary 搜索已发布的文章
+// @Produce  json
+// @Param q query string true "搜索词，中文按连续文字匹配" maxlength(100)
+// @Param page query int false "页码"
+// @Param page_size query int false "每页数量"
+// @Success 200 {object} search.Hit "成功，title 和 snippet 中命中的部分使用 <em> 标记"
+// @Failure 400 {object} errcode.Error "请求错误"
+// @Router /api/v1/search [get]
+func (a *ArticleHandler) Search(c *gin.Context) {
+	param := service.SearchRequest{}
+	response := app.NewResponse(c)
+	valid, errs := app.BindAndValid(c, &param)
+	if !valid {
+		global.Logger.ErrorfT("app.BindAndValid fail. errs: %v", errs)
+		response.ToErrorResponse(errcode.InvalidParams.WithDetails(errs.Errors()...))
+		return
+	}
+
+	svc := service.New(c.Request.Context())
+	pager := app.Pager{Page: app.GetPage(c), PageSize: app.GetPageSize(c)}
+	hits, totalRows := svc.SearchArticles(&param, &pager)
+
+	response.ToResponseList(hits, totalRows)
+}
+
+// @Summary 按工作流变更文章状态
+// @Produce  json
+// @Param id path int true "文章ID"
+// @Param state body string true "目标状态：草稿提交审核，审核通过后发布，发布后可以归档，归档后可以退回草稿" Enums(draft, in_review, published, archived)
+// @Param comment body string false "审核意见等说明" maxlength(255)
+// @Param publish_on body int false "定时发布的时间戳，目标状态为 published 且时间在未来时文章保持审核中，到期后自动发布"
+// @Success 200 {string} string "请求成功"
+// @Failure 400 {object} errcode.Error "请求错误"
+// @Failure 403 {object} errcode.Error "没有权限"
+// @Failure 404 {object} errcode.Error "文章不存在"
+// @Failure 409 {object} errcode.Error "不允许从当前状态变更为目标状态"
+// @Failure 500 {object} errcode.Error "内部错误"
+// @Router /api/v1/articles/{id}/state [patch]
+func (a *ArticleHandler) UpdateState(c *gin.Context) {
+	idStr := convert.StrTo(c.Param("id"))
+	param := service.UpdateArticleStateRequest{ID: idStr.MustUInt32()}
+	response := app.NewResponse(c)
+	valid, errs := app.BindAndValid(c, &param)
+	if !valid {
+		global.Logger.ErrorfT("app.BindAndValid fail. errs: %v", errs)
+		response.ToErrorResponse(errcode.InvalidParams.WithDetails(errs.Errors()...))
+		return
+	}
+
+	svc := service.New(c.Request.Context())
+	err := svc.UpdateArticleState(&param)
+	if err == service.ErrPermissionDenied {
+		response.ToErrorResponse(errcode.Forbidden)
+		return
+	}
+	if err == service.ErrArticleNotFound {
+		response.ToErrorResponse(errcode.NotFound)
+		return
+	}
+	if errors.Is(err, service.ErrArticleStateTransition) {
+		response.ToErrorResponse(errcode.ErrorArticleStateTransition.WithDetails(err.Error()))
+		return
+	}
+	if err != nil {
+		global.Logger.ErrorfT("svc.UpdateArticleState err: %v", err)
+		response.ToErrorResponse(errcode.ErrorUpdateArticleStateFail)
+		return
+	}
+
+	response.ToResponse(gin.H{})
+}
+
+// @Summary 获取文章的状态变更记录
+// @Produce  json
+// @Param id path int true "文章ID"
+// @Success 200 {object} service.ArticleTransitionInfo "请求成功"
+// @Failure 400 {object} errcode.Error "请求错误"
+// @Failure 403 {object} errcode.Error "作者只能查看自己文章的记录"
+// @Failure 404 {object} errcode.Error "文章不存在"
+// @Failure 500 {object} errcode.Error "内部错误"
+// @Router /api/v1/articles/{id}/transitions [get]
+func (a *ArticleHandler) Transitions(c *gin.Context) {
+	idStr := convert.StrTo(c.Param("id"))
+	param := service.ArticleTransitionListRequest{ID: idStr.MustUInt32()}
+	response := app.NewResponse(c)
+	valid, errs := app.BindAndValid(c, &param)
+	if !valid {
+		global.Logger.ErrorfT("app.BindAndValid fail. errs: %v", errs)
+		response.ToErrorResponse(errcode.InvalidParams.WithDetails(errs.Errors()...))
+		return
+	}
+
+	svc := service.New(c.Request.Context())
+	transitions, err := svc.GetArticleTransitionList(&param)
+	if err == service.ErrPermissionDenied {
+		response.ToErrorResponse(errcode.Forbidden)
+		return
+	}
+	if err == service.ErrArticleNotFound {
+		response.ToErrorResponse(errcode.NotFound)
+		return
+	}
+	if err != nil {
+		global.Logger.ErrorfT("svc.GetArticleTransitionList err: %v", err)
+		response.ToErrorResponse(errcode.ErrorGetArticleTransitionFail)
+		return
+	}
+
+	response.ToResponse(gin.H{"list": transitions})
+}
+
+// @Summary 获取文章的历史版本列表
+// @Produce  json
+// @Param id path int true "文章ID"
+// @Success 200 {object} model.ArticleRevision "请求成功，列表不包含内容"
+// @Failure 400 {object} errcode.Error "请求错误"
+// @Failure 403 {object} errcode.Error "作者只能查看自己文章的历史版本"
+// @Failure 404 {object} errcode.Error "文章不存在"
+// @Failure 500 {object} errcode.Error "内部错误"
+// @Router /api/v1/articles/{id}/revisions [get]
+func (a *ArticleHandler) Revisions(c *gin.Context) {
+	idStr := convert.StrTo(c.Param("id"))
+	param := service.ArticleRevisionListRequest{ID: idStr.MustUInt32()}
+	response := app.NewResponse(c)
+	valid, errs := app.BindAndValid(c, &param)
+	if !valid {
+		global.Logger.ErrorfT("app.BindAndValid fail. errs: %v", errs)
+		response.ToErrorResponse(errcode.InvalidParams.WithDetails(errs.Errors()...))
+		return
+	}
+
+	svc := service.New(c.Request.Context())
+	revisions, err := svc.GetArticleRevisionList(&param)
+	if err == service.ErrPermissionDenied {
+		response.ToErrorResponse(errcode.Forbidden)
+		return
+	}
+	if err == service.ErrArticleNotFound {
+		response.ToErrorResponse(errcode.NotFound)
+		return
+	}
+	if err != nil {
+		global.Logger.ErrorfT("svc.GetArticleRevisionList err: %v", err)
+		response.ToErrorResponse(errcode.ErrorGetArticleRevisionFail)
+		return
+	}
+
+	response.ToResponse(gin.H{"list": revisions})
+}
+
+// @Summary 获取文章的单个历史版本
+// @Produce  json
+// @Param id path int true "文章ID"
+// @Param revision path int true "版本号"
+// @Success 200 {object} model.ArticleRevision "请求成功"
+// @Failure 400 {object} errcode.Error "请求错误"
+// @Failure 403 {object} errcode.Error "作者只能查看自己文章的历史版本"
+// @Failure 404 {object} errcode.Error "文章或版本不存在"
+// @Failure 500 {object} errcode.Error "内部错误"
+// @Router /api/v1/articles/{id}/revisions/{revision} [get]
+func (a *ArticleHandler) Revision(c *gin.Context) {
+	idStr := convert.StrTo(c.Param("id"))
+	revisionStr := convert.StrTo(c.Param("revision"))
+	param := service.ArticleRevisionRequest{ID: idStr.MustUInt32(), Revision: revisionStr.MustUInt32()}
+	response := app.NewResponse(c)
+	valid, errs := app.BindAndValid(c, &param)
+	if !valid {
+		global.Logger.ErrorfT("app.BindAndValid fail. errs: %v", errs)
+		response.ToErrorResponse(errcode.InvalidParams.WithDetails(errs.Errors()...))
+		return
+	}
+
+	svc := service.New(c.Request.Context())
+	revision, err := svc.GetArticleRevision(&param)
+	if err == service.ErrPermissionDenied {
+		response.ToErrorResponse(errcode.Forbidden)
+		return
+	}
+	if err == service.ErrArticleNotFound || err == service.ErrArticleRevisionNotFound {
+		response.ToErrorResponse(errcode.NotFound)
+		return
+	}
+	if err != nil {
+		global.Logger.ErrorfT("svc.GetArticleRevision err: %v", err)
+		response.ToErrorResponse(errcode.ErrorGetArticleRevisionFail)
+		return
+	}
+
+	response.ToResponse(revision)
+}
+
+// @Summary 比较文章的两个历史版本
+// @Produce  json
+// @Param id path int true "文章ID"
+// @Param from query int true "旧版本号"
+// @Param to query int true "新版本号"
+// @Success 200 {object} service.ArticleRevisionDiff "请求成功，内容按行比较"
+// @Failure 400 {object} errcode.Error "请求错误"
+// @Failure 403 {object} errcode.Error "作者只能查看自己文章的历史版本"
+// @Failure 404 {object} errcode.Error "文章或版本不存在"
+// @Failure 500 {object} errcode.Error "内部错误"
+// @Router /api/v1/articles/{id}/revisions/diff [get]
+func (a *ArticleHandler) DiffRevisions(c *gin.Context) {
+	idStr := convert.StrTo(c.Param("id"))
+	param := service.ArticleRevisionDiffRequest{ID: idStr.MustUInt32()}
+	response := app.NewResponse(c)
+	valid, errs := app.BindAndValid(c, &param)
+	if !valid {
+		global.Logger.ErrorfT("app.BindAndValid fail. errs: %v", errs)
+		response.ToErrorResponse(errcode.InvalidParams.WithDetails(errs.Errors()...))
+		return
+	}
+
+	svc := service.New(c.Request.Context())
+	revisionDiff, err := svc.DiffArticleRevisions(&param)
+	if err == service.ErrPermissionDenied {
+		response.ToErrorResponse(errcode.Forbidden)
+		return
+	}
+	if err == service.ErrArticleNotFound || err == service.ErrArticleRevisionNotFound {
+		response.ToErrorResponse(errcode.NotFound)
+		return
+	}
+	if err != nil {
+		global.Logger.ErrorfT("svc.DiffArticleRevisions err: %v", err)
+		response.ToErrorResponse(errcode.ErrorGetArticleRevisionFail)
+		return
+	}
+
+	response.ToResponse(revisionDiff)
+}
+
+// @Summary 将文章恢复为历史版本，恢复后生成新的版本
+// @Produce  json
+// @Param id path int true "文章ID"
+// @Param revision path int true "要恢复的版本号"
+// @Success 200 {object} model.ArticleRevision "请求成功，返回恢复后的最新版本"
+// @Failure 400 {object} errcode.Error "请求错误"
+// @Failure 403 {object} errcode.Error "没有权限"
+// @Failure 404 {object} errcode.Error "文章或版本不存在"
+// @Failure 409 {object} errcode.Error "作者只能修改草稿状态的文章"
+// @Failure 500 {object} errcode.Error "内部错误"
+// @Router /api/v1/articles/{id}/revisions/{revision}/restore [post]
+func (a *ArticleHandler) RestoreRevision(c *gin.Context) {
+	idStr := convert.StrTo(c.Param("id"))
+	revisionStr := convert.StrTo(c.Param("revision"))
+	param := service.ArticleRevisionRequest{ID: idStr.MustUInt32(), Revision: revisionStr.MustUInt32()}
+	response := app.NewResponse(c)
+	valid, errs := app.BindAndValid(c, &param)
+	if !valid {
+		global.Logger.ErrorfT("app.BindAndValid fail. errs: %v", errs)
+		response.ToErrorResponse(errcode.InvalidParams.WithDetails(errs.Errors()...))
+		return
+	}
+
+	svc := service.New(c.Request.Context())
+	revision, err := svc.RestoreArticleRevision(&param)
+	if err == service.ErrPermissionDenied {
+		response.ToErrorResponse(errcode.Forbidden)
+		return
+	}
+	if err == service.ErrArticleNotEditable {
+		response.ToErrorResponse(errcode.ErrorArticleNotEditable)
+		return
+	}
+	if err == service.ErrArticleNotFound || err == service.ErrArticleRevisionNotFound {
+		response.ToErrorResponse(errcode.NotFound)
+		return
+	}
+	if err != nil {
+		global.Logger.ErrorfT("svc.RestoreArticleRevision err: %v", err)
+		response.ToErrorResponse(errcode.ErrorRestoreArticleRevisionFail)
+		return
+	}
+
+	response.ToResponse(revision)
 }
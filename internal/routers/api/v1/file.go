@@ -0,0 +1,87 @@
+package v1
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/go-programming-tour/blog-service/global"
+	"github.com/go-programming-tour/blog-service/internal/service"
+	"github.com/go-programming-tour/blog-service/pkg/app"
+	"github.com/go-programming-tour/blog-service/pkg/convert"
+	"github.com/go-programming-tour/blog-service/pkg/errcode"
+)
+
+// 上传文件管理处理器
+type FileHandler struct{}
+
+func NewFileHandler() *FileHandler {
+	return &FileHandler{}
+}
+
+// @Summary 回收不再被文章引用的上传文件
+// @Produce  json
+// @Param dry_run query bool false "只生成报告，不标记也不删除文件"
+// @Success 200 {object} service.FileGCReport "成功"
+// @Failure 400 {object} errcode.Error "请求错误"
+// @Failure 403 {object} errcode.Error "没有权限"
+// @Failure 500 {object} errcode.Error "内部错误"
+// @Router /api/v1/files/gc [post]
+func (f *FileHandler) GC(c *gin.Context) {
+	param := service.FileGCRequest{}
+	response := app.NewResponse(c)
+	valid, errs := app.BindAndValid(c, &param)
+	if !valid {
+		global.Logger.ErrorfT("app.BindAndValid errs: %v", errs)
+		response.ToErrorResponse(errcode.InvalidParams.WithDetails(errs.Errors()...))
+		return
+	}
+
+	svc := service.New(c.Request.Context())
+	report, err := svc.CollectOrphanedFiles(param.DryRun)
+	if err != nil {
+		global.Logger.ErrorfT("svc.CollectOrphanedFiles err: %v", err)
+		response.ToErrorResponse(errcode.ErrorCollectFileFail)
+		return
+	}
+
+	response.ToResponse(report)
+}
+
+// @Summary 获取文件的访问地址，私有文件返回带有效期的签名地址
+// @Produce  json
+// @Param id path int true "文件 ID"
+// @Param bind_user query bool false "签名地址只允许当前用户访问"
+// @Param expire query int false "签名地址的有效期，单位秒，60 到 604800"
+// @Success 200 {object} service.FileURL "成功"
+// @Failure 400 {object} errcode.Error "请求错误"
+// @Failure 403 {object} errcode.Error "没有权限"
+// @Failure 404 {object} errcode.Error "文件不存在"
+// @Failure 500 {object} errcode.Error "内部错误"
+// @Router /api/v1/files/{id}/url [get]
+func (f *FileHandler) URL(c *gin.Context) {
+	idStr := convert.StrTo(c.Param("id"))
+	param := service.FileURLRequest{ID: idStr.MustUInt32()}
+	response := app.NewResponse(c)
+	valid, errs := app.BindAndValid(c, &param)
+	if !valid {
+		global.Logger.ErrorfT("app.BindAndValid errs: %v", errs)
+		response.ToErrorResponse(errcode.InvalidParams.WithDetails(errs.Errors()...))
+		return
+	}
+
+	svc := service.New(c.Request.Context())
+	fileURL, err := svc.GetFileURL(&param)
+	if err == service.ErrPermissionDenied {
+		response.ToErrorResponse(errcode.Forbidden)
+		return
+	}
+	if err == service.ErrFileNotFound {
+		response.ToErrorResponse(errcode.ErrorFileNotFound)
+		return
+	}
+	if err != nil {
+		global.Logger.ErrorfT("svc.GetFileURL err: %v", err)
+		response.ToErrorResponse(errcode.ErrorGetFileFail)
+		return
+	}
+
+	response.ToResponse(fileURL)
+}
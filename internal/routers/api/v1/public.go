@@ -0,0 +1,209 @@
+package v1
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-programming-tour/blog-service/global"
+	"github.com/go-programming-tour/blog-service/internal/service"
+	"github.com/go-programming-tour/blog-service/pkg/app"
+	"github.com/go-programming-tour/blog-service/pkg/convert"
+	"github.com/go-programming-tour/blog-service/pkg/errcode"
+)
+
+// 公开接口处理器，不需要登录，只能读取已发布的文章和启用的标签
+type PublicHandler struct{}
+
+func NewPublicHandler() *PublicHandler {
+	return &PublicHandler{}
+}
+
+// @Summary 获取单篇已发布的文章
+// @Produce  json
+// @Param id path int true "文章ID"
+// @Success 200 {object} model.Article "请求成功"
+// @Failure 400 {object} errcode.Error "请求错误"
+// @Failure 404 {object} errcode.Error "文章不存在或未发布"
+// @Failure 429 {object} errcode.Error "请求过多"
+// @Failure 500 {object} errcode.Error "内部错误"
+// @Router /api/v1/public/articles/{id} [get]
+func (p *PublicHandler) GetArticle(c *gin.Context) {
+	idStr := convert.StrTo(c.Param("id"))
+	param := service.PublicArticleRequest{ID: idStr.MustUInt32()}
+	response := app.NewResponse(c)
+	valid, errs := app.BindAndValid(c, &param)
+	if !valid {
+		global.Logger.ErrorfT("app.BindAndValid fail. errs: %v", errs)
+		response.ToErrorResponse(errcode.InvalidParams.WithDetails(errs.Errors()...))
+		return
+	}
+
+	svc := service.New(c.Request.Context())
+	article, err := svc.GetPublicArticle(&param)
+	if err == service.ErrArticleNotFound {
+		response.ToErrorResponse(errcode.NotFound)
+		return
+	}
+	if err != nil {
+		global.Logger.ErrorfT("svc.GetPublicArticle err: %v", err)
+		response.ToErrorResponse(errcode.ErrorGetArticleFail)
+		return
+	}
+
+	response.ToResponse(article)
+}
+
+// @Summary 按别名获取单篇已发布的文章
+// @Produce  json
+// @Param slug path string true "文章别名"
+// @Success 200 {object} model.Article "请求成功"
+// @Success 301 {string} string "访问的是旧别名，重定向到文章当前的别名"
+// @Failure 400 {object} errcode.Error "请求错误"
+// @Failure 404 {object} errcode.Error "文章不存在或未发布"
+// @Failure 429 {object} errcode.Error "请求过多"
+// @Failure 500 {object} errcode.Error "内部错误"
+// @Router /api/v1/public/articles/by-slug/{slug} [get]
+func (p *PublicHandler) GetArticleBySlug(c *gin.Context) {
+	param := service.PublicArticleSlugRequest{Slug: c.Param("slug")}
+	response := app.NewResponse(c)
+	valid, errs := app.BindAndValid(c, &param)
+	if !valid {
+		global.Logger.ErrorfT("app.BindAndValid fail. errs: %v", errs)
+		response.ToErrorResponse(errcode.InvalidParams.WithDetails(errs.Errors()...))
+		return
+	}
+
+	svc := service.New(c.Request.Context())
+	article, err := svc.GetPublicArticleBySlug(&param)
+	var moved *service.ArticleSlugMoved
+	if errors.As(err, &moved) {
+		c.Redirect(http.StatusMovedPermanently, "/api/v1/public/articles/by-slug/"+url.PathEscape(moved.Slug))
+		return
+	}
+	if err == service.ErrArticleNotFound {
+		response.ToErrorResponse(errcode.NotFound)
+		return
+	}
+	if err != nil {
+		global.Logger.ErrorfT("svc.GetPublicArticleBySlug err: %v", err)
+		response.ToErrorResponse(errcode.ErrorGetArticleFail)
+		return
+	}
+
+	response.ToResponse(article)
+}
+
+// @Summary 获取已发布的文章列表
+// @Produce  json
+// @Param title query string false "文章标题" maxlength(100)
+// @Param tag_id query int false "标签ID"
+// @Param page query int false "页码"
+// @Param page_size query int false "每页数量"
+// @Success 200 {object} model.ArticleSwagger "请求成功"
+// @Failure 400 {object} errcode.Error "请求错误"
+// @Failure 429 {object} errcode.Error "请求过多"
+// @Failure 500 {object} errcode.Error "内部错误"
+// @Router /api/v1/public/articles [get]
+func (p *PublicHandler) ListArticles(c *gin.Context) {
+	param := service.PublicArticleListRequest{}
+	response := app.NewResponse(c)
+	valid, errs := app.BindAndValid(c, &param)
+	if !valid {
+		global.Logger.ErrorfT("app.BindAndValid fail. errs: %v", errs)
+		response.ToErrorResponse(errcode.InvalidParams.WithDetails(errs.Errors()...))
+		return
+	}
+
+	svc := service.New(c.Request.Context())
+	pager := app.Pager{Page: app.GetPage(c), PageSize: app.GetPageSize(c)}
+	totalRows, err := svc.CountPublicArticle(&param)
+	if err != nil {
+		global.Logger.ErrorfT("svc.CountPublicArticle err: %v", err)
+		response.ToErrorResponse(errcode.ErrorCountArticleFail)
+		return
+	}
+	articles, err := svc.GetPublicArticleList(&param, &pager)
+	if err != nil {
+		global.Logger.ErrorfT("svc.GetPublicArticleList err: %v", err)
+		response.ToErrorResponse(errcode.ErrorGetArticleListFail)
+		return
+	}
+
+	response.ToResponseList(articles, totalRows)
+}
+
+// @Summary 获取单个启用的标签
+// @Produce  json
+// @Param id path int true "标签 ID"
+// @Success 200 {object} model.Tag "成功"
+// @Failure 400 {object} errcode.Error "请求错误"
+// @Failure 404 {object} errcode.Error "标签不存在或未启用"
+// @Failure 429 {object} errcode.Error "请求过多"
+// @Failure 500 {object} errcode.Error "内部错误"
+// @Router /api/v1/public/tags/{id} [get]
+func (p *PublicHandler) GetTag(c *gin.Context) {
+	idStr := convert.StrTo(c.Param("id"))
+	param := service.PublicTagRequest{ID: idStr.MustUInt32()}
+	response := app.NewResponse(c)
+	valid, errs := app.BindAndValid(c, &param)
+	if !valid {
+		global.Logger.ErrorfT("app.BindAndValid fail. errs: %v", errs)
+		response.ToErrorResponse(errcode.InvalidParams.WithDetails(errs.Errors()...))
+		return
+	}
+
+	svc := service.New(c.Request.Context())
+	tag, err := svc.GetPublicTag(&param)
+	if err == service.ErrTagNotFound {
+		response.ToErrorResponse(errcode.NotFound)
+		return
+	}
+	if err != nil {
+		global.Logger.ErrorfT("svc.GetPublicTag err: %v", err)
+		response.ToErrorResponse(errcode.ErrorGetTagFail)
+		return
+	}
+
+	response.ToResponse(tag)
+}
+
+// @Summary 获取启用的标签列表
+// @Produce  json
+// @Param name query string false "标签名称" maxlength(100)
+// @Param with_article_count query bool false "是否返回每个标签的已发布文章数"
+// @Param page query int false "页码"
+// @Param page_size query int false "每页数量"
+// @Success 200 {object} model.TagSwagger "成功"
+// @Failure 400 {object} errcode.Error "请求错误"
+// @Failure 429 {object} errcode.Error "请求过多"
+// @Failure 500 {object} errcode.Error "内部错误"
+// @Router /api/v1/public/tags [get]
+func (p *PublicHandler) ListTags(c *gin.Context) {
+	param := service.PublicTagListRequest{}
+	response := app.NewResponse(c)
+	valid, errs := app.BindAndValid(c, &param)
+	if !valid {
+		global.Logger.ErrorfT("app.BindAndValid fail. errs: %v", errs)
+		response.ToErrorResponse(errcode.InvalidParams.WithDetails(errs.Errors()...))
+		return
+	}
+
+	svc := service.New(c.Request.Context())
+	pager := app.Pager{Page: app.GetPage(c), PageSize: app.GetPageSize(c)}
+	totalRows, err := svc.CountPublicTag(&param)
+	if err != nil {
+		global.Logger.ErrorfT("svc.CountPublicTag err: %v", err)
+		response.ToErrorResponse(errcode.ErrorCountTagFail)
+		return
+	}
+	tags, err := svc.GetPublicTagList(&param, &pager)
+	if err != nil {
+		global.Logger.ErrorfT("svc.GetPublicTagList err: %v", err)
+		response.ToErrorResponse(errcode.ErrorGetTagListFail)
+		return
+	}
+
+	response.ToResponseList(tags, totalRows)
+}
@@ -24,15 +24,41 @@ func NewTagHandler() *TagHandler {
 // @Param state query int false "状态" Enums(0, 1) default(1)
 // @Success 200 {object} model.Tag "成功"
 // @Failure 400 {object} errcode.Error "请求错误"
+// @Failure 404 {object} errcode.Error "标签不存在"
 // @Failure 500 {object} errcode.Error "内部错误"
 // @Router /api/v1/tags/{id} [get]
 func (t *TagHandler) Get(c *gin.Context) {
+	idStr := convert.StrTo(c.Param("id"))
+	param := service.TagRequest{ID: idStr.MustUInt32()}
+	response := app.NewResponse(c)
+	valid, errs := app.BindAndValid(c, &param)
+	if !valid {
+		// 未通过参数验证
+		global.Logger.ErrorfT("app.BindAndValid fail. errs: %v", errs)
+		response.ToErrorResponse(errcode.InvalidParams.WithDetails(errs.Errors()...))
+		return
+	}
+
+	svc := service.New(c.Request.Context())
+	tag, err := svc.GetTag(&param)
+	if err == service.ErrTagNotFound {
+		response.ToErrorResponse(errcode.NotFound)
+		return
+	}
+	if err != nil {
+		global.Logger.ErrorfT("svc.GetTag err: %v", err)
+		response.ToErrorResponse(errcode.ErrorGetTagFail)
+		return
+	}
+
+	response.ToResponse(tag)
 }
 
 // @Summary 获取多个标签
 // @Produce  json
 // @Param name query string false "标签名称" maxlength(100)
 // @Param state query int false "状态" Enums(0, 1) default(1)
+// @Param with_article_count query bool false "是否返回每个标签的已发布文章数"
 // @Param page query int false "页码"
 // @Param page_size query int false "每页数量"
 // @Success 200 {object} model.Tag "成功"
@@ -77,7 +103,6 @@ func (t *TagHandler) List(c *gin.Context) {
 // @Produce  json
 // @Param name body string true "标签名称" minlength(3) maxlength(100)
 // @Param state body int false "状态" Enums(0, 1) default(1)
-// @Param created_by body string true "创建者" minlength(3) maxlength(100)
 // @Success 200 {object} model.Tag "成功"
 // @Failure 400 {object} errcode.Error "请求错误"
 // @Failure 500 {object} errcode.Error "内部错误"
@@ -109,7 +134,6 @@ func (t *TagHandler) Create(c *gin.Context) {
 // @Param id path int true "标签 ID"
 // @Param name body string false "标签名称" minlength(3) maxlength(100)
 // @Param state body int false "状态" Enums(0, 1) default(1)
-// @Param modified_by body string true "修改者" minlength(3) maxlength(100)
 // @Success 200 {array} model.Tag "成功"
 // @Failure 400 {object} errcode.Error "请求错误"
 // @Failure 500 {object} errcode.Error "内部错误"
@@ -132,7 +156,8 @@ func (t *TagHandler) Update(c *gin.Context) {
 	svc := service.New(c.Request.Context()) // 请求上下文
 	err := svc.UpdateTag(&param)
 	if err != nil {
-		global.Logger.ErrorfT("svc.UpdateTag fail. err =", err)
+		global.Logger.ErrorfT("svc.UpdateTag fail. err: %v", err)
+		response.ToErrorResponse(errcode.ErrorUpdateTagFail)
 		return
 	}
 
@@ -0,0 +1,65 @@
+package v1
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/go-programming-tour/blog-service/global"
+	"github.com/go-programming-tour/blog-service/internal/service"
+	"github.com/go-programming-tour/blog-service/pkg/app"
+	"github.com/go-programming-tour/blog-service/pkg/convert"
+	"github.com/go-programming-tour/blog-service/pkg/errcode"
+)
+
+// 用户处理器
+type UserHandler struct{}
+
+func NewUserHandler() *UserHandler {
+	return &UserHandler{}
+}
+
+// @Summary 修改用户角色
+// @Produce  json
+// @Param id path int true "用户 ID"
+// @Param role body string true "角色" Enums(admin, editor, author, reader)
+// @Success 200 {string} string "成功"
+// @Failure 400 {object} errcode.Error "请求错误"
+// @Failure 403 {object} errcode.Error "没有权限"
+// @Failure 500 {object} errcode.Error "内部错误"
+// @Router /api/v1/users/{id}/role [put]
+func (u *UserHandler) UpdateRole(c *gin.Context) {
+	idStr := convert.StrTo(c.Param("id"))
+	param := service.UpdateUserRoleRequest{ID: idStr.MustUInt32()}
+	response := app.NewResponse(c)
+	valid, errs := app.BindAndValid(c, &param)
+	if !valid {
+		global.Logger.ErrorfT("app.BindAndValid fail. errs: %v", errs)
+		response.ToErrorResponse(errcode.InvalidParams.WithDetails(errs.Errors()...))
+		return
+	}
+
+	svc := service.New(c.Request.Context())
+	if err := svc.UpdateUserRole(&param); err != nil {
+		global.Logger.ErrorfT("svc.UpdateUserRole err: %v", err)
+		response.ToErrorResponse(errcode.ErrorUpdateUserFail)
+		return
+	}
+
+	response.ToResponse(gin.H{})
+}
+
+// @Summary 获取当前用户的存储用量和配额
+// @Produce  json
+// @Success 200 {object} service.StorageUsage "成功，配额为 0 时表示不限制"
+// @Failure 500 {object} errcode.Error "内部错误"
+// @Router /api/v1/me/storage [get]
+func (u *UserHandler) Storage(c *gin.Context) {
+	response := app.NewResponse(c)
+	svc := service.New(c.Request.Context())
+	usage, err := svc.GetStorageUsage()
+	if err != nil {
+		global.Logger.ErrorfT("svc.GetStorageUsage err: %v", err)
+		response.ToErrorResponse(errcode.ErrorGetStorageUsageFail)
+		return
+	}
+
+	response.ToResponse(usage)
+}
@@ -1,7 +1,6 @@
 package routers
 
 import (
-	"net/http"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -9,6 +8,7 @@ import (
 	_ "github.com/go-programming-tour/blog-service/docs"
 	"github.com/go-programming-tour/blog-service/global"
 	"github.com/go-programming-tour/blog-service/internal/middleware"
+	"github.com/go-programming-tour/blog-service/internal/model"
 	"github.com/go-programming-tour/blog-service/internal/routers/api"
 	v1 "github.com/go-programming-tour/blog-service/internal/routers/api/v1"
 	"github.com/go-programming-tour/blog-service/pkg/limiter"
@@ -16,15 +16,48 @@ import (
 	"github.com/swaggo/gin-swagger/swaggerFiles"
 )
 
-var methodLimiters = limiter.NewMethodLimiter().AddBuckets(limiter.LimiterBucketRule{
-	Key:          "/auth",
+var methodLimiters = limiter.NewMethodLimiter().AddBuckets(
+	limiter.LimiterBucketRule{
+		Key:          "/auth",
+		FillInterval: time.Second,
+		Capacity:     10,
+		Quantum:      10,
+	},
+	limiter.LimiterBucketRule{
+		Key:          "/auth/refresh",
+		FillInterval: time.Second,
+		Capacity:     10,
+		Quantum:      10,
+	},
+	limiter.LimiterBucketRule{
+		Key:          "/auth/register",
+		FillInterval: time.Second,
+		Capacity:     5,
+		Quantum:      5,
+	},
+)
+
+// 公开接口按客户端 IP 和路由分别限流
+var publicLimiters = limiter.NewClientLimiter(limiter.LimiterBucketRule{
 	FillInterval: time.Second,
-	Capacity:     10,
+	Capacity:     20,
 	Quantum:      10,
 })
 
+// 各类操作允许的角色
+var (
+	allRoles       = []string{model.UserRoleAdmin, model.UserRoleEditor, model.UserRoleAuthor, model.UserRoleReader}
+	tagWriters     = []string{model.UserRoleAdmin, model.UserRoleEditor}
+	articleWriters = []string{model.UserRoleAdmin, model.UserRoleEditor, model.UserRoleAuthor} // 作者只能修改自己的文章
+	adminOnly      = []string{model.UserRoleAdmin}
+)
+
 func NewRouter() *gin.Engine {
 	engin := gin.New()
+	// 只信任配置的反向代理设置的 X-Forwarded-For，否则客户端可以伪造 IP 绕过按 IP 的限流
+	if err := engin.SetTrustedProxies(global.ServerSetting.TrustedProxies); err != nil {
+		panic(err)
+	}
 
 	if global.ServerSetting.RunMode == "dubug" {
 		// 注册日志中间件
@@ -43,31 +76,94 @@ func NewRouter() *gin.Engine {
 	engin.Use(middleware.Translations())
 	// 注册swag路由
 	engin.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
-	// 注册上传文件的路由
-	upload := api.NewUploadHandler()
-	engin.POST("/upload/file", upload.UploadFile)
-	engin.StaticFS("/static", http.Dir(global.AppSetting.UploadSavePath))
-	// 注册jwt路由
+	// 注册上传文件的路由，上传需要登录，用量计入当前用户的配额
+	uploader := api.NewUploadHandler()
+	// tus 客户端通过 OPTIONS 探测服务端能力，不需要登录
+	engin.OPTIONS("/upload/tus", uploader.TusOptions)
+	uploadGroup := engin.Group("/upload")
+	uploadGroup.Use(middleware.JWT())
+	{
+		uploadGroup.POST("/file", uploader.UploadFile)
+		// 分片上传，上传中断后可以只重传缺失的分片
+		uploadGroup.POST("/sessions", uploader.CreateSession)
+		uploadGroup.GET("/sessions/:id", uploader.GetSession)
+		uploadGroup.PUT("/sessions/:id/chunks/:index", uploader.UploadChunk)
+		uploadGroup.POST("/sessions/:id/complete", uploader.CompleteSession)
+		uploadGroup.DELETE("/sessions/:id", uploader.DeleteSession)
+		// 兼容 tus 协议的断点续传
+		uploadGroup.POST("/tus", uploader.TusCreate)
+		uploadGroup.HEAD("/tus/:id", uploader.TusHead)
+		uploadGroup.PATCH("/tus/:id", uploader.TusPatch)
+		uploadGroup.DELETE("/tus/:id", uploader.TusDelete)
+	}
+	// 访问上传的文件，私有文件需要校验签名，绑定用户的签名地址还需要在 header 中携带该用户的 token
+	engin.GET("/static/*name", middleware.OptionalJWT(), api.ServeFile)
+	engin.HEAD("/static/*name", middleware.OptionalJWT(), api.ServeFile)
+	// 注册登录和注册用户的路由
 	engin.POST("/auth", api.GetAuth)
+	engin.POST("/auth/register", api.Register)
+	engin.POST("/auth/refresh", api.RefreshToken)
+	engin.POST("/auth/logout", middleware.JWT(), api.Logout)
+	// 公开验签公钥，供其他服务验证 Token
+	engin.GET("/.well-known/jwks.json", api.GetJWKS)
+
+	// 订阅源，支持按 ETag 和 Last-Modified 的条件请求
+	publicLimit := middleware.RateLimiter(publicLimiters)
+	engin.GET("/feed.rss", publicLimit, api.RSSFeed)
+	engin.GET("/feed.atom", publicLimit, api.AtomFeed)
+	engin.GET("/tags/:id/feed.atom", publicLimit, api.TagAtomFeed)
+
+	// 公开的只读接口，供不登录的读者使用，写操作只能通过需要登录的 /api/v1
+	public := v1.NewPublicHandler()
+	publicGroup := engin.Group("/api/v1/public")
+	publicGroup.Use(publicLimit)
+	{
+		publicGroup.GET("/articles", public.ListArticles)
+		publicGroup.GET("/articles/:id", public.GetArticle)
+		publicGroup.GET("/articles/by-slug/:slug", public.GetArticleBySlug)
+		publicGroup.GET("/tags", public.ListTags)
+		publicGroup.GET("/tags/:id", public.GetTag)
+	}
 
 	article := v1.NewArticleHandler()
 	tag := v1.NewTagHandler()
+	user := v1.NewUserHandler()
+	file := v1.NewFileHandler()
 	apiv1 := engin.Group("/api/v1")
 	// 添加jwt验证
 	apiv1.Use(middleware.JWT())
 	{
-		apiv1.POST("/tags", tag.Create)
-		apiv1.DELETE("/tags/:id", tag.Delete)
-		apiv1.PUT("/tags/:id", tag.Update)
-		apiv1.PATCH("/tags/:id/state", tag.Update)
-		apiv1.GET("/tags", tag.List)
-
-		apiv1.POST("/articles", article.Create)
-		apiv1.DELETE("/articles/:id", article.Delete)
-		apiv1.PUT("/articles/:id", article.Update)
-		apiv1.PATCH("/articles/:id/state", article.Update)
-		apiv1.GET("/articles", article.Get)
-		apiv1.GET("/articles/:id", article.List)
+		canRead := middleware.Authorize(allRoles...)
+		canWriteTag := middleware.Authorize(tagWriters...)
+		canWriteArticle := middleware.Authorize(articleWriters...)
+		canAdmin := middleware.Authorize(adminOnly...)
+
+		apiv1.POST("/tags", canWriteTag, tag.Create)
+		apiv1.DELETE("/tags/:id", canWriteTag, tag.Delete)
+		apiv1.PUT("/tags/:id", canWriteTag, tag.Update)
+		apiv1.PATCH("/tags/:id/state", canWriteTag, tag.Update)
+		apiv1.GET("/tags", canRead, tag.List)
+		apiv1.GET("/tags/:id", canRead, tag.Get)
+
+		apiv1.POST("/articles", canWriteArticle, article.Create)
+		apiv1.DELETE("/articles/:id", canWriteArticle, article.Delete)
+		apiv1.PUT("/articles/:id", canWriteArticle, article.Update)
+		apiv1.PATCH("/articles/:id/state", canWriteArticle, article.UpdateState)
+		apiv1.GET("/articles/:id/transitions", canWriteArticle, article.Transitions)
+		apiv1.GET("/articles/:id/revisions", canWriteArticle, article.Revisions)
+		apiv1.GET("/articles/:id/revisions/diff", canWriteArticle, article.DiffRevisions)
+		apiv1.GET("/articles/:id/revisions/:revision", canWriteArticle, article.Revision)
+		apiv1.POST("/articles/:id/revisions/:revision/restore", canWriteArticle, article.RestoreRevision)
+		apiv1.GET("/articles", canRead, article.List)
+		apiv1.GET("/articles/:id", canRead, article.Get)
+		apiv1.GET("/articles/by-slug/:slug", canRead, article.GetBySlug)
+		apiv1.GET("/search", canRead, article.Search)
+
+		apiv1.PUT("/users/:id/role", canAdmin, user.UpdateRole)
+		apiv1.GET("/me/storage", canRead, user.Storage)
+
+		apiv1.POST("/files/gc", canAdmin, file.GC)
+		apiv1.GET("/files/:id/url", canRead, file.URL)
 	}
 
 	return engin
@@ -0,0 +1,170 @@
+package routers
+
+import (
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-programming-tour/blog-service/global"
+	"github.com/go-programming-tour/blog-service/pkg/app"
+	"github.com/go-programming-tour/blog-service/pkg/logger"
+	"github.com/go-programming-tour/blog-service/pkg/setting"
+	"github.com/go-programming-tour/blog-service/pkg/upload"
+)
+
+func newTestRouter(t *testing.T) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	global.Logger = logger.NewLogger(ioutil.Discard, "", log.LstdFlags)
+	global.ServerSetting = &setting.ServerSetting{RunMode: "debug"}
+	global.EmailSetting = &setting.EmailSettingS{}
+	global.JWTSetting = &setting.JWTSettingS{}
+	global.AppSetting = &setting.AppSetting{UploadSavePath: t.TempDir(), UploadSignSecret: "secret"}
+	if err := upload.SetupStorage(global.AppSetting, nil); err != nil {
+		t.Fatalf("upload.SetupStorage err: %v", err)
+	}
+
+	return NewRouter()
+}
+
+// 分片上传和 tus 上传的接口都需要登录
+func TestUploadSessionRoutesRequireLogin(t *testing.T) {
+	router := newTestRouter(t)
+	tests := []struct {
+		method string
+		path   string
+	}{
+		{http.MethodPost, "/upload/sessions"},
+		{http.MethodGet, "/upload/sessions/abc"},
+		{http.MethodPut, "/upload/sessions/abc/chunks/0"},
+		{http.MethodPost, "/upload/sessions/abc/complete"},
+		{http.MethodDelete, "/upload/sessions/abc"},
+		{http.MethodPost, "/upload/tus"},
+		{http.MethodHead, "/upload/tus/abc"},
+		{http.MethodPatch, "/upload/tus/abc"},
+		{http.MethodDelete, "/upload/tus/abc"},
+	}
+	for _, tt := range tests {
+		// 没有令牌时按入参错误处理，令牌无效时鉴权失败
+		for token, want := range map[string]int{"": http.StatusBadRequest, "invalid": http.StatusUnauthorized} {
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest(tt.method, tt.path, strings.NewReader("data"))
+			if token != "" {
+				req.Header.Set("token", token)
+			}
+			router.ServeHTTP(w, req)
+			if w.Code != want {
+				t.Errorf("%s %s with token %q: status = %d, want %d", tt.method, tt.path, token, w.Code, want)
+			}
+		}
+	}
+}
+
+// 分片和临时文件不能通过 /static 访问
+func TestStaticHidesUploadChunks(t *testing.T) {
+	router := newTestRouter(t)
+	ctx := httptest.NewRequest(http.MethodGet, "/", nil).Context()
+	for _, name := range []string{upload.GetChunkName("abc", 0), "tmp/abc"} {
+		if err := upload.GetStorage().Put(ctx, name, strings.NewReader("data"), -1, "text/plain"); err != nil {
+			t.Fatalf("Put %s err: %v", name, err)
+		}
+		for _, path := range []string{"/static/" + name, "/static/x/../" + name} {
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+			if w.Code != http.StatusNotFound {
+				t.Errorf("GET %s: status = %d, want %d", path, w.Code, http.StatusNotFound)
+			}
+		}
+	}
+}
+
+// 只有可信代理设置的 X-Forwarded-For 会被用作客户端 IP
+func TestClientIPOnlyTrustsConfiguredProxies(t *testing.T) {
+	newTestRouter(t)
+	global.ServerSetting.TrustedProxies = []string{"10.0.0.1"}
+	router := NewRouter()
+	router.GET("/test/client-ip", func(c *gin.Context) {
+		c.String(http.StatusOK, c.ClientIP())
+	})
+
+	tests := []struct {
+		name       string
+		remoteAddr string
+		forwarded  string
+		want       string
+	}{
+		{"direct", "203.0.113.7:1234", "", "203.0.113.7"},
+		{"spoofed by client", "203.0.113.7:1234", "198.51.100.1", "203.0.113.7"},
+		{"trusted proxy", "10.0.0.1:1234", "198.51.100.1", "198.51.100.1"},
+		{"spoofed through trusted proxy", "10.0.0.1:1234", "192.0.2.9, 198.51.100.1", "198.51.100.1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/test/client-ip", nil)
+			req.RemoteAddr = tt.remoteAddr
+			if tt.forwarded != "" {
+				req.Header.Set("X-Forwarded-For", tt.forwarded)
+			}
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			if got := w.Body.String(); got != tt.want {
+				t.Errorf("client IP = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// 绑定用户的签名地址只接受 header 中的令牌，令牌不随地址出现在日志和 Referer 中
+func TestStaticBoundURLRequiresHeaderToken(t *testing.T) {
+	router := newTestRouter(t)
+	global.JWTSetting = &setting.JWTSettingS{Secret: "secret", Expire: time.Hour}
+	if err := app.SetupJWTKeys(global.JWTSetting); err != nil {
+		t.Fatalf("app.SetupJWTKeys err: %v", err)
+	}
+	token, err := app.GenerateToken(1, "alice", "author")
+	if err != nil {
+		t.Fatalf("app.GenerateToken err: %v", err)
+	}
+	claims, err := app.ParseToken(token)
+	if err != nil {
+		t.Fatalf("app.ParseToken err: %v", err)
+	}
+	// 缓存未吊销的状态，避免查询数据库
+	app.SetupRevocationCache(time.Minute)
+	app.GetRevocationCache().Set(claims.Id, false, time.Unix(claims.ExpiresAt, 0))
+
+	name := upload.PrivatePrefix + "doc.txt"
+	ctx := httptest.NewRequest(http.MethodGet, "/", nil).Context()
+	if err := upload.GetStorage().Put(ctx, name, strings.NewReader("data"), -1, "text/plain"); err != nil {
+		t.Fatalf("Put %s err: %v", name, err)
+	}
+	global.AppSetting.UploadPrivateServerUrl = "/static"
+	path := upload.SignURL(name, 1, time.Now().Add(time.Hour))
+
+	tests := []struct {
+		name   string
+		path   string
+		header string
+		want   int
+	}{
+		{"header token", path, token, http.StatusOK},
+		{"query token", path + "&token=" + token, "", http.StatusForbidden},
+		{"no token", path, "", http.StatusForbidden},
+		{"other user", upload.SignURL(name, 2, time.Now().Add(time.Hour)), token, http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+		if tt.header != "" {
+			req.Header.Set("token", tt.header)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		if w.Code != tt.want {
+			t.Errorf("%s: status = %d, want %d", tt.name, w.Code, tt.want)
+		}
+	}
+}
@@ -0,0 +1,354 @@
+package service
+
+import (
+	"errors"
+
+	"github.com/go-programming-tour/blog-service/internal/dao"
+	"github.com/go-programming-tour/blog-service/internal/model"
+	"github.com/go-programming-tour/blog-service/pkg/app"
+	"github.com/go-programming-tour/blog-service/pkg/markdown"
+	"github.com/jinzhu/gorm"
+)
+
+// 状态：0 草稿，1 已发布，2 审核中，3 已归档
+type ArticleRequest struct {
+	ID    uint32 `form:"id" binding:"required,gte=1"`
+	State uint8  `form:"state,default=1" binding:"oneof=0 1 2 3"`
+}
+
+type ArticleListRequest struct {
+	Title string `form:"title" binding:"max=100"`
+	TagID uint32 `form:"tag_id" binding:"gte=0"`
+	State uint8  `form:"state,default=1" binding:"oneof=0 1 2 3"`
+}
+
+type CreateArticleRequest struct {
+	Title         string   `form:"title" binding:"required,min=2,max=100"`
+	Slug          string   `form:"slug" binding:"max=100"` // 为空时由标题生成
+	Desc          string   `form:"desc" binding:"max=255"`
+	Content       string   `form:"content" binding:"required,min=2"`
+	CoverImageUrl string   `form:"cover_image_url" binding:"required,url"`
+	TagIDs        []uint32 `form:"tag_ids" binding:"dive,gte=1"`
+	State         uint8    `form:"state,default=0" binding:"oneof=0 2"` // 新文章只能是草稿或直接提交审核
+}
+
+// 创建者和修改者取自当前登录用户，不再从请求中读取
+// 状态只能通过 UpdateArticleState 按工作流变更
+// TagIDs 为 nil 时不修改文章的标签，为空切片时清空标签
+// 没有指定别名时，修改标题后由新标题重新生成别名，原来的别名保留为旧别名
+type UpdateArticleRequest struct {
+	ID            uint32   `form:"id" binding:"required,gte=1"`
+	Title         string   `form:"title" binding:"max=100"`
+	Slug          string   `form:"slug" binding:"max=100"`
+	Desc          string   `form:"desc" binding:"max=255"`
+	Content       string   `form:"content"`
+	CoverImageUrl string   `form:"cover_image_url" binding:"omitempty,url"`
+	TagIDs        []uint32 `form:"tag_ids" binding:"dive,gte=1"`
+}
+
+type DeleteArticleRequest struct {
+	ID uint32 `form:"id" binding:"required,gte=1"`
+}
+
+func (svc *Service) GetArticle(param *ArticleRequest) (*model.Article, error) {
+	if param.State != model.ArticleStatePublished && !svc.canViewUnpublished() {
+		return nil, ErrPermissionDenied
+	}
+	article, err := svc.dao.GetArticle(param.ID, param.State)
+	if err == gorm.ErrRecordNotFound {
+		return nil, ErrArticleNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !svc.canViewArticle(article) {
+		return nil, ErrArticleNotFound
+	}
+	if err := svc.attachArticleTags([]*model.Article{article}); err != nil {
+		return nil, err
+	}
+	if err := renderArticles([]*model.Article{article}); err != nil {
+		return nil, err
+	}
+
+	return article, nil
+}
+
+func (svc *Service) CountArticle(param *ArticleListRequest) (int, error) {
+	if param.State != model.ArticleStatePublished && !svc.canViewUnpublished() {
+		return 0, ErrPermissionDenied
+	}
+	return svc.dao.CountArticle(param.Title, param.State, param.TagID, svc.articleOwnerFilter(param.State))
+}
+
+func (svc *Service) GetArticleList(param *ArticleListRequest, pager *app.Pager) ([]*model.Article, error) {
+	if param.State != model.ArticleStatePublished && !svc.canViewUnpublished() {
+		return nil, ErrPermissionDenied
+	}
+	articles, err := svc.dao.GetArticleList(param.Title, param.State, param.TagID, svc.articleOwnerFilter(param.State), pager.Page, pager.PageSize)
+	if err != nil {
+		return nil, err
+	}
+	if err := svc.attachArticleTags(articles); err != nil {
+		return nil, err
+	}
+	if err := renderArticles(articles); err != nil {
+		return nil, err
+	}
+
+	return articles, nil
+}
+
+func (svc *Service) CreateArticle(param *CreateArticleRequest) (*model.Article, error) {
+	var article *model.Article
+	err := svc.slugTransaction(func(tx *dao.Dao) error {
+		slug, err := uniqueArticleSlug(tx, articleSlugBase(param.Slug, param.Title), 0)
+		if err != nil {
+			return err
+		}
+		article, err = tx.CreateArticle(&dao.Article{
+			Title:         param.Title,
+			Slug:          slug,
+			Desc:          param.Desc,
+			Content:       param.Content,
+			CoverImageUrl: param.CoverImageUrl,
+			CreatedBy:     svc.operator(),
+			State:         param.State,
+		})
+		if err != nil {
+			return err
+		}
+		if err := svc.recordArticleCreated(tx, article); err != nil {
+			return err
+		}
+		if err := tx.CreateArticleRevision(newArticleRevision(article, 1, 0, svc.operator())); err != nil {
+			return err
+		}
+
+		article.Tags, err = setArticleTags(tx, article.ID, param.TagIDs, svc.operator())
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	if article.State == model.ArticleStatePublished {
+		articleIndex.Add(newSearchDocument(article))
+	}
+
+	return article, nil
+}
+
+func (svc *Service) UpdateArticle(param *UpdateArticleRequest) error {
+	err := svc.slugTransaction(func(tx *dao.Dao) error {
+		// 先锁定文章，并发修改同一篇文章时串行分配版本号
+		before, err := tx.LockArticle(param.ID)
+		if err == gorm.ErrRecordNotFound {
+			return ErrArticleNotFound
+		}
+		if err != nil {
+			return err
+		}
+		if err := svc.checkArticleOwner(tx, param.ID); err != nil {
+			return err
+		}
+		if err := svc.checkArticleEditable(before); err != nil {
+			return err
+		}
+
+		err = tx.UpdateArticle(&dao.Article{
+			ID:            param.ID,
+			Title:         param.Title,
+			Desc:          param.Desc,
+			Content:       param.Content,
+			CoverImageUrl: param.CoverImageUrl,
+			ModifiedBy:    svc.operator(),
+		})
+		if err != nil {
+			return err
+		}
+		if err := svc.snapshotArticle(tx, before, 0); err != nil {
+			return err
+		}
+		if param.Slug != "" || (param.Title != "" && param.Title != before.Title) {
+			if err := svc.changeArticleSlug(tx, before, articleSlugBase(param.Slug, param.Title)); err != nil {
+				return err
+			}
+		}
+		if param.TagIDs == nil {
+			return nil
+		}
+
+		_, err = setArticleTags(tx, param.ID, param.TagIDs, svc.operator())
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	svc.indexArticle(param.ID)
+
+	return nil
+}
+
+func (svc *Service) DeleteArticle(param *DeleteArticleRequest) error {
+	err := svc.dao.Transaction(func(tx *dao.Dao) error {
+		if err := svc.checkArticleOwner(tx, param.ID); err != nil {
+			return err
+		}
+		if err := tx.DeleteArticle(param.ID); err != nil {
+			return err
+		}
+
+		return tx.DeleteArticleTag(param.ID, nil)
+	})
+	if err != nil {
+		return err
+	}
+	articleIndex.Remove(param.ID)
+
+	return nil
+}
+
+// 作者只能修改或删除自己创建的文章，其余角色由路由权限控制
+func (svc *Service) checkArticleOwner(tx *dao.Dao, articleID uint32) error {
+	claims, ok := app.ClaimsFromContext(svc.ctx)
+	if !ok {
+		return ErrPermissionDenied
+	}
+	if claims.Role != model.UserRoleAuthor {
+		return nil
+	}
+
+	article, err := tx.GetArticleByID(articleID)
+	if err != nil {
+		return err
+	}
+	if article.CreatedBy != claims.Username {
+		return ErrPermissionDenied
+	}
+
+	return nil
+}
+
+// 将文章的标签关联同步为 tagIDs，返回同步后的标签
+func setArticleTags(tx *dao.Dao, articleID uint32, tagIDs []uint32, operator string) ([]*model.Tag, error) {
+	tagIDs = uniqueIDs(tagIDs)
+	var tags []*model.Tag
+	if len(tagIDs) > 0 {
+		var err error
+		tags, err = tx.GetTagListByIDs(tagIDs)
+		if err != nil {
+			return nil, err
+		}
+		if len(tags) != len(tagIDs) {
+			return nil, errors.New("some tags do not exist")
+		}
+	}
+
+	articleTags, err := tx.GetArticleTagListByAIDs([]uint32{articleID})
+	if err != nil {
+		return nil, err
+	}
+
+	wanted := make(map[uint32]bool, len(tagIDs))
+	for _, id := range tagIDs {
+		wanted[id] = true
+	}
+	var removed []uint32
+	existing := make(map[uint32]bool, len(articleTags))
+	for _, at := range articleTags {
+		existing[at.TagID] = true
+		if !wanted[at.TagID] {
+			removed = append(removed, at.TagID)
+		}
+	}
+
+	if len(removed) > 0 {
+		if err := tx.DeleteArticleTag(articleID, removed); err != nil {
+			return nil, err
+		}
+	}
+	for _, id := range tagIDs {
+		if existing[id] {
+			continue
+		}
+		if err := tx.CreateArticleTag(articleID, id, operator); err != nil {
+			return nil, err
+		}
+	}
+
+	return tags, nil
+}
+
+// 为文章集合批量填充标签
+func (svc *Service) attachArticleTags(articles []*model.Article) error {
+	if len(articles) == 0 {
+		return nil
+	}
+
+	articleIDs := make([]uint32, 0, len(articles))
+	for _, article := range articles {
+		articleIDs = append(articleIDs, article.ID)
+	}
+	articleTags, err := svc.dao.GetArticleTagListByAIDs(articleIDs)
+	if err != nil {
+		return err
+	}
+	if len(articleTags) == 0 {
+		return nil
+	}
+
+	tagIDs := make([]uint32, 0, len(articleTags))
+	for _, at := range articleTags {
+		tagIDs = append(tagIDs, at.TagID)
+	}
+	tags, err := svc.dao.GetTagListByIDs(uniqueIDs(tagIDs))
+	if err != nil {
+		return err
+	}
+	tagMap := make(map[uint32]*model.Tag, len(tags))
+	for _, tag := range tags {
+		tagMap[tag.ID] = tag
+	}
+
+	articleMap := make(map[uint32]*model.Article, len(articles))
+	for _, article := range articles {
+		articleMap[article.ID] = article
+	}
+	for _, at := range articleTags {
+		article, ok := articleMap[at.ArticleID]
+		tag, exist := tagMap[at.TagID]
+		if ok && exist {
+			article.Tags = append(article.Tags, tag)
+		}
+	}
+
+	return nil
+}
+
+// 将文章内容渲染为 HTML 并生成目录，相同内容的渲染结果会被缓存
+func renderArticles(articles []*model.Article) error {
+	for _, article := range articles {
+		result, err := markdown.RenderCached(article.Content)
+		if err != nil {
+			return err
+		}
+		article.ContentHTML = result.HTML
+		article.TOC = result.TOC
+	}
+
+	return nil
+}
+
+// 去重并保持原有顺序
+func uniqueIDs(ids []uint32) []uint32 {
+	seen := make(map[uint32]bool, len(ids))
+	result := make([]uint32, 0, len(ids))
+	for _, id := range ids {
+		if !seen[id] {
+			seen[id] = true
+			result = append(result, id)
+		}
+	}
+
+	return result
+}
@@ -0,0 +1,226 @@
+package service
+
+import (
+	"errors"
+
+	"github.com/go-programming-tour/blog-service/internal/dao"
+	"github.com/go-programming-tour/blog-service/internal/model"
+	"github.com/go-programming-tour/blog-service/pkg/diff"
+	"github.com/jinzhu/gorm"
+)
+
+// 版本差异中每段变更前后保留的行数
+const revisionDiffContext = 3
+
+var ErrArticleRevisionNotFound = errors.New("article revision not found")
+
+type ArticleRevisionListRequest struct {
+	ID uint32 `form:"id" binding:"required,gte=1"`
+}
+
+type ArticleRevisionRequest struct {
+	ID       uint32 `form:"id" binding:"required,gte=1"`
+	Revision uint32 `form:"revision" binding:"required,gte=1"`
+}
+
+type ArticleRevisionDiffRequest struct {
+	ID   uint32 `form:"id" binding:"required,gte=1"`
+	From uint32 `form:"from" binding:"required,gte=1"`
+	To   uint32 `form:"to" binding:"required,gte=1"`
+}
+
+// 单个字段修改前后的值
+type FieldChange struct {
+	Old string `json:"old"`
+	New string `json:"new"`
+}
+
+// 两个版本之间的差异，标题、简述和封面只在有变化时返回，内容按行比较
+type ArticleRevisionDiff struct {
+	ArticleID     uint32       `json:"article_id"`
+	From          uint32       `json:"from"`
+	To            uint32       `json:"to"`
+	Title         *FieldChange `json:"title,omitempty"`
+	Desc          *FieldChange `json:"desc,omitempty"`
+	CoverImageUrl *FieldChange `json:"cover_image_url,omitempty"`
+	Hunks         []*diff.Hunk `json:"hunks"`
+}
+
+// 返回文章的全部历史版本，不包含内容
+func (svc *Service) GetArticleRevisionList(param *ArticleRevisionListRequest) ([]*model.ArticleRevision, error) {
+	if err := svc.checkArticleRevisionAccess(param.ID); err != nil {
+		return nil, err
+	}
+
+	return svc.dao.GetArticleRevisionList(param.ID)
+}
+
+// 返回文章指定版本的完整内容
+func (svc *Service) GetArticleRevision(param *ArticleRevisionRequest) (*model.ArticleRevision, error) {
+	if err := svc.checkArticleRevisionAccess(param.ID); err != nil {
+		return nil, err
+	}
+
+	return svc.getArticleRevision(svc.dao, param.ID, param.Revision)
+}
+
+// 比较文章的两个版本
+func (svc *Service) DiffArticleRevisions(param *ArticleRevisionDiffRequest) (*ArticleRevisionDiff, error) {
+	if err := svc.checkArticleRevisionAccess(param.ID); err != nil {
+		return nil, err
+	}
+	from, err := svc.getArticleRevision(svc.dao, param.ID, param.From)
+	if err != nil {
+		return nil, err
+	}
+	to, err := svc.getArticleRevision(svc.dao, param.ID, param.To)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ArticleRevisionDiff{
+		ArticleID:     param.ID,
+		From:          param.From,
+		To:            param.To,
+		Title:         fieldChange(from.Title, to.Title),
+		Desc:          fieldChange(from.Desc, to.Desc),
+		CoverImageUrl: fieldChange(from.CoverImageUrl, to.CoverImageUrl),
+		Hunks:         diff.Unified(diff.Text(from.Content, to.Content), revisionDiffContext),
+	}, nil
+}
+
+// 将文章恢复为历史版本，恢复本身总是作为一个新版本保存，即使内容与当前版本相同，修改者为当前用户
+func (svc *Service) RestoreArticleRevision(param *ArticleRevisionRequest) (*model.ArticleRevision, error) {
+	var restored *model.ArticleRevision
+	err := svc.dao.Transaction(func(tx *dao.Dao) error {
+		before, err := tx.LockArticle(param.ID)
+		if err == gorm.ErrRecordNotFound {
+			return ErrArticleNotFound
+		}
+		if err != nil {
+			return err
+		}
+		if err := svc.checkArticleOwner(tx, param.ID); err != nil {
+			return err
+		}
+		if err := svc.checkArticleEditable(before); err != nil {
+			return err
+		}
+		revision, err := svc.getArticleRevision(tx, param.ID, param.Revision)
+		if err != nil {
+			return err
+		}
+
+		err = tx.RestoreArticle(&dao.Article{
+			ID:            param.ID,
+			Title:         revision.Title,
+			Desc:          revision.Desc,
+			Content:       revision.Content,
+			CoverImageUrl: revision.CoverImageUrl,
+			ModifiedBy:    svc.operator(),
+		})
+		if err != nil {
+			return err
+		}
+		if err := svc.snapshotArticle(tx, before, param.Revision); err != nil {
+			return err
+		}
+
+		restored, err = tx.GetLatestArticleRevision(param.ID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	svc.indexArticle(param.ID)
+
+	return restored, nil
+}
+
+// 在文章修改后保存新版本，before 为修改前的文章，调用前需要先用 LockArticle 锁定文章
+// 修改前还没有任何历史版本时，先把修改前的内容保存为第一个版本，避免丢失已有的内容
+// 普通修改的标题、简述、内容和封面都没有变化时不保存，恢复时总是保存
+func (svc *Service) snapshotArticle(tx *dao.Dao, before *model.Article, restoredFrom uint32) error {
+	latest, err := tx.GetLatestArticleRevision(before.ID)
+	if err != nil {
+		return err
+	}
+	previous := before
+	next := uint32(2)
+	if latest != nil {
+		previous = &model.Article{
+			Title:         latest.Title,
+			Desc:          latest.Desc,
+			Content:       latest.Content,
+			CoverImageUrl: latest.CoverImageUrl,
+		}
+		next = latest.Revision + 1
+	} else {
+		createdBy, createdOn := before.ModifiedBy, before.ModifiedOn
+		if createdBy == "" {
+			createdBy, createdOn = before.CreatedBy, before.CreatedOn
+		}
+		first := newArticleRevision(before, 1, 0, createdBy)
+		first.CreatedOn = createdOn
+		if err := tx.CreateArticleRevision(first); err != nil {
+			return err
+		}
+	}
+
+	after, err := tx.GetArticleByID(before.ID)
+	if err != nil {
+		return err
+	}
+	if restoredFrom == 0 && previous.Title == after.Title && previous.Desc == after.Desc &&
+		previous.Content == after.Content && previous.CoverImageUrl == after.CoverImageUrl {
+		return nil
+	}
+
+	return tx.CreateArticleRevision(newArticleRevision(after, next, restoredFrom, svc.operator()))
+}
+
+func (svc *Service) getArticleRevision(d *dao.Dao, articleID, revisionNo uint32) (*model.ArticleRevision, error) {
+	revision, err := d.GetArticleRevision(articleID, revisionNo)
+	if err != nil {
+		return nil, err
+	}
+	if revision == nil {
+		return nil, ErrArticleRevisionNotFound
+	}
+
+	return revision, nil
+}
+
+// 文章需要存在，作者只能查看自己文章的历史版本
+func (svc *Service) checkArticleRevisionAccess(articleID uint32) error {
+	_, err := svc.dao.GetArticleByID(articleID)
+	if err == gorm.ErrRecordNotFound {
+		return ErrArticleNotFound
+	}
+	if err != nil {
+		return err
+	}
+
+	return svc.checkArticleOwner(svc.dao, articleID)
+}
+
+func newArticleRevision(article *model.Article, revisionNo, restoredFrom uint32, createdBy string) *dao.ArticleRevision {
+	return &dao.ArticleRevision{
+		ArticleID:     article.ID,
+		Revision:      revisionNo,
+		Title:         article.Title,
+		Desc:          article.Desc,
+		Content:       article.Content,
+		CoverImageUrl: article.CoverImageUrl,
+		RestoredFrom:  restoredFrom,
+		CreatedBy:     createdBy,
+	}
+}
+
+func fieldChange(oldValue, newValue string) *FieldChange {
+	if oldValue == newValue {
+		return nil
+	}
+
+	return &FieldChange{Old: oldValue, New: newValue}
+}
@@ -0,0 +1,64 @@
+package service
+
+import (
+	"time"
+
+	"github.com/go-programming-tour/blog-service/global"
+	"github.com/go-programming-tour/blog-service/internal/dao"
+	"github.com/go-programming-tour/blog-service/internal/model"
+)
+
+const (
+	// 定时发布任务的租约名称
+	articlePublisherLease = "article_publisher"
+	// 定时发布每批处理的文章数
+	articlePublishBatch = 100
+	// 定时发布记录在状态变更中的操作人
+	articlePublisher = "scheduler"
+)
+
+// 发布所有发布时间已到的审核中文章，返回发布的数量
+// 多个实例同时运行时只有持有租约的实例执行，每批开始前续期租约，续期失败说明租约已被其他实例接手，立即停止
+// 单篇文章的状态变更本身也只会成功一次，svc 的 ctx 结束时在当前文章之后停止
+func (svc *Service) PublishDueArticles(holder string, leaseTTL time.Duration) (int, error) {
+	var count int
+	for {
+		if err := svc.ctx.Err(); err != nil {
+			return count, err
+		}
+		acquired, err := svc.dao.AcquireLease(articlePublisherLease, holder, leaseTTL)
+		if err != nil || !acquired {
+			return count, err
+		}
+
+		articles, err := svc.dao.GetDueArticleList(uint32(time.Now().Unix()), articlePublishBatch)
+		if err != nil {
+			return count, err
+		}
+		published := 0
+		for _, article := range articles {
+			if svc.ctx.Err() != nil {
+				break
+			}
+			err := svc.dao.Transaction(func(tx *dao.Dao) error {
+				return changeArticleState(tx, article, model.ArticleStatePublished, article.PublishOn, "定时发布", 0, articlePublisher)
+			})
+			if err != nil {
+				global.Logger.ErrorfT("publish article %d err: %v", article.ID, err)
+				continue
+			}
+			svc.indexArticle(article.ID)
+			published++
+		}
+		count += published
+		// 整批都失败时留到下次执行，避免反复处理同一批文章
+		if len(articles) < articlePublishBatch || published == 0 {
+			return count, nil
+		}
+	}
+}
+
+// 释放定时发布任务的租约，服务退出时调用，其他实例可以立即接手
+func (svc *Service) ReleaseArticlePublisher(holder string) error {
+	return svc.dao.ReleaseLease(articlePublisherLease, holder)
+}
@@ -0,0 +1,192 @@
+package service
+
+import (
+	"strconv"
+
+	"github.com/go-programming-tour/blog-service/internal/dao"
+	"github.com/go-programming-tour/blog-service/internal/model"
+	"github.com/go-programming-tour/blog-service/pkg/util"
+	"github.com/jinzhu/gorm"
+)
+
+const (
+	// 为已有文章生成别名时每批处理的文章数
+	articleSlugBatch = 500
+	// 别名违反唯一索引时分配别名的事务最多执行的次数
+	articleSlugAttempts = 3
+)
+
+// 状态：0 草稿，1 已发布，2 审核中，3 已归档
+type ArticleSlugRequest struct {
+	Slug  string `form:"slug" binding:"required,max=100"`
+	State uint8  `form:"state,default=1" binding:"oneof=0 1 2 3"`
+}
+
+// 访问的是文章的旧别名，Slug 为文章当前的别名
+type ArticleSlugMoved struct {
+	Slug string
+}
+
+func (e *ArticleSlugMoved) Error() string {
+	return "article slug moved to " + e.Slug
+}
+
+// 按别名返回文章，别名是文章的旧别名时返回 *ArticleSlugMoved，由调用方重定向到当前别名
+func (svc *Service) GetArticleBySlug(param *ArticleSlugRequest) (*model.Article, error) {
+	if param.State != model.ArticleStatePublished && !svc.canViewUnpublished() {
+		return nil, ErrPermissionDenied
+	}
+
+	article, err := svc.dao.GetArticleBySlug(param.Slug, param.State)
+	if err == gorm.ErrRecordNotFound {
+		return nil, svc.resolveOldSlug(param)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !svc.canViewArticle(article) {
+		return nil, ErrArticleNotFound
+	}
+	if err := svc.attachArticleTags([]*model.Article{article}); err != nil {
+		return nil, err
+	}
+	if err := renderArticles([]*model.Article{article}); err != nil {
+		return nil, err
+	}
+
+	return article, nil
+}
+
+// 旧别名对应的文章存在时返回 *ArticleSlugMoved，否则返回 ErrArticleNotFound
+func (svc *Service) resolveOldSlug(param *ArticleSlugRequest) error {
+	old, err := svc.dao.GetArticleSlug(param.Slug)
+	if err != nil {
+		return err
+	}
+	if old == nil {
+		return ErrArticleNotFound
+	}
+	article, err := svc.dao.GetArticle(old.ArticleID, param.State)
+	if err == gorm.ErrRecordNotFound {
+		return ErrArticleNotFound
+	}
+	if err != nil {
+		return err
+	}
+	if !svc.canViewArticle(article) {
+		return ErrArticleNotFound
+	}
+
+	return &ArticleSlugMoved{Slug: article.Slug}
+}
+
+// 为还没有别名的文章生成别名，返回生成的数量，不修改文章的 modified_on
+// 多个实例同时启动时可能同时生成，相同的别名由唯一索引保证只有一个成功，失败的一方重新生成
+func (svc *Service) GenerateArticleSlugs() (int, error) {
+	count := 0
+	var afterID uint32
+	for {
+		articles, err := svc.dao.GetArticleListWithoutSlug(afterID, articleSlugBatch)
+		if err != nil {
+			return count, err
+		}
+		for _, article := range articles {
+			afterID = article.ID
+			err := svc.slugTransaction(func(tx *dao.Dao) error {
+				return svc.changeArticleSlug(tx, article, util.Slugify(article.Title))
+			})
+			if err != nil {
+				return count, err
+			}
+			count++
+		}
+		if len(articles) < articleSlugBatch {
+			return count, nil
+		}
+	}
+}
+
+// 将文章的别名修改为由 base 生成的唯一别名，原来的别名保留为旧别名
+func (svc *Service) changeArticleSlug(tx *dao.Dao, article *model.Article, base string) error {
+	slug, err := uniqueArticleSlug(tx, base, article.ID)
+	if err != nil {
+		return err
+	}
+	if slug == article.Slug {
+		return nil
+	}
+
+	old, err := tx.GetArticleSlug(slug)
+	if err != nil {
+		return err
+	}
+	// 重新使用自己的旧别名
+	if old != nil {
+		if err := tx.DeleteArticleSlug(slug); err != nil {
+			return err
+		}
+	}
+	if article.Slug != "" {
+		if err := tx.CreateArticleSlug(article.Slug, article.ID, svc.operator()); err != nil {
+			return err
+		}
+	}
+	if err := tx.UpdateArticleSlug(article.ID, slug); err != nil {
+		return err
+	}
+	article.Slug = slug
+
+	return nil
+}
+
+// 指定了别名时按别名规范化，否则由标题生成
+func articleSlugBase(slug, title string) string {
+	if slug != "" {
+		return util.Slugify(slug)
+	}
+
+	return util.Slugify(title)
+}
+
+// 返回由 base 生成的、未被其他文章使用的别名，冲突时依次追加 -2、-3 等后缀
+// articleID 为 0 表示新文章，检查和写入之间的并发冲突由唯一索引发现，调用方通过 slugTransaction 重试
+func uniqueArticleSlug(tx *dao.Dao, base string, articleID uint32) (string, error) {
+	slug := base
+	for i := 2; ; i++ {
+		taken, err := articleSlugTaken(tx, slug, articleID)
+		if err != nil {
+			return "", err
+		}
+		if !taken {
+			return slug, nil
+		}
+		slug = base + "-" + strconv.Itoa(i)
+	}
+}
+
+// 别名是其他文章的当前别名或旧别名时视为已被使用
+func articleSlugTaken(tx *dao.Dao, slug string, articleID uint32) (bool, error) {
+	exists, err := tx.ArticleSlugExists(slug, articleID)
+	if err != nil || exists {
+		return exists, err
+	}
+	old, err := tx.GetArticleSlug(slug)
+	if err != nil {
+		return false, err
+	}
+
+	return old != nil && old.ArticleID != articleID, nil
+}
+
+// 执行会分配别名的事务，并发分配到相同的别名违反唯一索引时重新执行，最多执行 articleSlugAttempts 次
+func (svc *Service) slugTransaction(fn func(tx *dao.Dao) error) error {
+	var err error
+	for i := 0; i < articleSlugAttempts; i++ {
+		err = svc.dao.Transaction(fn)
+		if !model.IsDuplicateKeyError(err) {
+			return err
+		}
+	}
+
+	return err
+}
@@ -0,0 +1,245 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/go-programming-tour/blog-service/internal/dao"
+	"github.com/go-programming-tour/blog-service/internal/model"
+	"github.com/go-programming-tour/blog-service/pkg/app"
+	"github.com/jinzhu/gorm"
+)
+
+var (
+	ErrArticleNotFound        = errors.New("article not found")
+	ErrArticleStateTransition = errors.New("illegal article state transition")
+	ErrArticleNotEditable     = errors.New("article is not a draft")
+)
+
+// 可以审核、发布和归档文章的角色
+var articleReviewers = []string{model.UserRoleAdmin, model.UserRoleEditor}
+
+// 允许的状态变更及可以执行该变更的角色，作者只能变更自己的文章
+var articleTransitions = map[uint8]map[uint8][]string{
+	model.ArticleStateDraft: {
+		model.ArticleStateInReview: {model.UserRoleAdmin, model.UserRoleEditor, model.UserRoleAuthor},
+	},
+	model.ArticleStateInReview: {
+		model.ArticleStateDraft:     {model.UserRoleAdmin, model.UserRoleEditor, model.UserRoleAuthor},
+		model.ArticleStatePublished: articleReviewers,
+	},
+	model.ArticleStatePublished: {
+		model.ArticleStateArchived: articleReviewers,
+	},
+	model.ArticleStateArchived: {
+		model.ArticleStateDraft: articleReviewers,
+	},
+}
+
+type UpdateArticleStateRequest struct {
+	ID        uint32 `form:"id" binding:"required,gte=1"`
+	State     string `form:"state" binding:"required,oneof=draft in_review published archived"`
+	Comment   string `form:"comment" binding:"max=255"`
+	PublishOn uint32 `form:"publish_on"` // 目标状态为 published 且时间在未来时定时发布
+}
+
+type ArticleTransitionListRequest struct {
+	ID uint32 `form:"id" binding:"required,gte=1"`
+}
+
+// 一次状态变更
+type ArticleTransitionInfo struct {
+	From      string `json:"from"`
+	To        string `json:"to"`
+	Operator  string `json:"operator"`
+	Comment   string `json:"comment"`
+	PublishOn uint32 `json:"publish_on"` // 设置定时发布的记录为计划的发布时间，from 和 to 相同
+	CreatedOn uint32 `json:"created_on"`
+}
+
+// 按工作流变更文章状态，并记录操作人和操作时间
+// 发布时指定了未来的发布时间则只设置定时发布，不立即变更状态
+func (svc *Service) UpdateArticleState(param *UpdateArticleStateRequest) error {
+	to, _ := model.ParseArticleState(param.State)
+	err := svc.dao.Transaction(func(tx *dao.Dao) error {
+		return svc.transitArticle(tx, param.ID, to, param.PublishOn, param.Comment)
+	})
+	if err != nil {
+		return err
+	}
+	svc.indexArticle(param.ID)
+
+	return nil
+}
+
+// 返回文章的状态变更记录，作者只能查看自己文章的记录
+func (svc *Service) GetArticleTransitionList(param *ArticleTransitionListRequest) ([]*ArticleTransitionInfo, error) {
+	if _, err := svc.dao.GetArticleByID(param.ID); err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, ErrArticleNotFound
+		}
+		return nil, err
+	}
+	if err := svc.checkArticleOwner(svc.dao, param.ID); err != nil {
+		return nil, err
+	}
+
+	transitions, err := svc.dao.GetArticleTransitionList(param.ID)
+	if err != nil {
+		return nil, err
+	}
+	infos := make([]*ArticleTransitionInfo, 0, len(transitions))
+	for _, transition := range transitions {
+		infos = append(infos, &ArticleTransitionInfo{
+			From:      model.ArticleStateName(transition.FromState),
+			To:        model.ArticleStateName(transition.ToState),
+			Operator:  transition.CreatedBy,
+			Comment:   transition.Comment,
+			PublishOn: transition.PublishOn,
+			CreatedOn: transition.CreatedOn,
+		})
+	}
+
+	return infos, nil
+}
+
+func (svc *Service) transitArticle(tx *dao.Dao, articleID uint32, to uint8, publishOn uint32, comment string) error {
+	article, err := tx.GetArticleByID(articleID)
+	if err == gorm.ErrRecordNotFound {
+		return ErrArticleNotFound
+	}
+	if err != nil {
+		return err
+	}
+
+	roles, ok := articleTransitions[article.State][to]
+	if !ok {
+		return fmt.Errorf("%w: from %s to %s", ErrArticleStateTransition,
+			model.ArticleStateName(article.State), model.ArticleStateName(to))
+	}
+	claims, ok := app.ClaimsFromContext(svc.ctx)
+	if !ok || !containsRole(roles, claims.Role) {
+		return ErrPermissionDenied
+	}
+	if err := svc.checkArticleOwner(tx, articleID); err != nil {
+		return err
+	}
+
+	now := uint32(time.Now().Unix())
+	if to == model.ArticleStatePublished && publishOn > now {
+		// 定时发布：文章保持审核中，到期后由 PublishDueArticles 发布，批准发布的审核人记录在这次变更中
+		scheduled, err := tx.ScheduleArticle(articleID, publishOn, claims.Username)
+		if err != nil {
+			return err
+		}
+		if !scheduled {
+			return errArticleStateChanged(articleID)
+		}
+		return tx.CreateArticleTransition(&dao.ArticleTransition{
+			ArticleID:  articleID,
+			FromState:  article.State,
+			ToState:    article.State,
+			OperatorID: claims.UserID,
+			Comment:    comment,
+			PublishOn:  publishOn,
+			CreatedBy:  claims.Username,
+		})
+	}
+	if to == model.ArticleStatePublished {
+		publishOn = now
+	} else {
+		publishOn = 0
+	}
+
+	return changeArticleState(tx, article, to, publishOn, comment, claims.UserID, claims.Username)
+}
+
+// 修改文章状态并记录状态变更，文章状态已被其他请求修改时返回 ErrArticleStateTransition
+func changeArticleState(tx *dao.Dao, article *model.Article, to uint8, publishOn uint32, comment string, operatorID uint32, operator string) error {
+	updated, err := tx.UpdateArticleState(article.ID, article.State, to, publishOn, operator)
+	if err != nil {
+		return err
+	}
+	if !updated {
+		return errArticleStateChanged(article.ID)
+	}
+
+	return tx.CreateArticleTransition(&dao.ArticleTransition{
+		ArticleID:  article.ID,
+		FromState:  article.State,
+		ToState:    to,
+		OperatorID: operatorID,
+		Comment:    comment,
+		CreatedBy:  operator,
+	})
+}
+
+func errArticleStateChanged(articleID uint32) error {
+	return fmt.Errorf("%w: state of article %d has changed", ErrArticleStateTransition, articleID)
+}
+
+// 作者只能修改草稿，审核中、已发布和已归档的文章需要先退回草稿，修改后重新审核才能生效
+func (svc *Service) checkArticleEditable(article *model.Article) error {
+	claims, ok := app.ClaimsFromContext(svc.ctx)
+	if !ok || claims.Role != model.UserRoleAuthor || article.State == model.ArticleStateDraft {
+		return nil
+	}
+
+	return ErrArticleNotEditable
+}
+
+// 未登录用户和读者只能查看已发布的文章
+func (svc *Service) canViewUnpublished() bool {
+	claims, ok := app.ClaimsFromContext(svc.ctx)
+	if !ok {
+		return false
+	}
+
+	return claims.Role != model.UserRoleReader
+}
+
+// 作者查询未发布的文章时只返回自己创建的文章，返回需要限制的创建者，不需要限制时返回空字符串
+func (svc *Service) articleOwnerFilter(state uint8) string {
+	if state == model.ArticleStatePublished {
+		return ""
+	}
+	claims, ok := app.ClaimsFromContext(svc.ctx)
+	if !ok || claims.Role != model.UserRoleAuthor {
+		return ""
+	}
+
+	return claims.Username
+}
+
+// 作者不能查看其他作者未发布的文章
+func (svc *Service) canViewArticle(article *model.Article) bool {
+	owner := svc.articleOwnerFilter(article.State)
+
+	return owner == "" || article.CreatedBy == owner
+}
+
+func containsRole(roles []string, role string) bool {
+	for _, r := range roles {
+		if r == role {
+			return true
+		}
+	}
+
+	return false
+}
+
+// 创建时直接提交审核的文章从草稿开始记录状态变更
+func (svc *Service) recordArticleCreated(tx *dao.Dao, article *model.Article) error {
+	if article.State == model.ArticleStateDraft {
+		return nil
+	}
+
+	return tx.CreateArticleTransition(&dao.ArticleTransition{
+		ArticleID:  article.ID,
+		FromState:  model.ArticleStateDraft,
+		ToState:    article.State,
+		OperatorID: svc.operatorID(),
+		CreatedBy:  svc.operator(),
+	})
+}
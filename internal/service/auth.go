@@ -1,21 +0,0 @@
-package service
-
-import "errors"
-
-type AuthRequest struct {
-	AppKey    string `form:"app_key" binding:"required"`
-	AppSecret string `form:"app_secret" bindind:"required"`
-}
-
-func (svc *Service) CheckAuth(param *AuthRequest) error {
-	auth, err := svc.dao.GetAuth(param.AppKey, param.AppSecret)
-	if err != nil {
-		return nil
-	}
-
-	if auth.ID > 0 {
-		return nil
-	}
-
-	return errors.New("auth info does not exist")
-}
@@ -0,0 +1,225 @@
+package service
+
+import (
+	"mime"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-programming-tour/blog-service/global"
+	"github.com/go-programming-tour/blog-service/internal/model"
+	"github.com/go-programming-tour/blog-service/pkg/feed"
+	"github.com/go-programming-tour/blog-service/pkg/markdown"
+	"github.com/go-programming-tour/blog-service/pkg/upload"
+	"github.com/go-programming-tour/blog-service/pkg/util"
+	"github.com/jinzhu/gorm"
+)
+
+// 订阅源的格式
+const (
+	FeedFormatRSS  = "rss"
+	FeedFormatAtom = "atom"
+)
+
+type TagFeedRequest struct {
+	ID uint32 `form:"id" binding:"required,gte=1"`
+}
+
+// 生成的订阅源，ETag 由内容计算，LastModified 为最新的文章修改时间
+type FeedResult struct {
+	Body         []byte
+	ContentType  string
+	ETag         string
+	LastModified time.Time
+}
+
+// 订阅源在内存中缓存 FeedCacheTTL，缓存期内的请求只比较 ETag 和 Last-Modified，不访问数据库
+var feedCache = struct {
+	sync.Mutex
+	items map[string]*cachedFeed
+}{items: make(map[string]*cachedFeed)}
+
+type cachedFeed struct {
+	result    *FeedResult
+	expiresAt time.Time
+}
+
+// 返回最新发布文章的订阅源
+func (svc *Service) GetArticleFeed(format string) (*FeedResult, error) {
+	return cachedFeedResult(format, func() (*FeedResult, error) {
+		return svc.buildFeed(format, nil)
+	})
+}
+
+// 返回关联了指定标签的最新发布文章的订阅源，标签不存在或未启用时返回 ErrTagNotFound
+func (svc *Service) GetTagFeed(param *TagFeedRequest, format string) (*FeedResult, error) {
+	return cachedFeedResult(format+":tag:"+strconv.FormatUint(uint64(param.ID), 10), func() (*FeedResult, error) {
+		tag, err := svc.dao.GetTag(param.ID, model.TagStateEnabled)
+		if err == gorm.ErrRecordNotFound {
+			return nil, ErrTagNotFound
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		return svc.buildFeed(format, tag)
+	})
+}
+
+func cachedFeedResult(key string, build func() (*FeedResult, error)) (*FeedResult, error) {
+	ttl := global.AppSetting.FeedCacheTTL
+	now := time.Now()
+	feedCache.Lock()
+	if cached, ok := feedCache.items[key]; ok && now.Before(cached.expiresAt) {
+		feedCache.Unlock()
+		return cached.result, nil
+	}
+	feedCache.Unlock()
+
+	result, err := build()
+	if err != nil || ttl <= 0 {
+		return result, err
+	}
+
+	feedCache.Lock()
+	defer feedCache.Unlock()
+	for k, cached := range feedCache.items {
+		if !now.Before(cached.expiresAt) {
+			delete(feedCache.items, k)
+		}
+	}
+	feedCache.items[key] = &cachedFeed{result: result, expiresAt: now.Add(ttl)}
+
+	return result, nil
+}
+
+// tag 不为 nil 时只包含关联了该标签的文章
+func (svc *Service) buildFeed(format string, tag *model.Tag) (*FeedResult, error) {
+	setting := global.AppSetting
+	var tagID uint32
+	f := &feed.Feed{
+		ID:          setting.FeedServerUrl + "/feed.atom",
+		Title:       setting.FeedTitle,
+		Description: setting.FeedDescription,
+		Link:        setting.FeedSiteUrl,
+		Self:        setting.FeedServerUrl + "/feed." + format,
+	}
+	if tag != nil {
+		tagID = tag.ID
+		tagPath := "/tags/" + strconv.FormatUint(uint64(tag.ID), 10)
+		f.ID = setting.FeedServerUrl + tagPath + "/feed.atom"
+		f.Title = setting.FeedTitle + " - " + tag.Name
+		f.Link = setting.FeedSiteUrl + tagPath
+		f.Self = setting.FeedServerUrl + tagPath + "/feed." + format
+	}
+
+	articles, err := svc.dao.GetLatestArticleList(tagID, setting.FeedSize)
+	if err != nil {
+		return nil, err
+	}
+	if err := svc.attachArticleTags(articles); err != nil {
+		return nil, err
+	}
+	for _, article := range articles {
+		item, err := svc.newFeedItem(article)
+		if err != nil {
+			return nil, err
+		}
+		f.Items = append(f.Items, item)
+	}
+	f.Updated = feed.LastUpdated(f.Items)
+	if f.Updated.IsZero() {
+		f.Updated = time.Unix(0, 0)
+	}
+
+	result := &FeedResult{LastModified: f.Updated}
+	if format == FeedFormatRSS {
+		result.Body, err = f.RSS()
+		result.ContentType = feed.RSSContentType
+	} else {
+		result.Body, err = f.Atom()
+		result.ContentType = feed.AtomContentType
+	}
+	if err != nil {
+		return nil, err
+	}
+	result.ETag = `"` + util.EncodeSHA256(string(result.Body))[:32] + `"`
+
+	return result, nil
+}
+
+func (svc *Service) newFeedItem(article *model.Article) (*feed.Item, error) {
+	setting := global.AppSetting
+	slug := article.Slug
+	if slug == "" {
+		slug = strconv.FormatUint(uint64(article.ID), 10)
+	}
+	published := article.PublishOn
+	if published == 0 {
+		published = article.CreatedOn
+	}
+	updated := article.ModifiedOn
+	if updated < published {
+		updated = published
+	}
+
+	item := &feed.Item{
+		ID:        setting.FeedServerUrl + "/api/v1/public/articles/" + strconv.FormatUint(uint64(article.ID), 10),
+		Title:     article.Title,
+		Link:      setting.FeedSiteUrl + "/articles/" + url.PathEscape(slug),
+		Author:    article.CreatedBy,
+		Summary:   article.Desc,
+		Published: time.Unix(int64(published), 0),
+		Updated:   time.Unix(int64(updated), 0),
+	}
+	for _, tag := range article.Tags {
+		item.Categories = append(item.Categories, tag.Name)
+	}
+	if setting.FeedFullContent {
+		rendered, err := markdown.RenderCached(article.Content)
+		if err != nil {
+			return nil, err
+		}
+		item.Content = rendered.HTML
+	}
+	if article.CoverImageUrl != "" {
+		enclosure, err := svc.coverEnclosure(article.CoverImageUrl)
+		if err != nil {
+			return nil, err
+		}
+		item.Enclosure = enclosure
+	}
+
+	return item, nil
+}
+
+// 封面是上传的原图时从文件记录中读取大小和类型，否则根据后缀推断类型，大小未知
+// 衍生图与原图的哈希相同，通过文件路径区分
+func (svc *Service) coverEnclosure(coverImageUrl string) (*feed.Enclosure, error) {
+	enclosure := &feed.Enclosure{URL: coverImageUrl}
+	var coverPath string
+	if u, err := url.Parse(coverImageUrl); err == nil {
+		coverPath = u.Path
+		enclosure.Type = mime.TypeByExtension(path.Ext(coverPath))
+	}
+	if hashes := upload.ExtractFileHashes(coverPath); len(hashes) > 0 {
+		file, err := svc.dao.GetStoredFileByHash(hashes[0], false)
+		if err != nil {
+			return nil, err
+		}
+		if file != nil && strings.HasSuffix(coverPath, "/"+file.Path) {
+			enclosure.Length = file.Size
+			if file.MimeType != "" {
+				enclosure.Type = file.MimeType
+			}
+		}
+	}
+	if enclosure.Type == "" {
+		enclosure.Type = "application/octet-stream"
+	}
+
+	return enclosure, nil
+}
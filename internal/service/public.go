@@ -0,0 +1,66 @@
+package service
+
+import (
+	"github.com/go-programming-tour/blog-service/internal/model"
+	"github.com/go-programming-tour/blog-service/pkg/app"
+)
+
+// 公开接口不需要登录，只返回已发布的文章和启用的标签，不接受状态参数
+
+type PublicArticleRequest struct {
+	ID uint32 `form:"id" binding:"required,gte=1"`
+}
+
+type PublicArticleSlugRequest struct {
+	Slug string `form:"slug" binding:"required,max=100"`
+}
+
+type PublicArticleListRequest struct {
+	Title string `form:"title" binding:"max=100"`
+	TagID uint32 `form:"tag_id" binding:"gte=0"`
+}
+
+type PublicTagRequest struct {
+	ID uint32 `form:"id" binding:"required,gte=1"`
+}
+
+type PublicTagListRequest struct {
+	Name             string `form:"name" binding:"max=100"`
+	WithArticleCount bool   `form:"with_article_count"`
+}
+
+func (svc *Service) GetPublicArticle(param *PublicArticleRequest) (*model.Article, error) {
+	return svc.GetArticle(&ArticleRequest{ID: param.ID, State: model.ArticleStatePublished})
+}
+
+func (svc *Service) GetPublicArticleBySlug(param *PublicArticleSlugRequest) (*model.Article, error) {
+	return svc.GetArticleBySlug(&ArticleSlugRequest{Slug: param.Slug, State: model.ArticleStatePublished})
+}
+
+func (svc *Service) CountPublicArticle(param *PublicArticleListRequest) (int, error) {
+	return svc.CountArticle(param.articleListRequest())
+}
+
+func (svc *Service) GetPublicArticleList(param *PublicArticleListRequest, pager *app.Pager) ([]*model.Article, error) {
+	return svc.GetArticleList(param.articleListRequest(), pager)
+}
+
+func (svc *Service) GetPublicTag(param *PublicTagRequest) (*model.Tag, error) {
+	return svc.GetTag(&TagRequest{ID: param.ID, State: model.TagStateEnabled})
+}
+
+func (svc *Service) CountPublicTag(param *PublicTagListRequest) (int, error) {
+	return svc.CountTag(&CountTagRequest{Name: param.Name, State: model.TagStateEnabled})
+}
+
+func (svc *Service) GetPublicTagList(param *PublicTagListRequest, pager *app.Pager) ([]*model.Tag, error) {
+	return svc.GetTagList(&TagListRequest{
+		Name:             param.Name,
+		State:            model.TagStateEnabled,
+		WithArticleCount: param.WithArticleCount,
+	}, pager)
+}
+
+func (r *PublicArticleListRequest) articleListRequest() *ArticleListRequest {
+	return &ArticleListRequest{Title: r.Title, TagID: r.TagID, State: model.ArticleStatePublished}
+}
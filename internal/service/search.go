@@ -0,0 +1,81 @@
+package service
+
+import (
+	"github.com/go-programming-tour/blog-service/global"
+	"github.com/go-programming-tour/blog-service/internal/model"
+	"github.com/go-programming-tour/blog-service/pkg/app"
+	"github.com/go-programming-tour/blog-service/pkg/search"
+	"github.com/jinzhu/gorm"
+)
+
+// 建立搜索索引时每批读取的文章数
+const searchIndexBatch = 500
+
+// 已发布文章的搜索索引，只保存在当前进程中
+// 启动后在后台从数据库建立并定期重建，本实例的文章变更会立即增量更新，其他实例的变更在下次重建后生效
+var articleIndex = search.NewIndex()
+
+type SearchRequest struct {
+	Q string `form:"q" binding:"required,max=100"`
+}
+
+// 在已发布文章的标题、简述和内容中搜索，按相关度排序
+func (svc *Service) SearchArticles(param *SearchRequest, pager *app.Pager) ([]*search.Hit, int) {
+	offset := app.GetPageOffset(pager.Page, pager.PageSize)
+
+	return articleIndex.Search(param.Q, offset, pager.PageSize)
+}
+
+// 从数据库重新建立全部已发布文章的搜索索引，svc 的 ctx 结束时放弃重建并保留原有的索引
+func (svc *Service) BuildSearchIndex() error {
+	var docs []*search.Document
+	var afterID uint32
+	for {
+		if err := svc.ctx.Err(); err != nil {
+			return err
+		}
+		articles, err := svc.dao.GetArticleListForIndex(afterID, searchIndexBatch)
+		if err != nil {
+			return err
+		}
+		for _, article := range articles {
+			afterID = article.ID
+			docs = append(docs, newSearchDocument(article))
+		}
+		if len(articles) < searchIndexBatch {
+			break
+		}
+	}
+	articleIndex.Reset(docs)
+
+	return nil
+}
+
+// 按文章的最新状态更新索引，未发布或已删除的文章从索引中移除
+// 索引只是数据库的副本，更新失败时只记录日志，不影响文章本身的修改
+func (svc *Service) indexArticle(id uint32) {
+	article, err := svc.dao.GetArticleByID(id)
+	if err == gorm.ErrRecordNotFound {
+		articleIndex.Remove(id)
+		return
+	}
+	if err != nil {
+		global.Logger.ErrorfT("svc.dao.GetArticleByID err: %v", err)
+		return
+	}
+	if article.State != model.ArticleStatePublished {
+		articleIndex.Remove(id)
+		return
+	}
+
+	articleIndex.Add(newSearchDocument(article))
+}
+
+func newSearchDocument(article *model.Article) *search.Document {
+	return &search.Document{
+		ID:      article.ID,
+		Title:   article.Title,
+		Desc:    article.Desc,
+		Content: article.Content,
+	}
+}
@@ -2,11 +2,16 @@ package service
 
 import (
 	"context"
+	"errors"
 
 	"github.com/go-programming-tour/blog-service/global"
 	"github.com/go-programming-tour/blog-service/internal/dao"
+	"github.com/go-programming-tour/blog-service/pkg/app"
 )
 
+// 当前用户无权执行操作时返回
+var ErrPermissionDenied = errors.New("permission denied")
+
 // service 对象
 type Service struct {
 	ctx context.Context
@@ -20,3 +25,21 @@ func New(ctx context.Context) Service {
 
 	return svc
 }
+
+// 返回当前登录用户的用户名，用于填充 created_by 和 modified_by
+func (svc *Service) operator() string {
+	if claims, ok := app.ClaimsFromContext(svc.ctx); ok {
+		return claims.Username
+	}
+
+	return ""
+}
+
+// 返回当前登录用户的 ID，未登录时返回 0
+func (svc *Service) operatorID() uint32 {
+	if claims, ok := app.ClaimsFromContext(svc.ctx); ok {
+		return claims.UserID
+	}
+
+	return 0
+}
@@ -1,10 +1,16 @@
 package service
 
 import (
+	"errors"
+
+	"github.com/go-programming-tour/blog-service/internal/dao"
 	"github.com/go-programming-tour/blog-service/internal/model"
 	"github.com/go-programming-tour/blog-service/pkg/app"
+	"github.com/jinzhu/gorm"
 )
 
+var ErrTagNotFound = errors.New("tag not found")
+
 // 为不同的接口写参数验证结构体
 // form标签：入参字段名
 // binding标签：入参校验的规则内容
@@ -13,22 +19,27 @@ type CountTagRequest struct {
 	State uint8  `form:"name,default=1" binding:"oneof=0 1"`
 }
 
-type TagListRequest struct {
-	Name  string `form:"name" binding:"max=100"`
+type TagRequest struct {
+	ID    uint32 `form:"id" binding:"required,gte=1"`
 	State uint8  `form:"state,default=1" binding:"oneof=0 1"`
 }
 
+type TagListRequest struct {
+	Name             string `form:"name" binding:"max=100"`
+	State            uint8  `form:"state,default=1" binding:"oneof=0 1"`
+	WithArticleCount bool   `form:"with_article_count"`
+}
+
+// 创建者和修改者取自当前登录用户，不再从请求中读取
 type CreateTagRequest struct {
-	Name      string `form:"name" binding:"required,min=3,max=100"`
-	CreatedBy string `form:"created_by" binding:"required,min=3,max=100"`
-	State     uint8  `form:"state,default=1" binding:"oneof=0 1"`
+	Name  string `form:"name" binding:"required,min=3,max=100"`
+	State uint8  `form:"state,default=1" binding:"oneof=0 1"`
 }
 
 type UpdateTagRequest struct {
-	ID         uint32 `form:"id" binding:"required,gte=1"`
-	Name       string `form:"name" binding:"max=100"`
-	State      uint8  `form:"state" binding:"oneof=0 1"`
-	ModifiedBy string `form:"modified_by" binding:"required,min=3,max=100"`
+	ID    uint32 `form:"id" binding:"required,gte=1"`
+	Name  string `form:"name" binding:"max=100"`
+	State uint8  `form:"state" binding:"oneof=0 1"`
 }
 
 type DeleteTagRequest struct {
@@ -39,18 +50,73 @@ func (svc *Service) CountTag(param *CountTagRequest) (int, error) {
 	return svc.dao.CountTag(param.Name, param.State)
 }
 
+// 返回标签详情，包含关联的已发布文章数量
+func (svc *Service) GetTag(param *TagRequest) (*model.Tag, error) {
+	tag, err := svc.dao.GetTag(param.ID, param.State)
+	if err == gorm.ErrRecordNotFound {
+		return nil, ErrTagNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := svc.attachArticleCount([]*model.Tag{tag}); err != nil {
+		return nil, err
+	}
+
+	return tag, nil
+}
+
 func (svc *Service) GetTagList(param *TagListRequest, paper *app.Pager) ([]*model.Tag, error) {
-	return svc.dao.GetTagList(param.Name, param.State, paper.Page, paper.PageSize)
+	tags, err := svc.dao.GetTagList(param.Name, param.State, paper.Page, paper.PageSize)
+	if err != nil {
+		return nil, err
+	}
+	if param.WithArticleCount {
+		if err := svc.attachArticleCount(tags); err != nil {
+			return nil, err
+		}
+	}
+
+	return tags, nil
 }
 
 func (svc *Service) CreateTag(param *CreateTagRequest) error {
-	return svc.dao.CreateTag(param.Name, param.State, param.CreatedBy)
+	return svc.dao.CreateTag(param.Name, param.State, svc.operator())
 }
 
 func (svc *Service) UpdateTag(param *UpdateTagRequest) error {
-	return svc.dao.UpdateTag(param.ID, param.Name, param.State, param.ModifiedBy)
+	return svc.dao.UpdateTag(param.ID, param.Name, param.State, svc.operator())
 }
 
+// 删除标签的同时删除它与文章的关联
 func (svc *Service) DeleteTag(param *DeleteTagRequest) error {
-	return svc.dao.DeleteTag(param.ID)
+	return svc.dao.Transaction(func(tx *dao.Dao) error {
+		if err := tx.DeleteTag(param.ID); err != nil {
+			return err
+		}
+
+		return tx.DeleteArticleTagByTagID(param.ID)
+	})
+}
+
+// 为标签集合批量填充已发布文章数量
+func (svc *Service) attachArticleCount(tags []*model.Tag) error {
+	if len(tags) == 0 {
+		return nil
+	}
+
+	tagIDs := make([]uint32, 0, len(tags))
+	for _, tag := range tags {
+		tagIDs = append(tagIDs, tag.ID)
+	}
+	counts, err := svc.dao.CountArticleByTagIDs(tagIDs)
+	if err != nil {
+		return err
+	}
+	for _, tag := range tags {
+		count := counts[tag.ID]
+		tag.ArticleCount = &count
+	}
+
+	return nil
 }
@@ -0,0 +1,144 @@
+package service
+
+import (
+	"errors"
+	"time"
+
+	"github.com/go-programming-tour/blog-service/global"
+	"github.com/go-programming-tour/blog-service/internal/dao"
+	"github.com/go-programming-tour/blog-service/internal/model"
+	"github.com/go-programming-tour/blog-service/pkg/app"
+	"github.com/go-programming-tour/blog-service/pkg/util"
+)
+
+var (
+	ErrRefreshTokenInvalid = errors.New("refresh token is invalid or expired")
+	ErrRefreshTokenReused  = errors.New("refresh token has been reused")
+)
+
+type RefreshTokenRequest struct {
+	RefreshToken string `form:"refresh_token" binding:"required"`
+}
+
+type LogoutRequest struct {
+	RefreshToken string `form:"refresh_token"`
+}
+
+// 登录和刷新时返回的令牌对
+type TokenPair struct {
+	Token        string `json:"token"`
+	RefreshToken string `json:"refresh_token"`
+	ExpiresIn    int64  `json:"expires_in"`
+}
+
+// 为用户签发访问令牌和刷新令牌，familyID 为空时开始新的 family
+func (svc *Service) IssueTokens(user *model.User, familyID string) (*TokenPair, error) {
+	return issueTokens(svc.dao, user, familyID)
+}
+
+// 轮换刷新令牌：旧令牌失效并签发同一 family 的新令牌
+// 已使用过的令牌再次出现时视为泄露，吊销整个 family
+func (svc *Service) RefreshToken(param *RefreshTokenRequest) (*TokenPair, error) {
+	var pair *TokenPair
+	var reused bool
+	err := svc.dao.Transaction(func(tx *dao.Dao) error {
+		token, err := tx.GetRefreshToken(util.EncodeSHA256(param.RefreshToken))
+		if err != nil {
+			return err
+		}
+		now := uint32(time.Now().Unix())
+		if token == nil || token.RevokedOn != 0 || token.ExpiresOn <= now {
+			return ErrRefreshTokenInvalid
+		}
+		if token.UsedOn != 0 {
+			reused = true
+			return tx.RevokeRefreshTokenFamily(token.FamilyID, now)
+		}
+		ok, err := tx.MarkRefreshTokenUsed(token.ID, now)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			reused = true
+			return tx.RevokeRefreshTokenFamily(token.FamilyID, now)
+		}
+
+		user, err := tx.GetUser(token.UserID)
+		if err != nil {
+			return err
+		}
+		if user == nil || user.State != 1 {
+			return ErrRefreshTokenInvalid
+		}
+
+		pair, err = issueTokens(tx, user, token.FamilyID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	if reused {
+		// 吊销 family 与检测到复用在同一个事务中提交
+		return nil, ErrRefreshTokenReused
+	}
+
+	return pair, nil
+}
+
+// 吊销当前的访问令牌，传入刷新令牌时一并吊销它所在的 family
+func (svc *Service) Logout(param *LogoutRequest) error {
+	claims, ok := app.ClaimsFromContext(svc.ctx)
+	if !ok {
+		return ErrPermissionDenied
+	}
+
+	err := svc.dao.Transaction(func(tx *dao.Dao) error {
+		if param.RefreshToken != "" {
+			token, err := tx.GetRefreshToken(util.EncodeSHA256(param.RefreshToken))
+			if err != nil {
+				return err
+			}
+			if token != nil && token.UserID == claims.UserID {
+				if err := tx.RevokeRefreshTokenFamily(token.FamilyID, uint32(time.Now().Unix())); err != nil {
+					return err
+				}
+			}
+		}
+
+		return tx.CreateRevokedToken(claims.Id, uint32(claims.ExpiresAt), claims.Username)
+	})
+	if err != nil {
+		return err
+	}
+	app.GetRevocationCache().Set(claims.Id, true, time.Unix(claims.ExpiresAt, 0))
+
+	return nil
+}
+
+func issueTokens(d *dao.Dao, user *model.User, familyID string) (*TokenPair, error) {
+	token, err := app.GenerateToken(user.ID, user.Username, user.Role)
+	if err != nil {
+		return nil, err
+	}
+
+	refreshToken, err := util.RandomString(32)
+	if err != nil {
+		return nil, err
+	}
+	if familyID == "" {
+		if familyID, err = util.RandomString(16); err != nil {
+			return nil, err
+		}
+	}
+	expiresOn := time.Now().Add(global.JWTSetting.RefreshExpire).Unix()
+	err = d.CreateRefreshToken(user.ID, familyID, util.EncodeSHA256(refreshToken), uint32(expiresOn))
+	if err != nil {
+		return nil, err
+	}
+
+	return &TokenPair{
+		Token:        token,
+		RefreshToken: refreshToken,
+		ExpiresIn:    int64(global.JWTSetting.Expire / time.Second),
+	}, nil
+}
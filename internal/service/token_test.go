@@ -0,0 +1,160 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/go-programming-tour/blog-service/global"
+	"github.com/go-programming-tour/blog-service/internal/model"
+	"github.com/go-programming-tour/blog-service/pkg/app"
+	"github.com/go-programming-tour/blog-service/pkg/setting"
+	"github.com/go-programming-tour/blog-service/pkg/util"
+	"github.com/jinzhu/gorm"
+
+	_ "github.com/jinzhu/gorm/dialects/sqlite"
+)
+
+// 使用内存中的 SQLite 作为测试数据库，只覆盖不依赖 MySQL 特有语法的路径
+func newTestService(t *testing.T, ctx context.Context, models ...interface{}) Service {
+	db, err := gorm.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatalf("gorm.Open err: %v", err)
+	}
+	// 每个连接都是独立的内存数据库，只能使用一个连接
+	db.DB().SetMaxOpenConns(1)
+	if err := db.AutoMigrate(models...).Error; err != nil {
+		t.Fatalf("AutoMigrate err: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	global.DBEngine = db
+	global.JWTSetting = &setting.JWTSettingS{Secret: "secret", Expire: time.Hour, RefreshExpire: 24 * time.Hour}
+	if err := app.SetupJWTKeys(global.JWTSetting); err != nil {
+		t.Fatalf("app.SetupJWTKeys err: %v", err)
+	}
+
+	return New(ctx)
+}
+
+func newTokenTestService(t *testing.T) (Service, *model.User) {
+	svc := newTestService(t, context.Background(), &model.User{}, &model.RefreshToken{}, &model.RevokedToken{})
+	user, err := svc.dao.CreateUser("alice", "hash", "alice", model.UserRoleAuthor)
+	if err != nil {
+		t.Fatalf("CreateUser err: %v", err)
+	}
+
+	return svc, user
+}
+
+func getRefreshToken(t *testing.T, svc Service, token string) *model.RefreshToken {
+	refreshToken, err := svc.dao.GetRefreshToken(util.EncodeSHA256(token))
+	if err != nil || refreshToken == nil {
+		t.Fatalf("GetRefreshToken = %v, %v", refreshToken, err)
+	}
+
+	return refreshToken
+}
+
+// 轮换签发同一 family 的新令牌，旧令牌被标记为已使用
+func TestRefreshTokenRotates(t *testing.T) {
+	svc, user := newTokenTestService(t)
+	first, err := svc.IssueTokens(user, "")
+	if err != nil {
+		t.Fatalf("IssueTokens err: %v", err)
+	}
+
+	second, err := svc.RefreshToken(&RefreshTokenRequest{RefreshToken: first.RefreshToken})
+	if err != nil {
+		t.Fatalf("RefreshToken err: %v", err)
+	}
+	if second.RefreshToken == first.RefreshToken {
+		t.Fatal("RefreshToken returned the same refresh token")
+	}
+	old, cur := getRefreshToken(t, svc, first.RefreshToken), getRefreshToken(t, svc, second.RefreshToken)
+	if old.UsedOn == 0 {
+		t.Error("rotated token is not marked as used")
+	}
+	if cur.FamilyID != old.FamilyID || cur.UsedOn != 0 || cur.RevokedOn != 0 {
+		t.Errorf("new token = %+v, want an unused token in family %q", cur, old.FamilyID)
+	}
+	if _, err := app.ParseToken(second.Token); err != nil {
+		t.Errorf("ParseToken err: %v", err)
+	}
+}
+
+// 复用已轮换的令牌时吊销整个 family，其他 family 不受影响
+func TestRefreshTokenReuseRevokesFamily(t *testing.T) {
+	svc, user := newTokenTestService(t)
+	first, err := svc.IssueTokens(user, "")
+	if err != nil {
+		t.Fatalf("IssueTokens err: %v", err)
+	}
+	other, err := svc.IssueTokens(user, "")
+	if err != nil {
+		t.Fatalf("IssueTokens err: %v", err)
+	}
+	second, err := svc.RefreshToken(&RefreshTokenRequest{RefreshToken: first.RefreshToken})
+	if err != nil {
+		t.Fatalf("RefreshToken err: %v", err)
+	}
+
+	if _, err := svc.RefreshToken(&RefreshTokenRequest{RefreshToken: first.RefreshToken}); err != ErrRefreshTokenReused {
+		t.Fatalf("reusing a rotated token: err = %v, want %v", err, ErrRefreshTokenReused)
+	}
+	for _, token := range []string{first.RefreshToken, second.RefreshToken} {
+		if getRefreshToken(t, svc, token).RevokedOn == 0 {
+			t.Errorf("token %q in the reused family is not revoked", token)
+		}
+	}
+	if _, err := svc.RefreshToken(&RefreshTokenRequest{RefreshToken: second.RefreshToken}); err != ErrRefreshTokenInvalid {
+		t.Errorf("latest token after reuse: err = %v, want %v", err, ErrRefreshTokenInvalid)
+	}
+	if _, err := svc.RefreshToken(&RefreshTokenRequest{RefreshToken: other.RefreshToken}); err != nil {
+		t.Errorf("token in another family: err = %v, want nil", err)
+	}
+}
+
+// 未知或过期的令牌无效
+func TestRefreshTokenInvalid(t *testing.T) {
+	svc, user := newTokenTestService(t)
+	if _, err := svc.RefreshToken(&RefreshTokenRequest{RefreshToken: "unknown"}); err != ErrRefreshTokenInvalid {
+		t.Errorf("unknown token: err = %v, want %v", err, ErrRefreshTokenInvalid)
+	}
+
+	global.JWTSetting.RefreshExpire = -time.Second
+	expired, err := svc.IssueTokens(user, "")
+	if err != nil {
+		t.Fatalf("IssueTokens err: %v", err)
+	}
+	if _, err := svc.RefreshToken(&RefreshTokenRequest{RefreshToken: expired.RefreshToken}); err != ErrRefreshTokenInvalid {
+		t.Errorf("expired token: err = %v, want %v", err, ErrRefreshTokenInvalid)
+	}
+}
+
+// 退出时吊销访问令牌的 jti 和刷新令牌所在的 family
+func TestLogoutRevokesTokens(t *testing.T) {
+	svc, user := newTokenTestService(t)
+	pair, err := svc.IssueTokens(user, "")
+	if err != nil {
+		t.Fatalf("IssueTokens err: %v", err)
+	}
+	claims, err := app.ParseToken(pair.Token)
+	if err != nil {
+		t.Fatalf("ParseToken err: %v", err)
+	}
+
+	svc.ctx = app.WithClaims(svc.ctx, claims)
+	if err := svc.Logout(&LogoutRequest{RefreshToken: pair.RefreshToken}); err != nil {
+		t.Fatalf("Logout err: %v", err)
+	}
+	if revoked, err := svc.dao.IsTokenRevoked(claims.Id); err != nil || !revoked {
+		t.Errorf("IsTokenRevoked = %v, %v, want true", revoked, err)
+	}
+	if revoked, ok := app.GetRevocationCache().Get(claims.Id); !ok || !revoked {
+		t.Errorf("cached revocation = %v, %v, want true, true", revoked, ok)
+	}
+	if _, err := svc.RefreshToken(&RefreshTokenRequest{RefreshToken: pair.RefreshToken}); err != ErrRefreshTokenInvalid {
+		t.Errorf("refresh after logout: err = %v, want %v", err, ErrRefreshTokenInvalid)
+	}
+}
@@ -1,44 +1,390 @@
 package service
 
 import (
+	"bufio"
 	"errors"
-	"mime/multipart"
-	"os"
+	"io"
+	"time"
 
 	"github.com/go-programming-tour/blog-service/global"
+	"github.com/go-programming-tour/blog-service/internal/dao"
+	"github.com/go-programming-tour/blog-service/internal/model"
+	"github.com/go-programming-tour/blog-service/pkg/app"
 	"github.com/go-programming-tour/blog-service/pkg/upload"
 )
 
 type FileInfo struct {
-	Name      string
-	AccessUrl string
+	ID           uint32
+	Name         string
+	OriginalName string
+	MimeType     string
+	AccessUrl    string
+	Size         int64
+	Hash         string            // 文件内容的 SHA-256
+	Private      bool              // 私有文件的访问地址为带有效期的签名地址
+	Derivatives  map[string]string // 图片衍生图的访问地址，键为衍生图名称
 }
 
-func (svc *Service) UploadFile(fileType upload.FileType, file multipart.File, fileHeader *multipart.FileHeader) (*FileInfo, error) {
-	fileName := upload.GetFileName(fileHeader.Filename)
-	if !upload.CheckContainExt(fileType, fileName) {
-		return nil, errors.New("file suffix is not supported.")
+type FileURLRequest struct {
+	ID       uint32 `form:"id" binding:"required,gte=1"`
+	BindUser bool   `form:"bind_user"`                                    // 签名地址只允许当前用户访问，访问时在 header 中携带 token
+	Expire   uint32 `form:"expire" binding:"omitempty,gte=60,lte=604800"` // 有效期，单位秒，默认使用配置的有效期
+}
+
+// 文件的访问地址，公开文件的地址不会过期
+type FileURL struct {
+	ID          uint32            `json:"id"`
+	AccessUrl   string            `json:"access_url"`
+	Derivatives map[string]string `json:"derivatives"`
+	Private     bool              `json:"private"`
+	ExpiresOn   uint32            `json:"expires_on"` // 签名地址的过期时间，公开文件为 0
+}
+
+var ErrFileNotFound = errors.New("file not found")
+
+// 复用的存储内容在创建记录前已被回收
+var errStoredFileCollected = errors.New("stored file has been collected")
+
+// 一次上传的文件信息
+type uploadMeta struct {
+	fileType    upload.FileType
+	name        string
+	contentType string
+	private     bool // 私有文件只能通过签名地址访问
+	// 合并分片时对应的上传会话，在创建或复用文件记录的同一个事务中完成会话并释放预留的用量
+	session *model.UploadSession
+}
+
+// 本次上传在用量中已经预留的大小，检查配额时不重复计算
+func (m *uploadMeta) reservedSize() int64 {
+	if m.session == nil {
+		return 0
+	}
+
+	return m.session.ReservedSize
+}
+
+// 将上传的文件流式写入存储，当前用户上传过相同内容时返回已有的文件记录
+// 其他用户上传过相同内容时只创建当前用户的记录，存储中的内容不重复保存
+// 公开文件和私有文件分别去重，相同内容可以同时存在两份
+func (svc *Service) UploadFile(fileType upload.FileType, name string, private bool, r io.Reader) (*FileInfo, error) {
+	return svc.uploadFile(&uploadMeta{fileType: fileType, name: name, private: private}, r)
+}
+
+func (svc *Service) uploadFile(meta *uploadMeta, r io.Reader) (*FileInfo, error) {
+	if err := checkUploadFile(meta.fileType, meta.name); err != nil {
+		return nil, err
+	}
+	// 配额已经用完时不再接收文件内容
+	if err := svc.checkQuota(0); err != nil {
+		return nil, err
+	}
+
+	// 根据文件开头的魔数判断真实类型，不信任客户端传入的 Content-Type
+	br := bufio.NewReaderSize(r, 512)
+	head, err := br.Peek(512)
+	if err != nil && err != io.EOF && err != bufio.ErrBufferFull {
+		return nil, err
+	}
+	contentType := upload.DetectContentType(meta.name, head)
+	if !upload.CheckContentType(meta.name, contentType) {
+		return nil, upload.ErrFileTypeMismatch
+	}
+	meta.contentType = contentType
+
+	// 先写入临时文件，读取完成后才能得到内容哈希
+	storage := upload.GetStorage()
+	tmpName, err := upload.GetTempFileName()
+	if err != nil {
+		return nil, err
+	}
+	var body io.Reader = upload.NewSizeLimitReader(br, upload.GetMaxSize(meta.fileType))
+	if contentType == "text/plain" {
+		body = upload.NewTextReader(body)
+	}
+	hr := upload.NewHashReader(body)
+	if err := storage.Put(svc.ctx, tmpName, hr, -1, contentType); err != nil {
+		return nil, err
+	}
+
+	if meta.fileType == upload.TypeImage {
+		return svc.saveImage(meta, tmpName)
+	}
+
+	return svc.saveFile(meta, tmpName, hr.Sum(), hr.Size())
+}
+
+// 将临时文件移动到按哈希命名的正式位置
+func (svc *Service) saveFile(meta *uploadMeta, tmpName, hash string, size int64) (*FileInfo, error) {
+	file, stored, err := svc.findFile(meta, hash)
+	if err != nil {
+		svc.removeTempFile(tmpName)
+		return nil, err
+	}
+	if file != nil {
+		svc.removeTempFile(tmpName)
+		return svc.reuseFile(meta, file)
+	}
+	if err := svc.checkQuota(size - meta.reservedSize()); err != nil {
+		svc.removeTempFile(tmpName)
+		return nil, err
+	}
+	if stored != nil {
+		fileInfo, err := svc.createFile(meta, hash, stored.Path, size, false)
+		if err != errStoredFileCollected {
+			svc.removeTempFile(tmpName)
+			return fileInfo, err
+		}
+		// 存储中的内容已被回收，改为保存本次上传的内容
+	}
+
+	fileName := upload.GetFileName(meta.fileType, hash, upload.GetFileExt(meta.name), meta.private)
+	if err := upload.GetStorage().Move(svc.ctx, tmpName, fileName); err != nil {
+		svc.removeTempFile(tmpName)
+		return nil, err
 	}
-	if upload.CheckMaxSize(fileType, file) {
-		return nil, errors.New("exceeded maximum file limit.")
+
+	return svc.createFile(meta, hash, fileName, size, true)
+}
+
+// 图片去除元数据后按处理后的内容计算哈希，并生成衍生图
+func (svc *Service) saveImage(meta *uploadMeta, tmpName string) (*FileInfo, error) {
+	storage := upload.GetStorage()
+	img, err := upload.PrepareImage(svc.ctx, storage, tmpName, meta.contentType)
+	svc.removeTempFile(tmpName)
+	if err != nil {
+		return nil, err
 	}
+	// 移动到正式位置后删除临时文件不会出错
+	defer svc.removeTempFile(img.Name)
 
-	uploadSavePath := upload.GetSavePath()
-	if upload.CheckSavePath(uploadSavePath) {
-		if err := upload.CreateSavePath(uploadSavePath, os.ModePerm); err != nil {
-			return nil, errors.New("failed to create save directory.")
+	file, stored, err := svc.findFile(meta, img.Hash)
+	if err != nil {
+		return nil, err
+	}
+	if stored != nil {
+		// 补齐配置变更后新增的衍生图
+		if err := upload.GenerateDerivatives(svc.ctx, storage, stored.Path, img); err != nil {
+			return nil, err
 		}
 	}
-	if upload.CheckPermission(uploadSavePath) {
-		return nil, errors.New("insufficient file permissions.")
+	if file != nil {
+		return svc.reuseFile(meta, file)
 	}
 
-	dst := uploadSavePath + "/" + fileName
-	if err := upload.SaveFile(fileHeader, dst); err != nil {
+	if err := svc.checkQuota(img.Size - meta.reservedSize()); err != nil {
+		return nil, err
+	}
+	if stored != nil {
+		fileInfo, err := svc.createFile(meta, img.Hash, stored.Path, img.Size, false)
+		if err != errStoredFileCollected {
+			return fileInfo, err
+		}
+		// 存储中的内容已被回收，改为保存本次处理后的内容
+	}
+	fileName := upload.GetFileName(upload.TypeImage, img.Hash, upload.GetFileExt(meta.name), meta.private)
+	if err := upload.GenerateDerivatives(svc.ctx, storage, fileName, img); err != nil {
 		return nil, err
 	}
+	if err := storage.Move(svc.ctx, img.Name, fileName); err != nil {
+		return nil, err
+	}
+
+	return svc.createFile(meta, img.Hash, fileName, img.Size, true)
+}
 
-	accessUrl := global.AppSetting.UploadServerUrl + "/" + fileName
+// 返回当前用户自己的相同内容的文件，以及存储中已有的相同内容的文件，不存在时分别为 nil
+func (svc *Service) findFile(meta *uploadMeta, hash string) (file, stored *model.File, err error) {
+	file, err = svc.dao.GetFileByHash(hash, meta.private, svc.operatorID())
+	if err != nil || file != nil {
+		return file, file, err
+	}
+	stored, err = svc.dao.GetStoredFileByHash(hash, meta.private)
+
+	return nil, stored, err
+}
+
+// 创建当前用户的文件记录，并在同一个事务中按配额增加用量，衍生图的大小一起计入
+// 合并分片时先在同一个事务中释放会话预留的用量，避免同一份数据被计算两次
+// created 为 true 表示存储中的内容由本次上传保存，超过配额时一并删除
+// created 为 false 时在锁内确认存储内容仍被其他记录使用，已被回收时返回 errStoredFileCollected
+func (svc *Service) createFile(meta *uploadMeta, hash, fileName string, size int64, created bool) (*FileInfo, error) {
+	derivativeSize, err := upload.GetDerivativesSize(svc.ctx, upload.GetStorage(), fileName, meta.contentType)
+	if err != nil && !created {
+		// 复用的内容可能正在被回收，改为保存本次上传的内容
+		return nil, errStoredFileCollected
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var file *model.File
+	err = svc.dao.Transaction(func(tx *dao.Dao) error {
+		if !created {
+			// 与回收任务锁定相同的记录，回收已提交时记录已被删除
+			files, err := tx.LockFilesByPath(fileName)
+			if err != nil {
+				return err
+			}
+			if len(files) == 0 {
+				return errStoredFileCollected
+			}
+		}
+
+		var err error
+		file, err = tx.CreateFile(&dao.File{
+			Hash:           hash,
+			Path:           fileName,
+			OriginalName:   meta.name,
+			MimeType:       meta.contentType,
+			Size:           size,
+			DerivativeSize: derivativeSize,
+			Private:        meta.private,
+			UploaderID:     svc.operatorID(),
+			CreatedBy:      svc.operator(),
+		})
+		if err != nil {
+			return err
+		}
+		if meta.session != nil {
+			if err := finishUploadSession(tx, meta.session, file.ID); err != nil {
+				return err
+			}
+		}
+
+		return svc.chargeUsage(tx, dao.UsageDelta{Size: size + derivativeSize, Files: 1})
+	})
+	if model.IsDuplicateKeyError(err) {
+		// 同一个用户并发上传相同内容时，唯一索引保证只有一条记录，此时复用已有的记录
+		existing, err := svc.dao.GetFileByHash(hash, meta.private, svc.operatorID())
+		if err != nil {
+			return nil, err
+		}
+		if existing != nil {
+			return svc.reuseFile(meta, existing)
+		}
+	}
+	if err == upload.ErrQuotaExceeded && created {
+		svc.removeUnusedObject(fileName, meta.contentType)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return newFileInfo(file), nil
+}
+
+// 删除没有任何文件记录使用的存储内容及其衍生图，失败时只记录日志
+func (svc *Service) removeUnusedObject(name, contentType string) {
+	count, err := svc.dao.CountOtherFilesByPath(0, name)
+	if err != nil {
+		global.Logger.ErrorfT("svc.dao.CountOtherFilesByPath err: %v", err)
+		return
+	}
+	if count > 0 {
+		return
+	}
+	for _, name := range append([]string{name}, upload.GetDerivativeNames(name, contentType)...) {
+		svc.removeTempFile(name)
+	}
+}
+
+// 检查文件类型和后缀是否允许上传
+func checkUploadFile(fileType upload.FileType, name string) error {
+	if !upload.CheckFileType(fileType) {
+		return upload.ErrFileTypeUnsupported
+	}
+	if !upload.CheckContainExt(fileType, name) {
+		return errors.New("file suffix is not supported.")
+	}
+
+	return nil
+}
+
+// 复用当前用户自己的记录，返回的文件名为本次上传的文件名
+// 再次上传的文件即将被引用，重新开始计算回收的宽限期
+func (svc *Service) reuseFile(meta *uploadMeta, file *model.File) (*FileInfo, error) {
+	if meta.session != nil {
+		err := svc.dao.Transaction(func(tx *dao.Dao) error {
+			return finishUploadSession(tx, meta.session, file.ID)
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	if file.OrphanedOn != 0 {
+		if err := svc.dao.UpdateFileOrphanedOn(file.ID, 0); err != nil {
+			return nil, err
+		}
+		file.OrphanedOn = 0
+	}
+
+	fileInfo := newFileInfo(file)
+	fileInfo.OriginalName = meta.name
+	return fileInfo, nil
+}
+
+func (svc *Service) removeTempFile(name string) {
+	if err := upload.GetStorage().Delete(svc.ctx, name); err != nil {
+		global.Logger.ErrorfT("storage.Delete %s err: %v", name, err)
+	}
+}
+
+// 私有文件返回不绑定用户的签名地址
+func newFileInfo(file *model.File) *FileInfo {
+	private := file.Private == 1
+	return &FileInfo{
+		ID:           file.ID,
+		Name:         file.Path,
+		OriginalName: file.OriginalName,
+		MimeType:     file.MimeType,
+		AccessUrl:    upload.GetFileURL(file.Path, private, 0, 0),
+		Size:         file.Size,
+		Hash:         file.Hash,
+		Private:      private,
+		Derivatives:  upload.GetDerivativeURLs(file.Path, file.MimeType, private, 0, 0),
+	}
+}
+
+// 返回文件的访问地址，私有文件只有上传者、管理员和编辑可以获取签名地址
+func (svc *Service) GetFileURL(param *FileURLRequest) (*FileURL, error) {
+	file, err := svc.dao.GetFile(param.ID)
+	if err != nil {
+		return nil, err
+	}
+	if file == nil {
+		return nil, ErrFileNotFound
+	}
+	if file.Private != 1 {
+		return &FileURL{
+			ID:          file.ID,
+			AccessUrl:   upload.GetFileURL(file.Path, false, 0, 0),
+			Derivatives: upload.GetDerivativeURLs(file.Path, file.MimeType, false, 0, 0),
+		}, nil
+	}
+
+	claims, ok := app.ClaimsFromContext(svc.ctx)
+	if !ok {
+		return nil, ErrPermissionDenied
+	}
+	if file.UploaderID != claims.UserID && claims.Role != model.UserRoleAdmin && claims.Role != model.UserRoleEditor {
+		return nil, ErrPermissionDenied
+	}
+
+	expire := time.Duration(param.Expire) * time.Second
+	if expire == 0 {
+		expire = global.AppSetting.UploadSignedUrlExpire
+	}
+	var userID uint32
+	if param.BindUser {
+		userID = claims.UserID
+	}
 
-	return &FileInfo{Name: fileName, AccessUrl: accessUrl}, nil
+	return &FileURL{
+		ID:          file.ID,
+		AccessUrl:   upload.GetFileURL(file.Path, true, userID, expire),
+		Derivatives: upload.GetDerivativeURLs(file.Path, file.MimeType, true, userID, expire),
+		Private:     true,
+		ExpiresOn:   uint32(time.Now().Add(expire).Unix()),
+	}, nil
 }
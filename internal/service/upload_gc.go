@@ -0,0 +1,199 @@
+package service
+
+import (
+	"time"
+
+	"github.com/go-programming-tour/blog-service/global"
+	"github.com/go-programming-tour/blog-service/internal/dao"
+	"github.com/go-programming-tour/blog-service/internal/model"
+	"github.com/go-programming-tour/blog-service/pkg/upload"
+)
+
+// 回收未引用文件时每批读取的记录数
+const fileGCBatch = 500
+
+type FileGCRequest struct {
+	DryRun bool `form:"dry_run"`
+}
+
+// 未被文章引用的文件
+type OrphanedFile struct {
+	ID         uint32 `json:"id"`
+	Path       string `json:"path"`
+	Size       int64  `json:"size"`
+	OrphanedOn uint32 `json:"orphaned_on"`
+}
+
+// 一次回收的结果
+type FileGCReport struct {
+	DryRun    bool            `json:"dry_run"`
+	Scanned   int             `json:"scanned"`    // 检查的文件数量
+	Orphaned  []*OrphanedFile `json:"orphaned"`   // 未被引用但仍在宽限期内的文件
+	Deleted   []*OrphanedFile `json:"deleted"`    // 已删除的文件，dry-run 时为将会删除的文件
+	FreedSize int64           `json:"freed_size"` // 删除的文件总大小
+}
+
+// 回收不再被任何文章封面或内容引用的上传文件
+// 每次回收重新统计文件的引用计数，引用计数为 0 的文件第一次被发现时只记录时间，超过宽限期仍未被引用才删除
+// dryRun 为 true 时只生成报告，svc 的 ctx 结束时在当前批次之后停止并返回 ctx 的错误
+func (svc *Service) CollectOrphanedFiles(dryRun bool) (*FileGCReport, error) {
+	refCounts, err := svc.countFileReferences()
+	if err != nil {
+		return nil, err
+	}
+
+	report := &FileGCReport{DryRun: dryRun, Orphaned: []*OrphanedFile{}, Deleted: []*OrphanedFile{}}
+	now := uint32(time.Now().Unix())
+	grace := uint32(global.AppSetting.UploadGCGracePeriod / time.Second)
+	var afterID uint32
+	for {
+		if err := svc.ctx.Err(); err != nil {
+			return nil, err
+		}
+		files, err := svc.dao.GetFileListAfterID(afterID, fileGCBatch)
+		if err != nil {
+			return nil, err
+		}
+		for _, file := range files {
+			afterID = file.ID
+			report.Scanned++
+
+			refCount := refCounts[file.Hash]
+			orphanedOn := file.OrphanedOn
+			if refCount > 0 {
+				orphanedOn = 0
+			} else if orphanedOn == 0 {
+				orphanedOn = now
+			}
+			if !dryRun && (refCount != file.RefCount || orphanedOn != file.OrphanedOn) {
+				if err := svc.dao.UpdateFileRefCount(file.ID, refCount, orphanedOn); err != nil {
+					return nil, err
+				}
+			}
+			if refCount > 0 {
+				continue
+			}
+
+			orphaned := &OrphanedFile{ID: file.ID, Path: file.Path, Size: file.Size, OrphanedOn: orphanedOn}
+			if now-orphaned.OrphanedOn < grace {
+				report.Orphaned = append(report.Orphaned, orphaned)
+				continue
+			}
+			if !dryRun && !svc.deleteFile(file) {
+				continue
+			}
+			report.Deleted = append(report.Deleted, orphaned)
+			report.FreedSize += file.Size
+		}
+		if len(files) < fileGCBatch {
+			break
+		}
+	}
+
+	return report, nil
+}
+
+// 统计每个文件哈希被多少篇未删除文章及其历史版本的封面或内容引用
+// 历史版本可能被恢复，其中引用的文件同样需要保留
+func (svc *Service) countFileReferences() (map[string]uint32, error) {
+	refCounts := make(map[string]uint32)
+	var afterID uint32
+	for {
+		articles, err := svc.dao.GetArticleListForScan(afterID, fileGCBatch)
+		if err != nil {
+			return nil, err
+		}
+		for _, article := range articles {
+			afterID = article.ID
+			countReferences(refCounts, article.CoverImageUrl, article.Content)
+		}
+		if len(articles) < fileGCBatch {
+			break
+		}
+	}
+
+	afterID = 0
+	for {
+		revisions, err := svc.dao.GetArticleRevisionListForScan(afterID, fileGCBatch)
+		if err != nil {
+			return nil, err
+		}
+		for _, revision := range revisions {
+			afterID = revision.ID
+			countReferences(refCounts, revision.CoverImageUrl, revision.Content)
+		}
+		if len(revisions) < fileGCBatch {
+			return refCounts, nil
+		}
+	}
+}
+
+// 同一篇文章或历史版本多次引用同一个文件时只计一次
+func countReferences(refCounts map[string]uint32, coverImageUrl, content string) {
+	seen := make(map[string]bool)
+	for _, hash := range upload.ExtractFileHashes(coverImageUrl + "\n" + content) {
+		if !seen[hash] {
+			seen[hash] = true
+			refCounts[hash]++
+		}
+	}
+}
+
+// 软删除文件记录，没有其他上传者的记录共用时一并删除存储中的文件及其衍生图
+// 在锁定共用存储内容的全部记录的事务中执行，上传相同内容时在同一把锁下确认存储内容仍在使用
+// 存储删除失败时回滚，保留记录等待下次回收；重新上传使文件不再是孤儿时跳过
+func (svc *Service) deleteFile(file *model.File) bool {
+	var deleted bool
+	err := svc.dao.Transaction(func(tx *dao.Dao) error {
+		files, err := tx.LockFilesByPath(file.Path)
+		if err != nil {
+			return err
+		}
+		var locked *model.File
+		for _, f := range files {
+			if f.ID == file.ID {
+				locked = f
+			}
+		}
+		if locked == nil || locked.OrphanedOn == 0 {
+			return nil
+		}
+		if len(files) == 1 {
+			if err := svc.deleteStoredFile(locked); err != nil {
+				return err
+			}
+		}
+		deleted = true
+
+		return deleteFileRecord(tx, locked)
+	})
+	if err != nil {
+		global.Logger.ErrorfT("svc.deleteFile err: %v", err)
+		return false
+	}
+
+	return deleted
+}
+
+// 删除存储中的文件，先删除衍生图，失败时原文件仍在，复用时会补齐衍生图
+func (svc *Service) deleteStoredFile(file *model.File) error {
+	storage := upload.GetStorage()
+	names := append(upload.GetDerivativeNames(file.Path, file.MimeType), file.Path)
+	for _, name := range names {
+		if err := storage.Delete(svc.ctx, name); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// 在事务中删除文件记录并释放上传者和整个服务的用量，多个实例同时回收时只释放一次用量
+func deleteFileRecord(tx *dao.Dao, file *model.File) error {
+	deleted, err := tx.DeleteFile(file.ID)
+	if err != nil || !deleted {
+		return err
+	}
+
+	return releaseUsage(tx, file.UploaderID, dao.UsageDelta{Size: file.Size + file.DerivativeSize, Files: 1})
+}
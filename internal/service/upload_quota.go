@@ -0,0 +1,103 @@
+package service
+
+import (
+	"strconv"
+
+	"github.com/go-programming-tour/blog-service/internal/dao"
+	"github.com/go-programming-tour/blog-service/internal/model"
+	"github.com/go-programming-tour/blog-service/pkg/app"
+	"github.com/go-programming-tour/blog-service/pkg/upload"
+)
+
+// 当前用户的存储用量和配额，配额为 0 时表示不限制
+type StorageUsage struct {
+	Size       int64 `json:"size"`        // 已保存文件及其衍生图的总大小，单位字节
+	Files      int   `json:"files"`       // 已保存文件的数量
+	StagedSize int64 `json:"staged_size"` // 未完成的分片上传预留的大小，单位字节
+	MaxSize    int64 `json:"max_size"`    // 总大小的配额，单位字节
+	MaxFiles   int   `json:"max_files"`   // 文件数量的配额
+}
+
+// 用量计入的归属方及其配额
+type usageOwner struct {
+	key   string
+	quota upload.Quota
+}
+
+// 返回当前用户的存储用量，相同内容计入每个上传者
+func (svc *Service) GetStorageUsage() (*StorageUsage, error) {
+	usage, err := svc.dao.GetUploadUsage(userUsageOwner(svc.operatorID()))
+	if err != nil {
+		return nil, err
+	}
+
+	quota := svc.userQuota()
+	result := &StorageUsage{MaxSize: quota.MaxSize, MaxFiles: quota.MaxFiles}
+	if usage != nil {
+		result.Size, result.Files, result.StagedSize = usage.Size, usage.Files, usage.StagedSize
+	}
+
+	return result, nil
+}
+
+// 检查当前用户和整个服务是否还能保存一个 size 字节的新文件，size 为 0 时只检查配额是否已经用完
+// 只用于在接收文件内容之前提前拒绝，实际扣减在创建记录的事务中由 chargeUsage 按配额条件完成
+func (svc *Service) checkQuota(size int64) error {
+	for _, owner := range svc.usageOwners() {
+		usage, err := svc.dao.GetUploadUsage(owner.key)
+		if err != nil {
+			return err
+		}
+		if usage != nil && !owner.quota.Allow(usage.Size+usage.StagedSize, usage.Files, size) {
+			return upload.ErrQuotaExceeded
+		}
+	}
+
+	return nil
+}
+
+// 在事务中为当前用户和整个服务增加用量，任一方超过配额时返回 ErrQuotaExceeded，由事务回滚已增加的部分
+func (svc *Service) chargeUsage(tx *dao.Dao, delta dao.UsageDelta) error {
+	for _, owner := range svc.usageOwners() {
+		added, err := tx.AddUploadUsage(owner.key, delta, owner.quota.MaxSize, owner.quota.MaxFiles)
+		if err != nil {
+			return err
+		}
+		if !added {
+			return upload.ErrQuotaExceeded
+		}
+	}
+
+	return nil
+}
+
+// 在事务中释放上传者和整个服务的用量，delta 为释放的量
+func releaseUsage(tx *dao.Dao, uploaderID uint32, delta dao.UsageDelta) error {
+	delta = dao.UsageDelta{Size: -delta.Size, Files: -delta.Files, StagedSize: -delta.StagedSize}
+	for _, owner := range []string{userUsageOwner(uploaderID), model.UsageOwnerTotal} {
+		if _, err := tx.AddUploadUsage(owner, delta, 0, 0); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (svc *Service) usageOwners() []usageOwner {
+	return []usageOwner{
+		{key: userUsageOwner(svc.operatorID()), quota: svc.userQuota()},
+		{key: model.UsageOwnerTotal, quota: upload.GetTotalQuota()},
+	}
+}
+
+func (svc *Service) userQuota() upload.Quota {
+	if claims, ok := app.ClaimsFromContext(svc.ctx); ok {
+		return upload.GetUserQuota(claims.Role)
+	}
+
+	return upload.GetUserQuota("")
+}
+
+func userUsageOwner(userID uint32) string {
+	return model.UsageOwnerUser + strconv.FormatUint(uint64(userID), 10)
+}
@@ -0,0 +1,502 @@
+package service
+
+import (
+	"errors"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/go-programming-tour/blog-service/global"
+	"github.com/go-programming-tour/blog-service/internal/dao"
+	"github.com/go-programming-tour/blog-service/internal/model"
+	"github.com/go-programming-tour/blog-service/pkg/upload"
+	"github.com/go-programming-tour/blog-service/pkg/util"
+)
+
+var (
+	ErrUploadSessionNotFound = errors.New("upload session does not exist or has expired")
+	ErrUploadChunkInvalid    = errors.New("upload chunk is invalid")
+	ErrUploadOffsetMismatch  = errors.New("upload offset does not match the received data")
+	ErrUploadIncomplete      = errors.New("upload is incomplete")
+
+	// 并发合并同一个会话时，后提交的请求发现会话已经完成
+	errUploadSessionCompleted = errors.New("upload session has been completed")
+)
+
+// 每次清理的会话数量
+const uploadSessionCleanBatch = 100
+
+type CreateUploadSessionRequest struct {
+	Type      int    `form:"type" binding:"required,gte=1"`
+	FileName  string `form:"file_name" binding:"required,max=255"`
+	Size      int64  `form:"size" binding:"required,gte=1"`
+	ChunkSize int64  `form:"chunk_size" binding:"omitempty,gte=262144,lte=104857600"`
+	Hash      string `form:"hash" binding:"omitempty,len=64,hexadecimal"`
+	Private   bool   `form:"private"`
+}
+
+// 分片上传会话的状态
+type UploadSessionInfo struct {
+	UploadID  string     `json:"upload_id"`
+	FileName  string     `json:"file_name"`
+	Size      int64      `json:"size"`
+	ChunkSize int64      `json:"chunk_size"`
+	Offset    int64      `json:"offset"`   // 从文件开头起连续接收的字节数
+	Received  [][2]int64 `json:"received"` // 已接收的字节区间，左闭右开
+	Chunks    []int64    `json:"chunks"`   // 已接收的分片序号，tus 上传时为空
+	ExpiresOn uint32     `json:"expires_on"`
+	Completed bool       `json:"completed"`
+	FileID    uint32     `json:"file_id"` // 合并完成后生成的文件
+}
+
+// 创建按序号上传分片的会话
+func (svc *Service) CreateUploadSession(param *CreateUploadSessionRequest) (*UploadSessionInfo, error) {
+	chunkSize := param.ChunkSize
+	if chunkSize == 0 {
+		chunkSize = int64(global.AppSetting.UploadChunkSize) * 1024 * 1024
+	}
+	session, err := svc.createUploadSession(upload.FileType(param.Type), param.FileName, param.Size, chunkSize, param.Hash, param.Private)
+	if err != nil {
+		return nil, err
+	}
+
+	return newUploadSessionInfo(session, nil), nil
+}
+
+// 创建 tus 协议的上传会话，分片按偏移顺序追加
+func (svc *Service) CreateTusUploadSession(fileType upload.FileType, name string, size int64, hash string, private bool) (*UploadSessionInfo, error) {
+	session, err := svc.createUploadSession(fileType, name, size, 0, hash, private)
+	if err != nil {
+		return nil, err
+	}
+
+	return newUploadSessionInfo(session, nil), nil
+}
+
+func (svc *Service) GetUploadSession(uploadID string) (*UploadSessionInfo, error) {
+	session, err := svc.getUploadSession(uploadID)
+	if err != nil {
+		return nil, err
+	}
+	chunks, err := svc.dao.GetUploadChunkList(session.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	return newUploadSessionInfo(session, chunks), nil
+}
+
+// 上传指定序号的分片，重复上传同一序号时覆盖原有内容
+func (svc *Service) UploadChunk(uploadID string, index int64, r io.Reader) (*UploadSessionInfo, error) {
+	session, err := svc.getUploadSession(uploadID)
+	if err != nil {
+		return nil, err
+	}
+	if session.State != model.UploadSessionUploading || session.ChunkSize <= 0 || index < 0 {
+		return nil, ErrUploadChunkInvalid
+	}
+	start := index * session.ChunkSize
+	if start >= session.Size {
+		return nil, ErrUploadChunkInvalid
+	}
+	size := session.ChunkSize
+	if start+size > session.Size {
+		size = session.Size - start
+	}
+
+	chunk, err := svc.stageChunk(size, true, r)
+	if err != nil {
+		return nil, err
+	}
+	if err := svc.commitChunk(session, start, chunk, -1); err != nil {
+		return nil, err
+	}
+
+	return svc.GetUploadSession(uploadID)
+}
+
+// 按 tus 协议在 offset 处追加数据，返回新的偏移
+// 接收完全部数据时自动合并文件
+func (svc *Service) AppendUploadChunk(uploadID string, offset int64, r io.Reader) (int64, error) {
+	session, err := svc.getUploadSession(uploadID)
+	if err != nil {
+		return 0, err
+	}
+	if session.State != model.UploadSessionUploading {
+		return 0, ErrUploadOffsetMismatch
+	}
+	chunks, err := svc.dao.GetUploadChunkList(session.ID)
+	if err != nil {
+		return 0, err
+	}
+	if _, received := chunkRanges(chunks); offset != received {
+		return 0, ErrUploadOffsetMismatch
+	}
+
+	chunk, err := svc.stageChunk(session.Size-offset, false, r)
+	if err != nil {
+		return 0, err
+	}
+	if err := svc.commitChunk(session, offset, chunk, offset); err != nil {
+		return 0, err
+	}
+	offset += chunk.size
+	if offset == session.Size {
+		if _, err := svc.completeUploadSession(session); err != nil {
+			return 0, err
+		}
+	}
+
+	return offset, nil
+}
+
+// 校验并合并全部分片，生成的文件与普通上传一样经过类型检查和去重
+func (svc *Service) CompleteUploadSession(uploadID string) (*FileInfo, error) {
+	session, err := svc.getUploadSession(uploadID)
+	if err != nil {
+		return nil, err
+	}
+
+	return svc.completeUploadSession(session)
+}
+
+// 放弃上传，删除已接收的分片
+func (svc *Service) DeleteUploadSession(uploadID string) error {
+	session, err := svc.getUploadSession(uploadID)
+	if err != nil {
+		return err
+	}
+
+	return svc.removeUploadSession(session)
+}
+
+// 清理已过期的上传会话及其分片，返回清理的会话数量，svc 的 ctx 结束时停止清理
+func (svc *Service) CleanExpiredUploadSessions() (int, error) {
+	count := 0
+	for {
+		if err := svc.ctx.Err(); err != nil {
+			return count, err
+		}
+		sessions, err := svc.dao.GetExpiredUploadSessionList(uint32(time.Now().Unix()), uploadSessionCleanBatch)
+		if err != nil {
+			return count, err
+		}
+		for _, session := range sessions {
+			if err := svc.removeUploadSession(session); err != nil {
+				return count, err
+			}
+			count++
+		}
+		if len(sessions) < uploadSessionCleanBatch {
+			return count, nil
+		}
+	}
+}
+
+func (svc *Service) createUploadSession(fileType upload.FileType, name string, size, chunkSize int64, hash string, private bool) (*model.UploadSession, error) {
+	if err := checkUploadFile(fileType, name); err != nil {
+		return nil, err
+	}
+	if size > upload.GetMaxSize(fileType) {
+		return nil, upload.ErrFileTooLarge
+	}
+	if err := svc.checkQuota(size); err != nil {
+		return nil, err
+	}
+
+	uploadID, err := util.RandomString(16)
+	if err != nil {
+		return nil, err
+	}
+
+	// 分片在合并前同样占用存储，创建会话时按文件大小预留用量
+	var session *model.UploadSession
+	err = svc.dao.Transaction(func(tx *dao.Dao) error {
+		var err error
+		session, err = tx.CreateUploadSession(&dao.UploadSession{
+			UploadID:     uploadID,
+			FileType:     int(fileType),
+			FileName:     name,
+			Size:         size,
+			ChunkSize:    chunkSize,
+			Hash:         strings.ToLower(hash),
+			UploaderID:   svc.operatorID(),
+			ExpiresOn:    uint32(time.Now().Add(global.AppSetting.UploadSessionExpire).Unix()),
+			Private:      private,
+			ReservedSize: size,
+			CreatedBy:    svc.operator(),
+		})
+		if err != nil {
+			return err
+		}
+
+		return svc.chargeUsage(tx, dao.UsageDelta{StagedSize: size})
+	})
+
+	return session, err
+}
+
+// 释放会话预留的用量，重复调用时只释放一次
+func (svc *Service) releaseUploadSession(session *model.UploadSession) error {
+	return svc.dao.Transaction(func(tx *dao.Dao) error {
+		return releaseUploadReservation(tx, session)
+	})
+}
+
+// 在事务中释放会话预留的用量，预留已被释放时不做任何事
+func releaseUploadReservation(tx *dao.Dao, session *model.UploadSession) error {
+	cleared, err := tx.ClearUploadSessionReservation(session.ID)
+	if err != nil || !cleared {
+		return err
+	}
+
+	return releaseUsage(tx, session.UploaderID, dao.UsageDelta{StagedSize: session.ReservedSize})
+}
+
+// 在创建或复用文件记录的事务中锁定会话，释放预留的用量并标记为完成
+// 会话已被其他请求完成时返回 errUploadSessionCompleted，由事务回滚本次创建的记录
+func finishUploadSession(tx *dao.Dao, session *model.UploadSession, fileID uint32) error {
+	locked, err := tx.LockUploadSession(session.ID)
+	if err != nil {
+		return err
+	}
+	if locked == nil {
+		return ErrUploadSessionNotFound
+	}
+	if locked.State == model.UploadSessionCompleted {
+		return errUploadSessionCompleted
+	}
+	if err := releaseUploadReservation(tx, locked); err != nil {
+		return err
+	}
+
+	return tx.CompleteUploadSession(locked.ID, fileID)
+}
+
+// 返回当前用户可以访问的未过期会话
+func (svc *Service) getUploadSession(uploadID string) (*model.UploadSession, error) {
+	session, err := svc.dao.GetUploadSession(uploadID)
+	if err != nil {
+		return nil, err
+	}
+	if session == nil || session.ExpiresOn <= uint32(time.Now().Unix()) || session.UploaderID != svc.operatorID() {
+		return nil, ErrUploadSessionNotFound
+	}
+
+	return session, nil
+}
+
+// 暂存在临时文件中还没有记录的分片
+type stagedChunk struct {
+	name string
+	size int64
+	hash string
+}
+
+// 将分片写入临时文件，最多读取 maxSize 个字节
+// exact 为 true 时分片必须正好是 maxSize 个字节
+func (svc *Service) stageChunk(maxSize int64, exact bool, r io.Reader) (*stagedChunk, error) {
+	name, err := upload.GetTempFileName()
+	if err != nil {
+		return nil, err
+	}
+	hr := upload.NewHashReader(upload.NewSizeLimitReader(r, maxSize))
+	if err := upload.GetStorage().Put(svc.ctx, name, hr, -1, "application/octet-stream"); err != nil {
+		return nil, err
+	}
+	if exact && hr.Size() != maxSize {
+		svc.removeTempFile(name)
+		return nil, ErrUploadChunkInvalid
+	}
+
+	return &stagedChunk{name: name, size: hr.Size(), hash: hr.Sum()}, nil
+}
+
+// 在锁定会话的事务中将暂存的分片移动到 start 对应的位置并记录，同一会话的并发写入依次执行
+// offset 不小于 0 时要求会话从开头连续接收的字节数仍为 offset，并发追加到同一偏移时只有一个成功
+func (svc *Service) commitChunk(session *model.UploadSession, start int64, chunk *stagedChunk, offset int64) error {
+	// 移动成功后临时文件已不存在，删除不会出错
+	defer svc.removeTempFile(chunk.name)
+	if chunk.size == 0 {
+		return nil
+	}
+
+	return svc.dao.Transaction(func(tx *dao.Dao) error {
+		locked, err := tx.LockUploadSession(session.ID)
+		if err != nil {
+			return err
+		}
+		if locked == nil {
+			return ErrUploadSessionNotFound
+		}
+		if locked.State != model.UploadSessionUploading && offset < 0 {
+			return ErrUploadChunkInvalid
+		}
+		if locked.State != model.UploadSessionUploading {
+			return ErrUploadOffsetMismatch
+		}
+		if offset >= 0 {
+			chunks, err := tx.GetUploadChunkList(session.ID)
+			if err != nil {
+				return err
+			}
+			if _, received := chunkRanges(chunks); received != offset {
+				return ErrUploadOffsetMismatch
+			}
+		}
+
+		if err := upload.GetStorage().Move(svc.ctx, chunk.name, upload.GetChunkName(session.UploadID, start)); err != nil {
+			return err
+		}
+		return tx.SaveUploadChunk(session.ID, start, chunk.size, chunk.hash, svc.operator())
+	})
+}
+
+// 合并分片并创建文件记录，失败时保留会话、分片和预留的用量，可以重试
+func (svc *Service) completeUploadSession(session *model.UploadSession) (*FileInfo, error) {
+	if session.State == model.UploadSessionCompleted {
+		return svc.getUploadSessionFile(session)
+	}
+
+	chunks, err := svc.dao.GetUploadChunkList(session.ID)
+	if err != nil {
+		return nil, err
+	}
+	// 分片必须从 0 开始首尾相接地覆盖整个文件
+	parts := make([]upload.Chunk, 0, len(chunks))
+	var offset int64
+	for _, chunk := range chunks {
+		if chunk.Start != offset {
+			return nil, ErrUploadIncomplete
+		}
+		parts = append(parts, upload.Chunk{
+			Name: upload.GetChunkName(session.UploadID, chunk.Start),
+			Size: chunk.Size,
+			Hash: chunk.Hash,
+		})
+		offset += chunk.Size
+	}
+	if offset != session.Size {
+		return nil, ErrUploadIncomplete
+	}
+
+	// 合并后的文件按实际大小计入用量，预留的部分在创建文件记录的事务中释放
+	r := upload.NewChunkReader(svc.ctx, upload.GetStorage(), parts, session.Hash)
+	defer r.Close()
+	fileInfo, err := svc.uploadFile(&uploadMeta{
+		fileType: upload.FileType(session.FileType),
+		name:     session.FileName,
+		private:  session.Private == 1,
+		session:  session,
+	}, r)
+	if err == errUploadSessionCompleted {
+		// 其他请求已经完成合并，返回它创建的文件，分片由它删除
+		completed, err := svc.dao.GetUploadSession(session.UploadID)
+		if err != nil {
+			return nil, err
+		}
+		if completed == nil {
+			return nil, ErrUploadSessionNotFound
+		}
+		return svc.getUploadSessionFile(completed)
+	}
+	if err != nil {
+		return nil, err
+	}
+	svc.removeUploadChunks(session, chunks)
+
+	return fileInfo, nil
+}
+
+// 返回已完成的会话合并生成的文件
+func (svc *Service) getUploadSessionFile(session *model.UploadSession) (*FileInfo, error) {
+	file, err := svc.dao.GetFile(session.FileID)
+	if err != nil {
+		return nil, err
+	}
+	if file == nil {
+		return nil, ErrUploadSessionNotFound
+	}
+
+	return newFileInfo(file), nil
+}
+
+// 删除会话及其分片
+func (svc *Service) removeUploadSession(session *model.UploadSession) error {
+	chunks, err := svc.dao.GetUploadChunkList(session.ID)
+	if err != nil {
+		return err
+	}
+	svc.removeUploadChunks(session, chunks)
+	if err := svc.releaseUploadSession(session); err != nil {
+		return err
+	}
+
+	return svc.dao.DeleteUploadSession(session.ID)
+}
+
+// 删除分片的内容和记录，失败时只记录日志，由过期清理兜底
+func (svc *Service) removeUploadChunks(session *model.UploadSession, chunks []*model.UploadChunk) {
+	for _, chunk := range chunks {
+		svc.removeTempFile(upload.GetChunkName(session.UploadID, chunk.Start))
+	}
+	if err := svc.dao.DeleteUploadChunks(session.ID); err != nil {
+		global.Logger.ErrorfT("svc.removeUploadChunks err: %v", err)
+	}
+}
+
+func newUploadSessionInfo(session *model.UploadSession, chunks []*model.UploadChunk) *UploadSessionInfo {
+	received, offset := chunkRanges(chunks)
+	info := &UploadSessionInfo{
+		UploadID:  session.UploadID,
+		FileName:  session.FileName,
+		Size:      session.Size,
+		ChunkSize: session.ChunkSize,
+		Offset:    offset,
+		Received:  received,
+		Chunks:    []int64{},
+		ExpiresOn: session.ExpiresOn,
+		Completed: session.State == model.UploadSessionCompleted,
+		FileID:    session.FileID,
+	}
+	if info.Completed {
+		info.Offset = session.Size
+		info.Received = [][2]int64{{0, session.Size}}
+	}
+	if session.ChunkSize > 0 {
+		for _, chunk := range chunks {
+			if chunk.Start%session.ChunkSize == 0 {
+				info.Chunks = append(info.Chunks, chunk.Start/session.ChunkSize)
+			}
+		}
+	}
+
+	return info
+}
+
+// 合并已接收的分片为字节区间，同时返回从 0 开始连续接收的字节数
+func chunkRanges(chunks []*model.UploadChunk) ([][2]int64, int64) {
+	sorted := make([]*model.UploadChunk, len(chunks))
+	copy(sorted, chunks)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Start < sorted[j].Start })
+
+	ranges := [][2]int64{}
+	for _, chunk := range sorted {
+		end := chunk.Start + chunk.Size
+		if n := len(ranges); n > 0 && chunk.Start <= ranges[n-1][1] {
+			if end > ranges[n-1][1] {
+				ranges[n-1][1] = end
+			}
+			continue
+		}
+		ranges = append(ranges, [2]int64{chunk.Start, end})
+	}
+
+	var offset int64
+	if len(ranges) > 0 && ranges[0][0] == 0 {
+		offset = ranges[0][1]
+	}
+
+	return ranges, offset
+}
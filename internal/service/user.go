@@ -0,0 +1,93 @@
+package service
+
+import (
+	"errors"
+
+	"github.com/go-programming-tour/blog-service/internal/model"
+	"github.com/go-programming-tour/blog-service/pkg/util"
+)
+
+var (
+	ErrUserExist         = errors.New("username already exists")
+	ErrIncorrectPassword = errors.New("username or password is incorrect")
+	ErrPasswordLength    = errors.New("password must be 8 to 72 bytes")
+)
+
+type LoginRequest struct {
+	Username string `form:"username" binding:"required,max=32"`
+	Password string `form:"password" binding:"required,max=72"`
+}
+
+// bcrypt 只使用密码的前 72 个字节
+type RegisterRequest struct {
+	Username string `form:"username" binding:"required,alphanum,min=3,max=32"`
+	Password string `form:"password" binding:"required,min=8,max=72"`
+	Nickname string `form:"nickname" binding:"max=100"`
+}
+
+type UpdateUserRoleRequest struct {
+	ID   uint32 `form:"id" binding:"required,gte=1"`
+	Role string `form:"role" binding:"required,oneof=admin editor author reader"`
+}
+
+// 校验用户名和密码，成功时返回对应的用户
+func (svc *Service) CheckAuth(param *LoginRequest) (*model.User, error) {
+	user, err := svc.dao.GetUserByUsername(param.Username)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		util.CompareDummyPassword(param.Password)
+		return nil, ErrIncorrectPassword
+	}
+	if !util.ComparePassword(user.Password, param.Password) || user.State != 1 {
+		return nil, ErrIncorrectPassword
+	}
+
+	return user, nil
+}
+
+// 用户名由 blog_user 的唯一索引保证不重复，并发注册同一用户名时只有一个成功
+func (svc *Service) Register(param *RegisterRequest) (*model.User, error) {
+	hash, err := util.HashPassword(param.Password)
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := svc.dao.CreateUser(param.Username, hash, param.Nickname, model.UserRoleReader)
+	if model.IsDuplicateKeyError(err) {
+		return nil, ErrUserExist
+	}
+
+	return user, err
+}
+
+// 还没有管理员时创建第一个管理员，已有管理员时不做任何修改并返回 false
+// 注册只能创建读者，新部署需要通过它得到可以分配角色的账号
+func (svc *Service) CreateFirstAdmin(username, password string) (bool, error) {
+	count, err := svc.dao.CountUserByRole(model.UserRoleAdmin)
+	if err != nil || count > 0 {
+		return false, err
+	}
+	if len(password) < 8 || len(password) > 72 {
+		return false, ErrPasswordLength
+	}
+
+	hash, err := util.HashPassword(password)
+	if err != nil {
+		return false, err
+	}
+	_, err = svc.dao.CreateUser(username, hash, username, model.UserRoleAdmin)
+	if model.IsDuplicateKeyError(err) {
+		return false, ErrUserExist
+	}
+	if err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+func (svc *Service) UpdateUserRole(param *UpdateUserRoleRequest) error {
+	return svc.dao.UpdateUserRole(param.ID, param.Role, svc.operator())
+}
@@ -1,16 +1,27 @@
 package main
 
 import (
+	"context"
+	"fmt"
 	"log"
 	"net/http"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/go-programming-tour/blog-service/global"
 	"github.com/go-programming-tour/blog-service/internal/model"
 	"github.com/go-programming-tour/blog-service/internal/routers"
+	"github.com/go-programming-tour/blog-service/internal/service"
+	"github.com/go-programming-tour/blog-service/pkg/app"
 	"github.com/go-programming-tour/blog-service/pkg/logger"
+	"github.com/go-programming-tour/blog-service/pkg/markdown"
 	"github.com/go-programming-tour/blog-service/pkg/setting"
+	"github.com/go-programming-tour/blog-service/pkg/upload"
+	"github.com/go-programming-tour/blog-service/pkg/util"
 	"gopkg.in/natefinch/lumberjack.v2"
 )
 
@@ -20,6 +31,16 @@ func init() {
 		log.Fatalf("init.setupSetting fail. err = %v", err)
 	}
 
+	err = setupJWTKeys()
+	if err != nil {
+		log.Fatalf("init.setupJWTKeys fail. err = %v", err)
+	}
+
+	err = setupStorage()
+	if err != nil {
+		log.Fatalf("init.setupStorage fail. err = %v", err)
+	}
+
 	err = setupDBEngine()
 	if err != nil {
 		log.Fatalf("init.setupDBEngine fail. err = %v", err)
@@ -29,6 +50,21 @@ func init() {
 	if err != nil {
 		log.Fatalf("init.setupLogger fail. err = %v", err)
 	}
+
+	err = setupAdmin()
+	if err != nil {
+		log.Fatalf("init.setupAdmin fail. err = %v", err)
+	}
+
+	err = setupMarkdownCache()
+	if err != nil {
+		log.Fatalf("init.setupMarkdownCache fail. err = %v", err)
+	}
+
+	err = setupArticleSlugs()
+	if err != nil {
+		log.Fatalf("init.setupArticleSlugs fail. err = %v", err)
+	}
 }
 
 // @title 博客系统
@@ -51,7 +87,119 @@ func main() {
 		MaxHeaderBytes: 1 << 20,
 	}
 
-	server.ListenAndServe()
+	// 收到退出信号后停止接收新请求，ctx 结束时后台任务在处理完当前的一项后返回
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	holder := leaseHolder()
+	var wg sync.WaitGroup
+	wg.Add(4)
+	// 搜索索引在后台建立，不阻塞启动，建立完成前搜索没有结果
+	go func() {
+		buildSearchIndex(ctx)
+		runPeriodically(ctx, &wg, global.AppSetting.SearchIndexRebuildInterval, buildSearchIndex)
+	}()
+	go runPeriodically(ctx, &wg, global.AppSetting.UploadSessionCleanInterval, cleanUploadSessions)
+	go runPeriodically(ctx, &wg, global.AppSetting.UploadGCInterval, collectOrphanedFiles)
+	go runPeriodically(ctx, &wg, global.AppSetting.ArticlePublishInterval, func(ctx context.Context) {
+		publishDueArticles(ctx, holder)
+	})
+
+	go func() {
+		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			log.Fatalf("server.ListenAndServe err: %v", err)
+		}
+	}()
+
+	<-ctx.Done()
+	stop()
+	log.Println("shutting down server...")
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), global.ServerSetting.ShutdownTimeout)
+	defer cancel()
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		log.Printf("server.Shutdown err: %v", err)
+	}
+	// 后台任务和请求共用退出的等待时间，超时后不再等待，未完成的任务在下次启动或由其他实例继续
+	if !waitTimeout(shutdownCtx, &wg) {
+		log.Println("background tasks did not finish before shutdown timeout")
+	}
+
+	svc := service.New(context.Background())
+	if err := svc.ReleaseArticlePublisher(holder); err != nil {
+		log.Printf("svc.ReleaseArticlePublisher err: %v", err)
+	}
+	log.Println("server exited")
+}
+
+// 按间隔执行后台任务，ctx 会传给任务，ctx 结束后等待正在执行的任务返回，interval 不大于 0 时不执行
+func runPeriodically(ctx context.Context, wg *sync.WaitGroup, interval time.Duration, task func(ctx context.Context)) {
+	defer wg.Done()
+	if interval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			task(ctx)
+		}
+	}
+}
+
+// 等待 wg 完成，ctx 先结束时返回 false
+func waitTimeout(ctx context.Context, wg *sync.WaitGroup) bool {
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// 清理过期的分片上传会话及其分片
+func cleanUploadSessions(ctx context.Context) {
+	svc := service.New(ctx)
+	count, err := svc.CleanExpiredUploadSessions()
+	if err != nil {
+		global.Logger.ErrorfT("svc.CleanExpiredUploadSessions err: %v", err)
+	}
+	if count > 0 {
+		global.Logger.InfofT("cleaned %d expired upload sessions", count)
+	}
+}
+
+// 发布到期的定时发布文章，租约的有效期为两个检查间隔，持有租约的实例退出后由其他实例接手
+func publishDueArticles(ctx context.Context, holder string) {
+	svc := service.New(ctx)
+	count, err := svc.PublishDueArticles(holder, 2*global.AppSetting.ArticlePublishInterval)
+	if err != nil {
+		global.Logger.ErrorfT("svc.PublishDueArticles err: %v", err)
+	}
+	if count > 0 {
+		global.Logger.InfofT("published %d scheduled articles", count)
+	}
+}
+
+// 当前实例持有租约时使用的标识
+func leaseHolder() string {
+	hostname, _ := os.Hostname()
+	suffix, err := util.RandomString(4)
+	if err != nil {
+		log.Fatalf("util.RandomString err: %v", err)
+	}
+
+	return fmt.Sprintf("%s-%d-%s", hostname, os.Getpid(), suffix)
 }
 
 // 读取配置文件，返回配置参数结构体
@@ -79,10 +227,24 @@ func setupSetting() error {
 	if err = setting.ReadSection("Email", &global.EmailSetting); err != nil {
 		return err
 	}
+	if err = setting.ReadSection("S3", &global.S3Setting); err != nil {
+		return err
+	}
 
 	global.ServerSetting.ReadTimeout *= time.Second
 	global.ServerSetting.WriteTimeout *= time.Second
+	global.ServerSetting.ShutdownTimeout *= time.Second
 	global.JWTSetting.Expire *= time.Second
+	global.JWTSetting.RefreshExpire *= time.Second
+	global.JWTSetting.RevocationCacheTTL *= time.Second
+	global.AppSetting.UploadSignedUrlExpire *= time.Second
+	global.AppSetting.UploadSessionExpire *= time.Second
+	global.AppSetting.UploadSessionCleanInterval *= time.Second
+	global.AppSetting.UploadGCGracePeriod *= time.Second
+	global.AppSetting.UploadGCInterval *= time.Second
+	global.AppSetting.ArticlePublishInterval *= time.Second
+	global.AppSetting.SearchIndexRebuildInterval *= time.Second
+	global.AppSetting.FeedCacheTTL *= time.Second
 
 	// fmt.Println(*global.ServerSetting)
 	// fmt.Println(*global.AppSetting)
@@ -91,6 +253,18 @@ func setupSetting() error {
 	return nil
 }
 
+// 加载 JWT 签名和验签密钥，并设置令牌吊销状态的缓存
+func setupJWTKeys() error {
+	app.SetupRevocationCache(global.JWTSetting.RevocationCacheTTL)
+
+	return app.SetupJWTKeys(global.JWTSetting)
+}
+
+// 创建上传文件的存储后端
+func setupStorage() error {
+	return upload.SetupStorage(global.AppSetting, global.S3Setting)
+}
+
 // 创建数据库实例
 func setupDBEngine() error {
 	var err error
@@ -102,6 +276,49 @@ func setupDBEngine() error {
 	return nil
 }
 
+// 还没有管理员时，使用环境变量 BLOG_ADMIN_USERNAME 和 BLOG_ADMIN_PASSWORD 创建第一个管理员
+// 已有管理员后这两个变量不再生效，可以从部署中移除
+func setupAdmin() error {
+	username, password := os.Getenv("BLOG_ADMIN_USERNAME"), os.Getenv("BLOG_ADMIN_PASSWORD")
+	if username == "" || password == "" {
+		return nil
+	}
+
+	svc := service.New(context.Background())
+	created, err := svc.CreateFirstAdmin(username, password)
+	if created {
+		global.Logger.InfofT("created admin user %s", username)
+	}
+
+	return err
+}
+
+// 创建文章渲染结果的缓存
+func setupMarkdownCache() error {
+	markdown.SetupCache(global.AppSetting.MarkdownCacheSize)
+
+	return nil
+}
+
+// 从数据库重新建立文章的搜索索引，失败时保留原有的索引
+func buildSearchIndex(ctx context.Context) {
+	svc := service.New(ctx)
+	if err := svc.BuildSearchIndex(); err != nil {
+		global.Logger.ErrorfT("svc.BuildSearchIndex err: %v", err)
+	}
+}
+
+// 为还没有别名的已有文章生成别名，只在执行 0010 迁移后的第一次启动时有需要处理的文章，不修改文章的修改时间
+func setupArticleSlugs() error {
+	svc := service.New(context.Background())
+	count, err := svc.GenerateArticleSlugs()
+	if count > 0 {
+		global.Logger.InfofT("generated slugs for %d articles", count)
+	}
+
+	return err
+}
+
 // 初始化日志组件
 func setupLogger() error {
 	fileName := global.AppSetting.LogSavePath + "/" +
@@ -117,3 +334,16 @@ func setupLogger() error {
 
 	return nil
 }
+
+// 回收不再被文章引用的上传文件
+func collectOrphanedFiles(ctx context.Context) {
+	svc := service.New(ctx)
+	report, err := svc.CollectOrphanedFiles(false)
+	if err != nil {
+		global.Logger.ErrorfT("svc.CollectOrphanedFiles err: %v", err)
+		return
+	}
+	if len(report.Deleted) > 0 {
+		global.Logger.InfofT("deleted %d orphaned files, freed %d bytes", len(report.Deleted), report.FreedSize)
+	}
+}
@@ -1,6 +1,8 @@
 package app
 
 import (
+	"context"
+	"strconv"
 	"time"
 
 	jwt "github.com/dgrijalva/jwt-go"
@@ -8,38 +10,54 @@ import (
 	"github.com/go-programming-tour/blog-service/pkg/util"
 )
 
+// 只携带用户身份信息，不包含任何密钥相关的内容
 type Claims struct {
-	AppKey    string `json:"app_key"`
-	AppSecret string `json:"app_secret"`
+	UserID   uint32 `json:"user_id"`
+	Username string `json:"username"`
+	Role     string `json:"role"`
 	jwt.StandardClaims
 }
 
+type claimsKey struct{}
+
 func GetJWTSecret() []byte {
 	return []byte(global.JWTSetting.Secret)
 }
 
-func GenerateToken(appKey, appSecret string) (string, error) {
+// 每个令牌都带有随机的 JWT ID，用于吊销
+func GenerateToken(userID uint32, username, role string) (string, error) {
+	jti, err := util.RandomString(16)
+	if err != nil {
+		return "", err
+	}
+
 	nowTime := time.Now()
 	expireTime := nowTime.Add(global.JWTSetting.Expire)
 	claims := Claims{
-		AppKey:    util.EncodeMD5(appKey),
-		AppSecret: util.EncodeMD5(appSecret),
+		UserID:   userID,
+		Username: username,
+		Role:     role,
 		StandardClaims: jwt.StandardClaims{
+			Id:        jti,
+			Subject:   strconv.FormatUint(uint64(userID), 10),
+			IssuedAt:  nowTime.Unix(),
 			ExpiresAt: expireTime.Unix(),
 			Issuer:    global.JWTSetting.Issuer,
 		},
 	}
-	// 生成jwt token
-	tokenClaims := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	// 生成jwt token，使用非对称密钥时在头部写入 kid
+	method, key, kid := signingKey()
+	tokenClaims := jwt.NewWithClaims(method, claims)
+	if kid != "" {
+		tokenClaims.Header["kid"] = kid
+	}
 	// 生成签名字符串
-	token, err := tokenClaims.SignedString(GetJWTSecret())
+	token, err := tokenClaims.SignedString(key)
 	return token, err
 }
 
 func ParseToken(token string) (*Claims, error) {
-	tokenClaims, err := jwt.ParseWithClaims(token, &Claims{}, func(token *jwt.Token) (interface{}, error) {
-		return GetJWTSecret(), nil
-	})
+	tokenClaims, err := jwt.ParseWithClaims(token, &Claims{}, verifyKey)
 	if err != nil {
 		return nil, err
 	}
@@ -51,3 +69,14 @@ func ParseToken(token string) (*Claims, error) {
 
 	return nil, err
 }
+
+// 将当前用户的 Claims 存入上下文
+func WithClaims(ctx context.Context, claims *Claims) context.Context {
+	return context.WithValue(ctx, claimsKey{}, claims)
+}
+
+// 从上下文中取出当前用户的 Claims
+func ClaimsFromContext(ctx context.Context) (*Claims, bool) {
+	claims, ok := ctx.Value(claimsKey{}).(*Claims)
+	return claims, ok
+}
@@ -0,0 +1,248 @@
+package app
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"sort"
+
+	jwt "github.com/dgrijalva/jwt-go"
+	"github.com/go-programming-tour/blog-service/pkg/setting"
+)
+
+// 签名或验签用的密钥
+type jwtKey struct {
+	id      string
+	method  jwt.SigningMethod
+	private crypto.PrivateKey
+	public  crypto.PublicKey
+}
+
+// 启动时加载，之后只读
+var (
+	jwtSigningKey  *jwtKey
+	jwtKeys        = map[string]*jwtKey{}
+	jwtAcceptHS256 bool // 是否接受没有 kid 的 HS256 令牌
+)
+
+// JSON Web Key，只包含公钥部分
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+	Y   string `json:"y,omitempty"`
+}
+
+// 从配置加载非对称密钥，未配置任何密钥时使用 Secret 签名
+func SetupJWTKeys(s *setting.JWTSettingS) error {
+	keys := make(map[string]*jwtKey, len(s.Keys))
+	for _, ks := range s.Keys {
+		key, err := loadJWTKey(ks)
+		if err != nil {
+			return fmt.Errorf("load jwt key %q: %v", ks.ID, err)
+		}
+		if _, ok := keys[key.id]; ok {
+			return fmt.Errorf("duplicate jwt key id %q", key.id)
+		}
+		keys[key.id] = key
+	}
+
+	var signingKey *jwtKey
+	if s.SigningKeyID != "" {
+		signingKey = keys[s.SigningKeyID]
+		if signingKey == nil || signingKey.private == nil {
+			return fmt.Errorf("jwt signing key %q has no private key", s.SigningKeyID)
+		}
+	} else if len(keys) > 0 {
+		// 配置了非对称密钥时只使用它们签发，否则签发的 HS256 令牌将无法通过验签
+		return errors.New("jwt signing key id must be configured when jwt keys are configured")
+	} else if s.Secret == "" {
+		return errors.New("either jwt secret or signing key must be configured")
+	}
+
+	jwtKeys = keys
+	jwtSigningKey = signingKey
+	// 配置了非对称密钥后不再接受 HS256 令牌，除非显式允许，使迁移期间签发的旧令牌继续有效
+	jwtAcceptHS256 = s.Secret != "" && (signingKey == nil || s.AllowLegacyHS256)
+	return nil
+}
+
+// 返回所有验签公钥，按 kid 排序
+func GetJWKS() []JWK {
+	jwks := make([]JWK, 0, len(jwtKeys))
+	for _, key := range jwtKeys {
+		jwks = append(jwks, key.jwk())
+	}
+	sort.Slice(jwks, func(i, j int) bool { return jwks[i].Kid < jwks[j].Kid })
+
+	return jwks
+}
+
+// 返回签发 Token 所用的算法、密钥和 kid
+func signingKey() (jwt.SigningMethod, interface{}, string) {
+	if jwtSigningKey == nil {
+		return jwt.SigningMethodHS256, GetJWTSecret(), ""
+	}
+
+	return jwtSigningKey.method, jwtSigningKey.private, jwtSigningKey.id
+}
+
+// 根据 Token 头部的 kid 选择验签公钥
+// 没有 kid 的 Token 只在使用 Secret 签名且没有配置非对称密钥时按 HS256 验证
+func verifyKey(token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+	if kid == "" {
+		if token.Method == jwt.SigningMethodHS256 && jwtAcceptHS256 {
+			return GetJWTSecret(), nil
+		}
+		return nil, errors.New("token has no kid")
+	}
+
+	key, ok := jwtKeys[kid]
+	if !ok {
+		return nil, fmt.Errorf("unknown kid %q", kid)
+	}
+	if token.Method.Alg() != key.method.Alg() {
+		return nil, fmt.Errorf("unexpected signing method %q for kid %q", token.Method.Alg(), kid)
+	}
+
+	return key.public, nil
+}
+
+func loadJWTKey(ks setting.JWTKeySetting) (*jwtKey, error) {
+	if ks.ID == "" {
+		return nil, errors.New("key id is empty")
+	}
+	key := &jwtKey{id: ks.ID}
+	switch ks.Algorithm {
+	case jwt.SigningMethodRS256.Alg():
+		key.method = jwt.SigningMethodRS256
+	case jwt.SigningMethodES256.Alg():
+		key.method = jwt.SigningMethodES256
+	case SigningMethodEdDSA.Alg():
+		key.method = SigningMethodEdDSA
+	default:
+		return nil, fmt.Errorf("unsupported algorithm %q", ks.Algorithm)
+	}
+
+	var err error
+	switch {
+	case ks.PrivateKeyFile != "":
+		if key.private, key.public, err = readPrivateKey(ks.PrivateKeyFile); err != nil {
+			return nil, err
+		}
+	case ks.PublicKeyFile != "":
+		if key.public, err = readPublicKey(ks.PublicKeyFile); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, errors.New("neither private nor public key file is configured")
+	}
+
+	if !key.matchAlgorithm() {
+		return nil, fmt.Errorf("key type %T does not match algorithm %s", key.public, ks.Algorithm)
+	}
+
+	return key, nil
+}
+
+// 检查密钥类型与算法是否匹配，ES256 只接受 P-256 曲线
+func (k *jwtKey) matchAlgorithm() bool {
+	switch pub := k.public.(type) {
+	case *rsa.PublicKey:
+		return k.method == jwt.SigningMethodRS256
+	case *ecdsa.PublicKey:
+		return k.method == jwt.SigningMethodES256 && pub.Curve == elliptic.P256()
+	case ed25519.PublicKey:
+		return k.method == SigningMethodEdDSA
+	}
+
+	return false
+}
+
+func (k *jwtKey) jwk() JWK {
+	jwk := JWK{Kid: k.id, Use: "sig", Alg: k.method.Alg()}
+	switch pub := k.public.(type) {
+	case *rsa.PublicKey:
+		jwk.Kty = "RSA"
+		jwk.N = base64.RawURLEncoding.EncodeToString(pub.N.Bytes())
+		jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes())
+	case *ecdsa.PublicKey:
+		size := (pub.Curve.Params().BitSize + 7) / 8
+		jwk.Kty = "EC"
+		jwk.Crv = pub.Curve.Params().Name
+		jwk.X = base64.RawURLEncoding.EncodeToString(pub.X.FillBytes(make([]byte, size)))
+		jwk.Y = base64.RawURLEncoding.EncodeToString(pub.Y.FillBytes(make([]byte, size)))
+	case ed25519.PublicKey:
+		jwk.Kty = "OKP"
+		jwk.Crv = "Ed25519"
+		jwk.X = base64.RawURLEncoding.EncodeToString(pub)
+	}
+
+	return jwk
+}
+
+func readPEM(file string) (*pem.Block, error) {
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("no PEM data found in %s", file)
+	}
+
+	return block, nil
+}
+
+// 支持 PKCS#8、PKCS#1 和 SEC 1 格式的私钥
+func readPrivateKey(file string) (crypto.PrivateKey, crypto.PublicKey, error) {
+	block, err := readPEM(file)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var key interface{}
+	if key, err = x509.ParsePKCS8PrivateKey(block.Bytes); err != nil {
+		if key, err = x509.ParsePKCS1PrivateKey(block.Bytes); err != nil {
+			if key, err = x509.ParseECPrivateKey(block.Bytes); err != nil {
+				return nil, nil, fmt.Errorf("unsupported private key in %s", file)
+			}
+		}
+	}
+
+	switch k := key.(type) {
+	case *rsa.PrivateKey:
+		return k, &k.PublicKey, nil
+	case *ecdsa.PrivateKey:
+		return k, &k.PublicKey, nil
+	case ed25519.PrivateKey:
+		return k, k.Public(), nil
+	}
+
+	return nil, nil, fmt.Errorf("unsupported private key type %T in %s", key, file)
+}
+
+// 支持 PKIX 格式的公钥
+func readPublicKey(file string) (crypto.PublicKey, error) {
+	block, err := readPEM(file)
+	if err != nil {
+		return nil, err
+	}
+
+	return x509.ParsePKIXPublicKey(block.Bytes)
+}
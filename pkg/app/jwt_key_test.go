@@ -0,0 +1,265 @@
+package app
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+	"time"
+
+	jwt "github.com/dgrijalva/jwt-go"
+	"github.com/go-programming-tour/blog-service/global"
+	"github.com/go-programming-tour/blog-service/pkg/setting"
+)
+
+// 测试用的密钥文件
+type testKeyFiles struct {
+	private string
+	public  string
+}
+
+func writeTestKey(t *testing.T, name string, key crypto.Signer) testKeyFiles {
+	dir := t.TempDir()
+	privateDER, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatalf("MarshalPKCS8PrivateKey err: %v", err)
+	}
+	publicDER, err := x509.MarshalPKIXPublicKey(key.Public())
+	if err != nil {
+		t.Fatalf("MarshalPKIXPublicKey err: %v", err)
+	}
+
+	files := testKeyFiles{private: filepath.Join(dir, name+".pem"), public: filepath.Join(dir, name+".pub.pem")}
+	for file, block := range map[string]*pem.Block{
+		files.private: {Type: "PRIVATE KEY", Bytes: privateDER},
+		files.public:  {Type: "PUBLIC KEY", Bytes: publicDER},
+	} {
+		if err := ioutil.WriteFile(file, pem.EncodeToMemory(block), 0600); err != nil {
+			t.Fatalf("WriteFile err: %v", err)
+		}
+	}
+
+	return files
+}
+
+func setupTestJWT(t *testing.T, s *setting.JWTSettingS) {
+	s.Expire = time.Hour
+	global.JWTSetting = s
+	if err := SetupJWTKeys(s); err != nil {
+		t.Fatalf("SetupJWTKeys err: %v", err)
+	}
+}
+
+func tokenKid(t *testing.T, token string) string {
+	parsed, _, err := new(jwt.Parser).ParseUnverified(token, &Claims{})
+	if err != nil {
+		t.Fatalf("ParseUnverified err: %v", err)
+	}
+	kid, _ := parsed.Header["kid"].(string)
+
+	return kid
+}
+
+// 轮换签名密钥后旧令牌在旧公钥移除前仍可验证，新令牌使用新的 kid
+func TestJWTKeyRotation(t *testing.T) {
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("ed25519.GenerateKey err: %v", err)
+	}
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("ecdsa.GenerateKey err: %v", err)
+	}
+	oldKey, newKey := writeTestKey(t, "old", edKey), writeTestKey(t, "new", ecKey)
+
+	setupTestJWT(t, &setting.JWTSettingS{
+		SigningKeyID: "old",
+		Keys:         []setting.JWTKeySetting{{ID: "old", Algorithm: "EdDSA", PrivateKeyFile: oldKey.private}},
+	})
+	oldToken, err := GenerateToken(1, "alice", "author")
+	if err != nil {
+		t.Fatalf("GenerateToken err: %v", err)
+	}
+
+	// 新密钥签发，旧密钥只保留公钥用于验签
+	setupTestJWT(t, &setting.JWTSettingS{
+		SigningKeyID: "new",
+		Keys: []setting.JWTKeySetting{
+			{ID: "new", Algorithm: "ES256", PrivateKeyFile: newKey.private},
+			{ID: "old", Algorithm: "EdDSA", PublicKeyFile: oldKey.public},
+		},
+	})
+	newToken, err := GenerateToken(1, "alice", "author")
+	if err != nil {
+		t.Fatalf("GenerateToken err: %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		token   string
+		wantKid string
+	}{
+		{"old", oldToken, "old"},
+		{"new", newToken, "new"},
+	}
+	for _, tt := range tests {
+		if kid := tokenKid(t, tt.token); kid != tt.wantKid {
+			t.Errorf("%s token kid = %q, want %q", tt.name, kid, tt.wantKid)
+		}
+		claims, err := ParseToken(tt.token)
+		if err != nil {
+			t.Errorf("ParseToken(%s) err: %v", tt.name, err)
+			continue
+		}
+		if claims.UserID != 1 || claims.Username != "alice" {
+			t.Errorf("ParseToken(%s) = %+v", tt.name, claims)
+		}
+	}
+
+	// 旧公钥移除后旧令牌失效
+	setupTestJWT(t, &setting.JWTSettingS{
+		SigningKeyID: "new",
+		Keys:         []setting.JWTKeySetting{{ID: "new", Algorithm: "ES256", PrivateKeyFile: newKey.private}},
+	})
+	if _, err := ParseToken(oldToken); err == nil {
+		t.Error("ParseToken accepted a token signed by a removed key")
+	}
+	if _, err := ParseToken(newToken); err != nil {
+		t.Errorf("ParseToken(new) err: %v", err)
+	}
+}
+
+// 配置非对称密钥后不接受 HS256 令牌，除非显式允许过渡
+func TestJWTRejectsHS256WithKeys(t *testing.T) {
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("ed25519.GenerateKey err: %v", err)
+	}
+	key := writeTestKey(t, "ed", edKey)
+	publicPEM, err := ioutil.ReadFile(key.public)
+	if err != nil {
+		t.Fatalf("ReadFile err: %v", err)
+	}
+
+	setupTestJWT(t, &setting.JWTSettingS{Secret: "secret"})
+	legacyToken, err := GenerateToken(1, "alice", "author")
+	if err != nil {
+		t.Fatalf("GenerateToken err: %v", err)
+	}
+	// 以公钥作为 HS256 密钥伪造带 kid 的令牌
+	forged := jwt.NewWithClaims(jwt.SigningMethodHS256, Claims{UserID: 1, StandardClaims: jwt.StandardClaims{ExpiresAt: time.Now().Add(time.Hour).Unix()}})
+	forged.Header["kid"] = "ed"
+	forgedToken, err := forged.SignedString(publicPEM)
+	if err != nil {
+		t.Fatalf("SignedString err: %v", err)
+	}
+
+	keys := []setting.JWTKeySetting{{ID: "ed", Algorithm: "EdDSA", PrivateKeyFile: key.private}}
+	tests := []struct {
+		name        string
+		allowLegacy bool
+		token       string
+		wantErr     bool
+	}{
+		{"legacy", false, legacyToken, true},
+		{"legacy allowed", true, legacyToken, false},
+		{"forged", false, forgedToken, true},
+		{"forged with legacy allowed", true, forgedToken, true},
+	}
+	for _, tt := range tests {
+		setupTestJWT(t, &setting.JWTSettingS{Secret: "secret", SigningKeyID: "ed", Keys: keys, AllowLegacyHS256: tt.allowLegacy})
+		_, err := ParseToken(tt.token)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%s: ParseToken err = %v, wantErr %v", tt.name, err, tt.wantErr)
+		}
+	}
+}
+
+// JWKS 只包含公钥，按 kid 排序
+func TestGetJWKS(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("rsa.GenerateKey err: %v", err)
+	}
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("ecdsa.GenerateKey err: %v", err)
+	}
+	edPublic, edKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("ed25519.GenerateKey err: %v", err)
+	}
+	rsaFiles, ecFiles, edFiles := writeTestKey(t, "rsa", rsaKey), writeTestKey(t, "ec", ecKey), writeTestKey(t, "ed", edKey)
+
+	setupTestJWT(t, &setting.JWTSettingS{
+		SigningKeyID: "c-rsa",
+		Keys: []setting.JWTKeySetting{
+			{ID: "c-rsa", Algorithm: "RS256", PrivateKeyFile: rsaFiles.private},
+			{ID: "a-ed", Algorithm: "EdDSA", PublicKeyFile: edFiles.public},
+			{ID: "b-ec", Algorithm: "ES256", PublicKeyFile: ecFiles.public},
+		},
+	})
+
+	b64 := base64.RawURLEncoding.EncodeToString
+	want := []JWK{
+		{Kty: "OKP", Kid: "a-ed", Use: "sig", Alg: "EdDSA", Crv: "Ed25519", X: b64(edPublic)},
+		{Kty: "EC", Kid: "b-ec", Use: "sig", Alg: "ES256", Crv: "P-256", X: b64(ecKey.X.FillBytes(make([]byte, 32))), Y: b64(ecKey.Y.FillBytes(make([]byte, 32)))},
+		{Kty: "RSA", Kid: "c-rsa", Use: "sig", Alg: "RS256", N: b64(rsaKey.N.Bytes()), E: "AQAB"},
+	}
+	got := GetJWKS()
+	if len(got) != len(want) {
+		t.Fatalf("GetJWKS() returned %d keys, want %d", len(got), len(want))
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("GetJWKS()[%d] = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+}
+
+// 密钥类型与算法不匹配时拒绝加载
+func TestSetupJWTKeysRejectsMismatchedAlgorithm(t *testing.T) {
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("ecdsa.GenerateKey err: %v", err)
+	}
+	files := writeTestKey(t, "ec", ecKey)
+	err = SetupJWTKeys(&setting.JWTSettingS{
+		SigningKeyID: "ec",
+		Keys:         []setting.JWTKeySetting{{ID: "ec", Algorithm: "RS256", PrivateKeyFile: files.private}},
+	})
+	if err == nil {
+		t.Error("SetupJWTKeys accepted an EC key for RS256")
+	}
+}
+
+// 配置了非对称密钥时必须指定签名密钥，签名密钥必须有私钥
+func TestSetupJWTKeysRequiresSigningKey(t *testing.T) {
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("ed25519.GenerateKey err: %v", err)
+	}
+	files := writeTestKey(t, "ed", edKey)
+	tests := []struct {
+		name string
+		s    *setting.JWTSettingS
+	}{
+		{"no signing key id", &setting.JWTSettingS{Secret: "secret", Keys: []setting.JWTKeySetting{{ID: "ed", Algorithm: "EdDSA", PrivateKeyFile: files.private}}}},
+		{"public key only", &setting.JWTSettingS{SigningKeyID: "ed", Keys: []setting.JWTKeySetting{{ID: "ed", Algorithm: "EdDSA", PublicKeyFile: files.public}}}},
+		{"unknown signing key", &setting.JWTSettingS{SigningKeyID: "other", Keys: []setting.JWTKeySetting{{ID: "ed", Algorithm: "EdDSA", PrivateKeyFile: files.private}}}},
+		{"nothing configured", &setting.JWTSettingS{}},
+	}
+	for _, tt := range tests {
+		if err := SetupJWTKeys(tt.s); err == nil {
+			t.Errorf("%s: SetupJWTKeys err = nil, want error", tt.name)
+		}
+	}
+}
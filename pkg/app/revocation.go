@@ -0,0 +1,92 @@
+package app
+
+import (
+	"sync"
+	"time"
+)
+
+// 缓存的吊销状态数量超过该值时清理过期的条目
+const revocationCacheSweepSize = 10000
+
+// 访问令牌吊销状态的进程内缓存，避免每个请求都查询数据库
+// 已吊销的状态缓存到令牌过期，未吊销的状态只缓存 ttl，其他实例上的吊销最迟在 ttl 后生效
+type RevocationCache struct {
+	mu    sync.Mutex
+	ttl   time.Duration
+	items map[string]revocationEntry
+}
+
+type revocationEntry struct {
+	revoked   bool
+	expiresAt time.Time
+}
+
+var revocations = NewRevocationCache(0)
+
+func NewRevocationCache(ttl time.Duration) *RevocationCache {
+	return &RevocationCache{ttl: ttl, items: make(map[string]revocationEntry)}
+}
+
+// 设置未吊销状态的缓存时间，为 0 时不缓存未吊销的状态
+func SetupRevocationCache(ttl time.Duration) {
+	revocations = NewRevocationCache(ttl)
+}
+
+// 返回进程内的吊销状态缓存
+func GetRevocationCache() *RevocationCache {
+	return revocations
+}
+
+// 返回缓存的吊销状态，ok 为 false 时需要查询数据库
+func (c *RevocationCache) Get(jti string) (revoked, ok bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry, ok := c.items[jti]
+	if !ok {
+		return false, false
+	}
+	if !time.Now().Before(entry.expiresAt) {
+		delete(c.items, jti)
+		return false, false
+	}
+
+	return entry.revoked, true
+}
+
+// 缓存查询到的吊销状态，tokenExpiresAt 为令牌本身的过期时间
+func (c *RevocationCache) Set(jti string, revoked bool, tokenExpiresAt time.Time) {
+	expiresAt := tokenExpiresAt
+	if !revoked {
+		if c.ttl <= 0 {
+			return
+		}
+		if t := time.Now().Add(c.ttl); t.Before(expiresAt) {
+			expiresAt = t
+		}
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if len(c.items) >= revocationCacheSweepSize {
+		c.sweep()
+	}
+	c.items[jti] = revocationEntry{revoked: revoked, expiresAt: expiresAt}
+}
+
+// 删除过期的条目，仍然过多时丢弃未吊销的条目，它们可以重新从数据库查询
+func (c *RevocationCache) sweep() {
+	now := time.Now()
+	for jti, entry := range c.items {
+		if !now.Before(entry.expiresAt) {
+			delete(c.items, jti)
+		}
+	}
+	if len(c.items) < revocationCacheSweepSize {
+		return
+	}
+	for jti, entry := range c.items {
+		if !entry.revoked {
+			delete(c.items, jti)
+		}
+	}
+}
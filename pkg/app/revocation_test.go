@@ -0,0 +1,73 @@
+package app
+
+import (
+	"strconv"
+	"testing"
+	"time"
+)
+
+// 未吊销的状态缓存 ttl 后过期，已吊销的状态缓存到令牌过期
+func TestRevocationCacheExpires(t *testing.T) {
+	cache := NewRevocationCache(20 * time.Millisecond)
+	tokenExpiresAt := time.Now().Add(time.Hour)
+	cache.Set("valid", false, tokenExpiresAt)
+	cache.Set("revoked", true, tokenExpiresAt)
+	cache.Set("expired", true, time.Now().Add(-time.Second))
+
+	tests := []struct {
+		jti         string
+		wait        time.Duration
+		wantRevoked bool
+		wantOK      bool
+	}{
+		{"valid", 0, false, true},
+		{"revoked", 0, true, true},
+		{"expired", 0, false, false},
+		{"unknown", 0, false, false},
+		{"valid", 30 * time.Millisecond, false, false},
+		{"revoked", 0, true, true},
+	}
+	for _, tt := range tests {
+		time.Sleep(tt.wait)
+		revoked, ok := cache.Get(tt.jti)
+		if revoked != tt.wantRevoked || ok != tt.wantOK {
+			t.Errorf("Get(%q) after %v = %v, %v, want %v, %v", tt.jti, tt.wait, revoked, ok, tt.wantRevoked, tt.wantOK)
+		}
+	}
+}
+
+// ttl 为 0 时不缓存未吊销的状态，未吊销的状态不会超过令牌本身的过期时间
+func TestRevocationCacheValidEntries(t *testing.T) {
+	cache := NewRevocationCache(0)
+	cache.Set("valid", false, time.Now().Add(time.Hour))
+	if _, ok := cache.Get("valid"); ok {
+		t.Error("valid token is cached with zero ttl")
+	}
+
+	cache = NewRevocationCache(time.Hour)
+	cache.Set("valid", false, time.Now().Add(-time.Second))
+	if _, ok := cache.Get("valid"); ok {
+		t.Error("valid token is cached beyond its expiry")
+	}
+}
+
+// 条目过多时先清理过期的条目，再丢弃未吊销的条目，已吊销的条目保留
+func TestRevocationCacheSweep(t *testing.T) {
+	cache := NewRevocationCache(time.Hour)
+	tokenExpiresAt := time.Now().Add(time.Hour)
+	cache.Set("revoked", true, tokenExpiresAt)
+	for i := 1; i < revocationCacheSweepSize; i++ {
+		cache.Set(strconv.Itoa(i), false, tokenExpiresAt)
+	}
+	cache.Set("latest", false, tokenExpiresAt)
+
+	if revoked, ok := cache.Get("revoked"); !ok || !revoked {
+		t.Errorf("Get(revoked) = %v, %v, want true, true", revoked, ok)
+	}
+	if _, ok := cache.Get("latest"); !ok {
+		t.Error("latest entry is not cached")
+	}
+	if n := len(cache.items); n != 2 {
+		t.Errorf("len(items) = %d, want 2", n)
+	}
+}
@@ -32,3 +32,15 @@ func (s *StrTo) MustUInt32() uint32 {
 
 	return v
 }
+
+func (s *StrTo) Int64() (int64, error) {
+	v, err := strconv.ParseInt(s.String(), 10, 64)
+
+	return v, err
+}
+
+func (s *StrTo) MustInt64() int64 {
+	v, _ := s.Int64()
+
+	return v
+}
@@ -0,0 +1,217 @@
+package diff
+
+import "strings"
+
+// 行的变更类型
+type Op string
+
+const (
+	OpEqual  Op = "equal"
+	OpInsert Op = "insert"
+	OpDelete Op = "delete"
+)
+
+// 差异中的一行，OldLine 和 NewLine 为从 1 开始的行号，不存在于对应一侧时为 0
+type Line struct {
+	Op      Op     `json:"op"`
+	Text    string `json:"text"`
+	OldLine int    `json:"old_line"`
+	NewLine int    `json:"new_line"`
+}
+
+// 一段连续的变更及其上下文
+type Hunk struct {
+	OldStart int     `json:"old_start"`
+	OldLines int     `json:"old_lines"`
+	NewStart int     `json:"new_start"`
+	NewLines int     `json:"new_lines"`
+	Lines    []*Line `json:"lines"`
+}
+
+// 按行比较两段文本，换行符统一为 \n
+func Text(oldText, newText string) []*Line {
+	return Lines(splitLines(oldText), splitLines(newText))
+}
+
+// 使用 Myers 算法计算从 a 到 b 的最短编辑序列
+func Lines(a, b []string) []*Line {
+	// 先去掉相同的前缀和后缀，缩小需要比较的范围
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+
+	var lines []*Line
+	for i := 0; i < prefix; i++ {
+		lines = append(lines, &Line{Op: OpEqual, Text: a[i], OldLine: i + 1, NewLine: i + 1})
+	}
+	for _, line := range myers(a[prefix:len(a)-suffix], b[prefix:len(b)-suffix]) {
+		if line.OldLine > 0 {
+			line.OldLine += prefix
+		}
+		if line.NewLine > 0 {
+			line.NewLine += prefix
+		}
+		lines = append(lines, line)
+	}
+	for i := suffix; i > 0; i-- {
+		lines = append(lines, &Line{Op: OpEqual, Text: a[len(a)-i], OldLine: len(a) - i + 1, NewLine: len(b) - i + 1})
+	}
+
+	return lines
+}
+
+// 将差异按变更分组，每组前后保留 context 行相同的内容，没有变更时返回空切片
+func Unified(lines []*Line, context int) []*Hunk {
+	hunks := []*Hunk{}
+	var hunk *Hunk
+	// 上一个变更行的下标
+	lastChange := -1
+	for i, line := range lines {
+		if line.Op == OpEqual {
+			continue
+		}
+		start := i - context
+		if start < 0 {
+			start = 0
+		}
+		if hunk != nil && start <= lastChange+context+1 {
+			// 与上一组的上下文重叠，合并为一组
+			hunk.Lines = append(hunk.Lines, lines[lastChange+1:i+1]...)
+		} else {
+			if hunk != nil {
+				hunks = append(hunks, closeHunk(hunk, lines, lastChange, context))
+			}
+			hunk = &Hunk{Lines: append([]*Line{}, lines[start:i+1]...)}
+		}
+		lastChange = i
+	}
+	if hunk != nil {
+		hunks = append(hunks, closeHunk(hunk, lines, lastChange, context))
+	}
+
+	return hunks
+}
+
+// 补上最后一个变更之后的上下文，并计算起始行号和行数
+func closeHunk(hunk *Hunk, lines []*Line, lastChange, context int) *Hunk {
+	end := lastChange + 1 + context
+	if end > len(lines) {
+		end = len(lines)
+	}
+	hunk.Lines = append(hunk.Lines, lines[lastChange+1:end]...)
+
+	for _, line := range hunk.Lines {
+		if line.Op != OpInsert {
+			if hunk.OldStart == 0 {
+				hunk.OldStart = line.OldLine
+			}
+			hunk.OldLines++
+		}
+		if line.Op != OpDelete {
+			if hunk.NewStart == 0 {
+				hunk.NewStart = line.NewLine
+			}
+			hunk.NewLines++
+		}
+	}
+
+	return hunk
+}
+
+// 最大编辑步数，超过时不再计算最短编辑序列，避免差异很大时占用过多内存和时间
+const maxEdits = 2000
+
+func myers(a, b []string) []*Line {
+	n, m := len(a), len(b)
+	max := n + m
+	if max > maxEdits {
+		max = maxEdits
+	}
+	offset := max + 1
+	v := make([]int, 2*max+3)
+	var trace [][]int
+	for d := 0; d <= max; d++ {
+		// 只保存第 d 步可能访问的对角线，内存为 O(D^2)
+		trace = append(trace, append([]int(nil), v[offset-d-1:offset+d+2]...))
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+				x = v[offset+k+1]
+			} else {
+				x = v[offset+k-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x++
+				y++
+			}
+			v[offset+k] = x
+			if x >= n && y >= m {
+				return backtrack(a, b, trace)
+			}
+		}
+	}
+
+	// 差异过大时整体删除后插入
+	lines := make([]*Line, 0, n+m)
+	for i, text := range a {
+		lines = append(lines, &Line{Op: OpDelete, Text: text, OldLine: i + 1})
+	}
+	for i, text := range b {
+		lines = append(lines, &Line{Op: OpInsert, Text: text, NewLine: i + 1})
+	}
+
+	return lines
+}
+
+// 从终点沿着每一步的选择回溯，得到编辑序列
+func backtrack(a, b []string, trace [][]int) []*Line {
+	var lines []*Line
+	x, y := len(a), len(b)
+	for d := len(trace) - 1; d >= 0; d-- {
+		// trace[d][0] 对应对角线 -d-1
+		v := trace[d]
+		k := x - y
+		var prevK int
+		if k == -d || (k != d && v[k-1+d+1] < v[k+1+d+1]) {
+			prevK = k + 1
+		} else {
+			prevK = k - 1
+		}
+		prevX := v[prevK+d+1]
+		prevY := prevX - prevK
+		for x > prevX && y > prevY {
+			lines = append(lines, &Line{Op: OpEqual, Text: a[x-1], OldLine: x, NewLine: y})
+			x--
+			y--
+		}
+		if d > 0 {
+			if x == prevX {
+				lines = append(lines, &Line{Op: OpInsert, Text: b[y-1], NewLine: y})
+			} else {
+				lines = append(lines, &Line{Op: OpDelete, Text: a[x-1], OldLine: x})
+			}
+		}
+		x, y = prevX, prevY
+	}
+
+	for i, j := 0, len(lines)-1; i < j; i, j = i+1, j-1 {
+		lines[i], lines[j] = lines[j], lines[i]
+	}
+
+	return lines
+}
+
+func splitLines(text string) []string {
+	if text == "" {
+		return nil
+	}
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+
+	return strings.Split(strings.TrimSuffix(text, "\n"), "\n")
+}
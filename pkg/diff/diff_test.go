@@ -0,0 +1,218 @@
+package diff
+
+import (
+	"math/rand"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// 由差异还原出比较的两侧，并检查行号连续
+func apply(t *testing.T, lines []*Line) (a, b []string) {
+	t.Helper()
+	for _, line := range lines {
+		if line.Op != OpInsert {
+			a = append(a, line.Text)
+			if line.OldLine != len(a) {
+				t.Fatalf("OldLine = %d, want %d", line.OldLine, len(a))
+			}
+		}
+		if line.Op != OpDelete {
+			b = append(b, line.Text)
+			if line.NewLine != len(b) {
+				t.Fatalf("NewLine = %d, want %d", line.NewLine, len(b))
+			}
+		}
+	}
+
+	return a, b
+}
+
+func countEdits(lines []*Line) int {
+	edits := 0
+	for _, line := range lines {
+		if line.Op != OpEqual {
+			edits++
+		}
+	}
+
+	return edits
+}
+
+// 最短编辑步数为 n + m - 2 * LCS
+func minEdits(a, b []string) int {
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] > lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+
+	return len(a) + len(b) - 2*lcs[0][0]
+}
+
+func ops(lines []*Line) string {
+	var b strings.Builder
+	for _, line := range lines {
+		switch line.Op {
+		case OpEqual:
+			b.WriteByte('=')
+		case OpInsert:
+			b.WriteByte('+')
+		case OpDelete:
+			b.WriteByte('-')
+		}
+	}
+
+	return b.String()
+}
+
+func TestLines(t *testing.T) {
+	tests := []struct {
+		name string
+		a    string
+		b    string
+		ops  string
+	}{
+		{"both empty", "", "", ""},
+		{"insert into empty", "", "a b", "++"},
+		{"delete all", "a b", "", "--"},
+		{"equal", "a b c", "a b c", "==="},
+		{"insert middle", "a c", "a b c", "=+="},
+		{"delete middle", "a b c", "a c", "=-="},
+		{"replace", "a b c", "a x c", "=-+="},
+		{"insert at start and end", "b", "a b c", "+=+"},
+		{"classic", "a b c a b b a", "c b a b a c", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a, b := strings.Fields(tt.a), strings.Fields(tt.b)
+			lines := Lines(a, b)
+			gotA, gotB := apply(t, lines)
+			if strings.Join(gotA, " ") != tt.a {
+				t.Errorf("old side = %q, want %q", gotA, tt.a)
+			}
+			if strings.Join(gotB, " ") != tt.b {
+				t.Errorf("new side = %q, want %q", gotB, tt.b)
+			}
+			if edits, want := countEdits(lines), minEdits(a, b); edits != want {
+				t.Errorf("edits = %d, want %d", edits, want)
+			}
+			if tt.ops != "" && ops(lines) != tt.ops {
+				t.Errorf("ops = %s, want %s", ops(lines), tt.ops)
+			}
+		})
+	}
+}
+
+func TestLinesRandom(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	randomLines := func() []string {
+		lines := make([]string, r.Intn(30))
+		for i := range lines {
+			lines[i] = strconv.Itoa(r.Intn(5))
+		}
+		return lines
+	}
+	for i := 0; i < 500; i++ {
+		a, b := randomLines(), randomLines()
+		lines := Lines(a, b)
+		gotA, gotB := apply(t, lines)
+		if strings.Join(gotA, "\n") != strings.Join(a, "\n") || strings.Join(gotB, "\n") != strings.Join(b, "\n") {
+			t.Fatalf("Lines(%q, %q) does not reproduce the inputs", a, b)
+		}
+		if edits, want := countEdits(lines), minEdits(a, b); edits != want {
+			t.Fatalf("Lines(%q, %q) edits = %d, want %d", a, b, edits, want)
+		}
+	}
+}
+
+func TestLinesTooManyEdits(t *testing.T) {
+	a := make([]string, maxEdits)
+	b := make([]string, maxEdits)
+	for i := range a {
+		a[i] = "a" + strconv.Itoa(i)
+		b[i] = "b" + strconv.Itoa(i)
+	}
+	a = append([]string{"same"}, a...)
+	b = append([]string{"same"}, b...)
+
+	lines := Lines(a, b)
+	gotA, gotB := apply(t, lines)
+	if !reflect.DeepEqual(gotA, a) || !reflect.DeepEqual(gotB, b) {
+		t.Fatal("fallback does not reproduce the inputs")
+	}
+	// 相同的前缀仍然保留，其余整体删除后插入
+	if want := "=" + strings.Repeat("-", maxEdits) + strings.Repeat("+", maxEdits); ops(lines) != want {
+		t.Errorf("ops = %.10s..., want =---...+++", ops(lines))
+	}
+}
+
+func TestText(t *testing.T) {
+	lines := Text("a\r\nb\r\n", "a\nc\n")
+	if got := ops(lines); got != "=-+" {
+		t.Errorf("ops = %s, want =-+", got)
+	}
+	if lines[0].Text != "a" || lines[1].Text != "b" || lines[2].Text != "c" {
+		t.Errorf("lines = %q %q %q", lines[0].Text, lines[1].Text, lines[2].Text)
+	}
+}
+
+func TestUnified(t *testing.T) {
+	numbered := func(n int) []string {
+		lines := make([]string, n)
+		for i := range lines {
+			lines[i] = strconv.Itoa(i + 1)
+		}
+		return lines
+	}
+	replace := func(lines []string, i int, text string) []string {
+		lines = append([]string{}, lines...)
+		lines[i] = text
+		return lines
+	}
+
+	a := numbered(20)
+	tests := []struct {
+		name  string
+		b     []string
+		hunks []Hunk
+	}{
+		{"no change", a, nil},
+		{"single change", replace(a, 9, "x"), []Hunk{{OldStart: 7, OldLines: 7, NewStart: 7, NewLines: 7}}},
+		{"change at start", replace(a, 0, "x"), []Hunk{{OldStart: 1, OldLines: 4, NewStart: 1, NewLines: 4}}},
+		{"close changes merge", replace(replace(a, 4, "x"), 10, "y"), []Hunk{{OldStart: 2, OldLines: 13, NewStart: 2, NewLines: 13}}},
+		{"far changes split", replace(replace(a, 2, "x"), 15, "y"), []Hunk{
+			{OldStart: 1, OldLines: 6, NewStart: 1, NewLines: 6},
+			{OldStart: 13, OldLines: 7, NewStart: 13, NewLines: 7},
+		}},
+		{"insert at end", append(append([]string{}, a...), "21"), []Hunk{{OldStart: 18, OldLines: 3, NewStart: 18, NewLines: 4}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hunks := Unified(Lines(a, tt.b), 3)
+			if len(hunks) != len(tt.hunks) {
+				t.Fatalf("len(hunks) = %d, want %d", len(hunks), len(tt.hunks))
+			}
+			for i, hunk := range hunks {
+				want := tt.hunks[i]
+				if hunk.OldStart != want.OldStart || hunk.OldLines != want.OldLines ||
+					hunk.NewStart != want.NewStart || hunk.NewLines != want.NewLines {
+					t.Errorf("hunk %d = -%d,%d +%d,%d, want -%d,%d +%d,%d", i,
+						hunk.OldStart, hunk.OldLines, hunk.NewStart, hunk.NewLines,
+						want.OldStart, want.OldLines, want.NewStart, want.NewLines)
+				}
+			}
+		})
+	}
+}
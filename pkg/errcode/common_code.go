@@ -5,9 +5,12 @@ var (
 	ServerError               = NewError(10000000, "服务内部错误")
 	InvalidParams             = NewError(10000001, "入参错误")
 	NotFound                  = NewError(10000002, "找不到")
-	UnauthorizedAuthNotExist  = NewError(10000003, "鉴权失败，找不到对应的AppKey和AppSecret")
+	UnauthorizedAuthNotExist  = NewError(10000003, "鉴权失败，用户名或密码错误")
 	UnauthorizedTokenError    = NewError(10000004, "鉴权失败，Token错误")
 	UnauthorizedTokenTimeout  = NewError(10000005, "鉴权失败，Token超时")
 	UnauthorizedTokenGenerate = NewError(10000006, "鉴权失败，Token生成失败")
 	TooManyRequests           = NewError(10000007, "请求过多")
+	Forbidden                 = NewError(10000008, "没有权限执行该操作")
+	UnauthorizedTokenRevoked  = NewError(10000009, "鉴权失败，Token已失效")
+	UnauthorizedRefreshToken  = NewError(10000010, "鉴权失败，刷新令牌无效")
 )
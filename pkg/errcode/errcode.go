@@ -61,6 +61,8 @@ func (e *Error) StatusCode() int {
 		return http.StatusInternalServerError
 	case InvalidParams.GetCode():
 		return http.StatusBadRequest
+	case NotFound.GetCode():
+		return http.StatusNotFound
 	case UnauthorizedAuthNotExist.GetCode():
 		fallthrough
 	case UnauthorizedTokenError.GetCode():
@@ -68,9 +70,45 @@ func (e *Error) StatusCode() int {
 	case UnauthorizedTokenGenerate.GetCode():
 		fallthrough
 	case UnauthorizedTokenTimeout.GetCode():
+		fallthrough
+	case UnauthorizedTokenRevoked.GetCode():
+		fallthrough
+	case UnauthorizedRefreshToken.GetCode():
 		return http.StatusUnauthorized
+	case Forbidden.GetCode():
+		return http.StatusForbidden
 	case TooManyRequests.GetCode():
 		return http.StatusTooManyRequests
+	case ErrorUploadFileTooLarge.GetCode():
+		return http.StatusRequestEntityTooLarge
+	case ErrorUploadFileMismatch.GetCode():
+		return http.StatusUnsupportedMediaType
+	case ErrorUploadImageInvalid.GetCode():
+		fallthrough
+	case ErrorUploadImageTooBig.GetCode():
+		fallthrough
+	case ErrorUploadTypeInvalid.GetCode():
+		fallthrough
+	case ErrorUploadChunkInvalid.GetCode():
+		fallthrough
+	case ErrorUploadIncomplete.GetCode():
+		fallthrough
+	case ErrorUploadChecksumMismatch.GetCode():
+		return http.StatusBadRequest
+	case ErrorFileSignatureInvalid.GetCode():
+		fallthrough
+	case ErrorUploadQuotaExceeded.GetCode():
+		return http.StatusForbidden
+	case ErrorUploadSessionNotFound.GetCode():
+		fallthrough
+	case ErrorFileNotFound.GetCode():
+		return http.StatusNotFound
+	case ErrorUploadOffsetMismatch.GetCode():
+		fallthrough
+	case ErrorArticleNotEditable.GetCode():
+		fallthrough
+	case ErrorArticleStateTransition.GetCode():
+		return http.StatusConflict
 	}
 
 	return http.StatusInternalServerError
@@ -7,5 +7,41 @@ var (
 	ErrorUpdateTagFail  = NewError(20010003, "更新标签失败")
 	ErrorDeleteTagFail  = NewError(20010004, "删除标签失败")
 	ErrorCountTagFail   = NewError(20010005, "统计标签失败")
-	ErrorUploadFileFail = NewError(20030001, "上传文件失败")
+	ErrorGetTagFail     = NewError(20010006, "获取单个标签失败")
+
+	ErrorGetArticleFail             = NewError(20020001, "获取单篇文章失败")
+	ErrorGetArticleListFail         = NewError(20020002, "获取文章列表失败")
+	ErrorCreateArticleFail          = NewError(20020003, "创建文章失败")
+	ErrorUpdateArticleFail          = NewError(20020004, "更新文章失败")
+	ErrorDeleteArticleFail          = NewError(20020005, "删除文章失败")
+	ErrorCountArticleFail           = NewError(20020006, "统计文章失败")
+	ErrorArticleStateTransition     = NewError(20020007, "不允许的文章状态变更")
+	ErrorUpdateArticleStateFail     = NewError(20020008, "变更文章状态失败")
+	ErrorGetArticleTransitionFail   = NewError(20020009, "获取文章状态变更记录失败")
+	ErrorGetArticleRevisionFail     = NewError(20020010, "获取文章历史版本失败")
+	ErrorRestoreArticleRevisionFail = NewError(20020011, "恢复文章历史版本失败")
+	ErrorGetFeedFail                = NewError(20020012, "获取订阅源失败")
+	ErrorArticleNotEditable         = NewError(20020013, "作者只能修改草稿状态的文章")
+
+	ErrorUploadFileFail         = NewError(20030001, "上传文件失败")
+	ErrorUploadFileTooLarge     = NewError(20030002, "上传文件超出大小限制")
+	ErrorUploadFileMismatch     = NewError(20030003, "上传文件的内容与类型不符")
+	ErrorUploadImageInvalid     = NewError(20030004, "上传的图片无法解析")
+	ErrorUploadImageTooBig      = NewError(20030005, "上传的图片尺寸超出限制")
+	ErrorUploadTypeInvalid      = NewError(20030006, "不支持的上传文件类型")
+	ErrorUploadSessionNotFound  = NewError(20030007, "上传会话不存在或已过期")
+	ErrorUploadChunkInvalid     = NewError(20030008, "上传分片不合法或已损坏")
+	ErrorUploadOffsetMismatch   = NewError(20030009, "上传偏移量与已接收的数据不一致")
+	ErrorUploadIncomplete       = NewError(20030010, "上传的分片不完整")
+	ErrorUploadChecksumMismatch = NewError(20030011, "上传文件的校验和不一致")
+	ErrorCollectFileFail        = NewError(20030012, "回收未引用的文件失败")
+	ErrorFileSignatureInvalid   = NewError(20030013, "文件签名无效或已过期")
+	ErrorFileNotFound           = NewError(20030014, "文件不存在")
+	ErrorGetFileFail            = NewError(20030015, "获取文件失败")
+	ErrorUploadQuotaExceeded    = NewError(20030016, "上传文件超出存储配额")
+	ErrorGetStorageUsageFail    = NewError(20030017, "获取存储用量失败")
+
+	ErrorUserExist        = NewError(20040001, "用户名已存在")
+	ErrorRegisterUserFail = NewError(20040002, "注册用户失败")
+	ErrorUpdateUserFail   = NewError(20040003, "更新用户失败")
 )
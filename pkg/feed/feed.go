@@ -0,0 +1,251 @@
+package feed
+
+import (
+	"bytes"
+	"encoding/xml"
+	"strconv"
+	"time"
+)
+
+const (
+	RSSContentType  = "application/rss+xml; charset=utf-8"
+	AtomContentType = "application/atom+xml; charset=utf-8"
+)
+
+// 与输出格式无关的订阅源，由 RSS 或 Atom 方法输出
+type Feed struct {
+	ID          string // Atom 的 id，使用不会变化的地址
+	Title       string
+	Description string
+	Link        string // 网站地址
+	Self        string // 订阅源自身的地址
+	Updated     time.Time
+	Items       []*Item
+}
+
+type Item struct {
+	ID         string // 不会随标题或别名变化的唯一标识
+	Title      string
+	Link       string
+	Author     string
+	Summary    string
+	Content    string // 渲染后的 HTML，为空时只输出摘要
+	Categories []string
+	Published  time.Time
+	Updated    time.Time
+	Enclosure  *Enclosure
+}
+
+// 附件，用于文章的封面图，Length 未知时为 0
+type Enclosure struct {
+	URL    string
+	Type   string
+	Length int64
+}
+
+// 订阅源的更新时间，为所有条目中最晚的更新时间
+func LastUpdated(items []*Item) time.Time {
+	var updated time.Time
+	for _, item := range items {
+		if item.Updated.After(updated) {
+			updated = item.Updated
+		}
+	}
+
+	return updated
+}
+
+type rss struct {
+	XMLName   xml.Name   `xml:"rss"`
+	Version   string     `xml:"version,attr"`
+	ContentNS string     `xml:"xmlns:content,attr"`
+	DCNS      string     `xml:"xmlns:dc,attr"`
+	AtomNS    string     `xml:"xmlns:atom,attr"`
+	Channel   rssChannel `xml:"channel"`
+}
+
+type rssChannel struct {
+	Title         string     `xml:"title"`
+	Link          string     `xml:"link"`
+	Description   string     `xml:"description"`
+	AtomLink      *atomLink  `xml:"atom:link,omitempty"`
+	LastBuildDate string     `xml:"lastBuildDate,omitempty"`
+	Items         []*rssItem `xml:"item"`
+}
+
+type rssItem struct {
+	Title       string        `xml:"title"`
+	Link        string        `xml:"link"`
+	GUID        rssGUID       `xml:"guid"`
+	Creator     string        `xml:"dc:creator,omitempty"` // RSS 的 author 要求是邮箱，作者名使用 dc:creator
+	Categories  []string      `xml:"category"`
+	Description string        `xml:"description"`
+	Content     *cdata        `xml:"content:encoded,omitempty"`
+	PubDate     string        `xml:"pubDate"`
+	Enclosure   *rssEnclosure `xml:"enclosure,omitempty"`
+}
+
+type rssGUID struct {
+	Value       string `xml:",chardata"`
+	IsPermaLink bool   `xml:"isPermaLink,attr"`
+}
+
+type rssEnclosure struct {
+	URL    string `xml:"url,attr"`
+	Length string `xml:"length,attr"`
+	Type   string `xml:"type,attr"`
+}
+
+type cdata struct {
+	Value string `xml:",cdata"`
+}
+
+// 输出 RSS 2.0，完整内容放在 content:encoded 中
+func (f *Feed) RSS() ([]byte, error) {
+	channel := rssChannel{
+		Title:       f.Title,
+		Link:        f.Link,
+		Description: f.Description,
+	}
+	if f.Self != "" {
+		channel.AtomLink = &atomLink{Href: f.Self, Rel: "self", Type: "application/rss+xml"}
+	}
+	if !f.Updated.IsZero() {
+		channel.LastBuildDate = f.Updated.UTC().Format(time.RFC1123Z)
+	}
+	for _, item := range f.Items {
+		ri := &rssItem{
+			Title:       item.Title,
+			Link:        item.Link,
+			GUID:        rssGUID{Value: item.ID},
+			Creator:     item.Author,
+			Categories:  item.Categories,
+			Description: item.Summary,
+			PubDate:     item.Published.UTC().Format(time.RFC1123Z),
+		}
+		if item.Content != "" {
+			ri.Content = &cdata{Value: item.Content}
+		}
+		if item.Enclosure != nil {
+			ri.Enclosure = &rssEnclosure{
+				URL:    item.Enclosure.URL,
+				Length: strconv.FormatInt(item.Enclosure.Length, 10),
+				Type:   item.Enclosure.Type,
+			}
+		}
+		channel.Items = append(channel.Items, ri)
+	}
+
+	return marshal(&rss{
+		Version:   "2.0",
+		ContentNS: "http://purl.org/rss/1.0/modules/content/",
+		DCNS:      "http://purl.org/dc/elements/1.1/",
+		AtomNS:    "http://www.w3.org/2005/Atom",
+		Channel:   channel,
+	})
+}
+
+type atom struct {
+	XMLName  xml.Name     `xml:"http://www.w3.org/2005/Atom feed"`
+	ID       string       `xml:"id"`
+	Title    string       `xml:"title"`
+	Subtitle string       `xml:"subtitle,omitempty"`
+	Updated  string       `xml:"updated"`
+	Links    []*atomLink  `xml:"link"`
+	Entries  []*atomEntry `xml:"entry"`
+}
+
+type atomLink struct {
+	Href   string `xml:"href,attr"`
+	Rel    string `xml:"rel,attr,omitempty"`
+	Type   string `xml:"type,attr,omitempty"`
+	Length string `xml:"length,attr,omitempty"`
+}
+
+type atomEntry struct {
+	ID         string          `xml:"id"`
+	Title      string          `xml:"title"`
+	Links      []*atomLink     `xml:"link"`
+	Author     *atomAuthor     `xml:"author,omitempty"`
+	Categories []*atomCategory `xml:"category"`
+	Published  string          `xml:"published"`
+	Updated    string          `xml:"updated"`
+	Summary    *atomText       `xml:"summary,omitempty"`
+	Content    *atomText       `xml:"content,omitempty"`
+}
+
+type atomAuthor struct {
+	Name string `xml:"name"`
+}
+
+type atomCategory struct {
+	Term string `xml:"term,attr"`
+}
+
+type atomText struct {
+	Type  string `xml:"type,attr"`
+	Value string `xml:",chardata"`
+}
+
+// 输出 Atom 1.0，条目的作者为空时使用 unknown，满足 Atom 要求每个条目都有作者
+func (f *Feed) Atom() ([]byte, error) {
+	feed := atom{
+		ID:       f.ID,
+		Title:    f.Title,
+		Subtitle: f.Description,
+		Updated:  atomTime(f.Updated),
+		Links:    []*atomLink{{Href: f.Link, Rel: "alternate", Type: "text/html"}},
+	}
+	if f.Self != "" {
+		feed.Links = append(feed.Links, &atomLink{Href: f.Self, Rel: "self", Type: "application/atom+xml"})
+	}
+	for _, item := range f.Items {
+		author := item.Author
+		if author == "" {
+			author = "unknown"
+		}
+		entry := &atomEntry{
+			ID:        item.ID,
+			Title:     item.Title,
+			Links:     []*atomLink{{Href: item.Link, Rel: "alternate", Type: "text/html"}},
+			Author:    &atomAuthor{Name: author},
+			Published: atomTime(item.Published),
+			Updated:   atomTime(item.Updated),
+		}
+		for _, category := range item.Categories {
+			entry.Categories = append(entry.Categories, &atomCategory{Term: category})
+		}
+		if item.Summary != "" {
+			entry.Summary = &atomText{Type: "text", Value: item.Summary}
+		}
+		if item.Content != "" {
+			entry.Content = &atomText{Type: "html", Value: item.Content}
+		}
+		if item.Enclosure != nil {
+			link := &atomLink{Href: item.Enclosure.URL, Rel: "enclosure", Type: item.Enclosure.Type}
+			if item.Enclosure.Length > 0 {
+				link.Length = strconv.FormatInt(item.Enclosure.Length, 10)
+			}
+			entry.Links = append(entry.Links, link)
+		}
+		feed.Entries = append(feed.Entries, entry)
+	}
+
+	return marshal(&feed)
+}
+
+func atomTime(t time.Time) string {
+	return t.UTC().Format(time.RFC3339)
+}
+
+func marshal(v interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteString(xml.Header)
+	enc := xml.NewEncoder(&buf)
+	enc.Indent("", "  ")
+	if err := enc.Encode(v); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
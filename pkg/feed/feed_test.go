@@ -0,0 +1,279 @@
+package feed
+
+import (
+	"bytes"
+	"encoding/xml"
+	"strings"
+	"testing"
+	"time"
+)
+
+var (
+	testPublished = time.Date(2021, 3, 4, 5, 6, 7, 0, time.FixedZone("CST", 8*3600))
+	testUpdated   = time.Date(2021, 3, 5, 0, 0, 0, 0, time.UTC)
+)
+
+func newTestFeed() *Feed {
+	return &Feed{
+		ID:          "https://api.example.com/feed.atom",
+		Title:       "Blog",
+		Description: "最新文章",
+		Link:        "https://example.com",
+		Self:        "https://api.example.com/feed.rss",
+		Updated:     testUpdated,
+		Items: []*Item{
+			{
+				ID:         "https://api.example.com/api/v1/public/articles/1",
+				Title:      "Go & XML",
+				Link:       "https://example.com/articles/go-xml",
+				Author:     "alice",
+				Summary:    "summary <b>",
+				Content:    "<p>a ]]> b</p>",
+				Categories: []string{"go", "xml"},
+				Published:  testPublished,
+				Updated:    testUpdated,
+				Enclosure:  &Enclosure{URL: "https://example.com/static/cover.png", Type: "image/png", Length: 1234},
+			},
+			{
+				ID:        "https://api.example.com/api/v1/public/articles/2",
+				Title:     "No content",
+				Link:      "https://example.com/articles/2",
+				Summary:   "summary",
+				Published: testPublished,
+				Updated:   testPublished,
+				Enclosure: &Enclosure{URL: "https://example.com/cover.jpg", Type: "image/jpeg"},
+			},
+		},
+	}
+}
+
+// 只用于解析输出的结构，带命名空间的元素按命名空间地址匹配
+type parsedRSS struct {
+	XMLName xml.Name `xml:"rss"`
+	Version string   `xml:"version,attr"`
+	Channel struct {
+		Title string `xml:"title"`
+		// 不带命名空间的字段也会匹配 atom:link，按元素的命名空间区分
+		Links []struct {
+			XMLName xml.Name
+			Href    string `xml:"href,attr"`
+			Rel     string `xml:"rel,attr"`
+			Value   string `xml:",chardata"`
+		} `xml:"link"`
+		LastBuildDate string `xml:"lastBuildDate"`
+		Items         []struct {
+			Title       string   `xml:"title"`
+			Link        string   `xml:"link"`
+			GUID        string   `xml:"guid"`
+			Creator     string   `xml:"http://purl.org/dc/elements/1.1/ creator"`
+			Categories  []string `xml:"category"`
+			Description string   `xml:"description"`
+			Content     *string  `xml:"http://purl.org/rss/1.0/modules/content/ encoded"`
+			PubDate     string   `xml:"pubDate"`
+			Enclosure   struct {
+				URL    string `xml:"url,attr"`
+				Length string `xml:"length,attr"`
+				Type   string `xml:"type,attr"`
+			} `xml:"enclosure"`
+		} `xml:"item"`
+	} `xml:"channel"`
+}
+
+func TestRSS(t *testing.T) {
+	body, err := newTestFeed().RSS()
+	if err != nil {
+		t.Fatalf("RSS err: %v", err)
+	}
+	if !bytes.HasPrefix(body, []byte(xml.Header)) {
+		t.Errorf("RSS does not start with the XML header: %q", body[:40])
+	}
+	for _, s := range []string{
+		`xmlns:content="http://purl.org/rss/1.0/modules/content/"`,
+		`xmlns:dc="http://purl.org/dc/elements/1.1/"`,
+		`xmlns:atom="http://www.w3.org/2005/Atom"`,
+		`<guid isPermaLink="false">`,
+		// CDATA 中的 ]]> 需要拆开
+		`<content:encoded><![CDATA[<p>a ]]]]><![CDATA[> b</p>]]></content:encoded>`,
+	} {
+		if !bytes.Contains(body, []byte(s)) {
+			t.Errorf("RSS does not contain %q:\n%s", s, body)
+		}
+	}
+
+	var got parsedRSS
+	if err := xml.Unmarshal(body, &got); err != nil {
+		t.Fatalf("Unmarshal err: %v", err)
+	}
+	channel := got.Channel
+	if got.Version != "2.0" || channel.Title != "Blog" || len(channel.Links) != 2 {
+		t.Fatalf("channel = %q %q %+v", got.Version, channel.Title, channel.Links)
+	}
+	if link := channel.Links[0]; link.XMLName.Space != "" || link.Value != "https://example.com" {
+		t.Errorf("link = %+v", link)
+	}
+	if link := channel.Links[1]; link.XMLName.Space != "http://www.w3.org/2005/Atom" ||
+		link.Href != "https://api.example.com/feed.rss" || link.Rel != "self" {
+		t.Errorf("atom:link = %+v", link)
+	}
+	if want := "Fri, 05 Mar 2021 00:00:00 +0000"; channel.LastBuildDate != want {
+		t.Errorf("lastBuildDate = %q, want %q", channel.LastBuildDate, want)
+	}
+	if len(channel.Items) != 2 {
+		t.Fatalf("got %d items, want 2", len(channel.Items))
+	}
+
+	first, second := channel.Items[0], channel.Items[1]
+	if first.Title != "Go & XML" || first.Creator != "alice" || first.Description != "summary <b>" {
+		t.Errorf("item = %q %q %q", first.Title, first.Creator, first.Description)
+	}
+	if strings.Join(first.Categories, ",") != "go,xml" {
+		t.Errorf("categories = %v", first.Categories)
+	}
+	if first.Content == nil || *first.Content != "<p>a ]]> b</p>" {
+		t.Errorf("content:encoded = %v", first.Content)
+	}
+	// 发布时间转换为 UTC
+	if want := "Wed, 03 Mar 2021 21:06:07 +0000"; first.PubDate != want {
+		t.Errorf("pubDate = %q, want %q", first.PubDate, want)
+	}
+	if _, err := time.Parse(time.RFC1123Z, first.PubDate); err != nil {
+		t.Errorf("pubDate is not RFC1123Z: %v", err)
+	}
+	if e := first.Enclosure; e.URL != "https://example.com/static/cover.png" || e.Length != "1234" || e.Type != "image/png" {
+		t.Errorf("enclosure = %+v", e)
+	}
+
+	// 没有内容时不输出 content:encoded，大小未知的附件 length 为 0
+	if second.Content != nil {
+		t.Errorf("content:encoded = %q, want none", *second.Content)
+	}
+	if second.Creator != "" {
+		t.Errorf("dc:creator = %q, want none", second.Creator)
+	}
+	if e := second.Enclosure; e.Length != "0" || e.Type != "image/jpeg" {
+		t.Errorf("enclosure = %+v", e)
+	}
+}
+
+type parsedAtomLink struct {
+	Href   string `xml:"href,attr"`
+	Rel    string `xml:"rel,attr"`
+	Type   string `xml:"type,attr"`
+	Length string `xml:"length,attr"`
+}
+
+type parsedAtom struct {
+	XMLName xml.Name         `xml:"http://www.w3.org/2005/Atom feed"`
+	ID      string           `xml:"id"`
+	Title   string           `xml:"title"`
+	Updated string           `xml:"updated"`
+	Links   []parsedAtomLink `xml:"link"`
+	Entries []struct {
+		ID         string           `xml:"id"`
+		Title      string           `xml:"title"`
+		Links      []parsedAtomLink `xml:"link"`
+		Author     string           `xml:"author>name"`
+		Categories []struct {
+			Term string `xml:"term,attr"`
+		} `xml:"category"`
+		Published string `xml:"published"`
+		Updated   string `xml:"updated"`
+		Summary   *struct {
+			Type  string `xml:"type,attr"`
+			Value string `xml:",chardata"`
+		} `xml:"summary"`
+		Content *struct {
+			Type  string `xml:"type,attr"`
+			Value string `xml:",chardata"`
+		} `xml:"content"`
+	} `xml:"entry"`
+}
+
+func TestAtom(t *testing.T) {
+	body, err := newTestFeed().Atom()
+	if err != nil {
+		t.Fatalf("Atom err: %v", err)
+	}
+	if !bytes.Contains(body, []byte(`<feed xmlns="http://www.w3.org/2005/Atom">`)) {
+		t.Errorf("Atom does not declare the default namespace:\n%s", body)
+	}
+
+	var got parsedAtom
+	if err := xml.Unmarshal(body, &got); err != nil {
+		t.Fatalf("Unmarshal err: %v", err)
+	}
+	if got.ID != "https://api.example.com/feed.atom" || got.Title != "Blog" {
+		t.Errorf("feed = %q %q", got.ID, got.Title)
+	}
+	if want := "2021-03-05T00:00:00Z"; got.Updated != want {
+		t.Errorf("updated = %q, want %q", got.Updated, want)
+	}
+	wantLinks := []parsedAtomLink{
+		{Href: "https://example.com", Rel: "alternate", Type: "text/html"},
+		{Href: "https://api.example.com/feed.rss", Rel: "self", Type: "application/atom+xml"},
+	}
+	if !equalLinks(got.Links, wantLinks) {
+		t.Errorf("links = %+v, want %+v", got.Links, wantLinks)
+	}
+	if len(got.Entries) != 2 {
+		t.Fatalf("got %d entries, want 2", len(got.Entries))
+	}
+
+	first, second := got.Entries[0], got.Entries[1]
+	if first.Author != "alice" || len(first.Categories) != 2 || first.Categories[1].Term != "xml" {
+		t.Errorf("entry = %q %+v", first.Author, first.Categories)
+	}
+	if want := "2021-03-03T21:06:07Z"; first.Published != want {
+		t.Errorf("published = %q, want %q", first.Published, want)
+	}
+	if _, err := time.Parse(time.RFC3339, first.Updated); err != nil {
+		t.Errorf("updated is not RFC3339: %v", err)
+	}
+	if first.Summary == nil || first.Summary.Type != "text" || first.Summary.Value != "summary <b>" {
+		t.Errorf("summary = %+v", first.Summary)
+	}
+	if first.Content == nil || first.Content.Type != "html" || first.Content.Value != "<p>a ]]> b</p>" {
+		t.Errorf("content = %+v", first.Content)
+	}
+	wantLinks = []parsedAtomLink{
+		{Href: "https://example.com/articles/go-xml", Rel: "alternate", Type: "text/html"},
+		{Href: "https://example.com/static/cover.png", Rel: "enclosure", Type: "image/png", Length: "1234"},
+	}
+	if !equalLinks(first.Links, wantLinks) {
+		t.Errorf("links = %+v, want %+v", first.Links, wantLinks)
+	}
+
+	// 作者为空时使用 unknown，大小未知的附件不输出 length
+	if second.Author != "unknown" {
+		t.Errorf("author = %q, want unknown", second.Author)
+	}
+	if second.Content != nil {
+		t.Errorf("content = %+v, want none", second.Content)
+	}
+	if len(second.Links) != 2 || second.Links[1].Length != "" {
+		t.Errorf("links = %+v", second.Links)
+	}
+}
+
+func TestLastUpdated(t *testing.T) {
+	items := newTestFeed().Items
+	if got := LastUpdated(items); !got.Equal(testUpdated) {
+		t.Errorf("LastUpdated = %v, want %v", got, testUpdated)
+	}
+	if got := LastUpdated(nil); !got.IsZero() {
+		t.Errorf("LastUpdated(nil) = %v, want zero", got)
+	}
+}
+
+func equalLinks(got, want []parsedAtomLink) bool {
+	if len(got) != len(want) {
+		return false
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			return false
+		}
+	}
+
+	return true
+}
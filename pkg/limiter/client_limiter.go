@@ -0,0 +1,122 @@
+package limiter
+
+import (
+	"container/list"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/juju/ratelimit"
+)
+
+// 最多保存的令牌桶数量，超过时删除最久没有使用的令牌桶
+const maxClientBuckets = 10000
+
+// 按客户端 IP 限流，每个客户端在每个路由上使用独立的令牌桶
+// 规则的 Key 为路由路径，例如 /api/v1/public/articles/:id，没有单独配置的路由使用默认规则
+// 客户端 IP 取自 gin 的 ClientIP，只有配置为可信的代理设置的 X-Forwarded-For 才会被采用
+type ClientLimiter struct {
+	mu          sync.Mutex
+	defaultRule LimiterBucketRule
+	rules       map[string]LimiterBucketRule
+	maxBuckets  int
+	buckets     map[string]*list.Element
+	lru         *list.List // 元素为 *clientBucket，最近使用的在前
+}
+
+type clientBucket struct {
+	key      string
+	bucket   *ratelimit.Bucket
+	refill   time.Duration // 从空到装满需要的时间，空闲超过该时间的令牌桶与新建的没有区别
+	lastUsed time.Time
+}
+
+func NewClientLimiter(defaultRule LimiterBucketRule) LimiterIface {
+	return newClientLimiter(defaultRule, maxClientBuckets)
+}
+
+func newClientLimiter(defaultRule LimiterBucketRule, maxBuckets int) *ClientLimiter {
+	return &ClientLimiter{
+		defaultRule: defaultRule,
+		rules:       make(map[string]LimiterBucketRule),
+		maxBuckets:  maxBuckets,
+		buckets:     make(map[string]*list.Element),
+		lru:         list.New(),
+	}
+}
+
+func (l *ClientLimiter) Key(c *gin.Context) string {
+	return c.FullPath() + " " + c.ClientIP()
+}
+
+// 令牌桶在客户端第一次访问时创建，每次获取时先删除已经空闲到装满的令牌桶
+func (l *ClientLimiter) GetBucket(key string) (*ratelimit.Bucket, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	l.evictIdle(now)
+	if e, ok := l.buckets[key]; ok {
+		b := e.Value.(*clientBucket)
+		b.lastUsed = now
+		l.lru.MoveToFront(e)
+		return b.bucket, true
+	}
+	if l.lru.Len() >= l.maxBuckets {
+		l.remove(l.lru.Back())
+	}
+
+	rule := l.defaultRule
+	if i := strings.LastIndexByte(key, ' '); i >= 0 {
+		if r, ok := l.rules[key[:i]]; ok {
+			rule = r
+		}
+	}
+	b := &clientBucket{
+		key:      key,
+		bucket:   ratelimit.NewBucketWithQuantum(rule.FillInterval, rule.Capacity, rule.Quantum),
+		refill:   refillDuration(rule),
+		lastUsed: now,
+	}
+	l.buckets[key] = l.lru.PushFront(b)
+
+	return b.bucket, true
+}
+
+func (l *ClientLimiter) AddBuckets(rules ...LimiterBucketRule) LimiterIface {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	for _, rule := range rules {
+		l.rules[rule.Key] = rule
+	}
+
+	return l
+}
+
+// 从最久没有使用的令牌桶开始删除已经空闲到装满的令牌桶，遇到仍需保留的令牌桶时停止
+// 不同规则的装满时间不同，停止位置之后可能还有可以删除的令牌桶，留给之后的调用或数量上限处理
+func (l *ClientLimiter) evictIdle(now time.Time) {
+	for e := l.lru.Back(); e != nil; e = l.lru.Back() {
+		b := e.Value.(*clientBucket)
+		if now.Sub(b.lastUsed) < b.refill {
+			return
+		}
+		l.remove(e)
+	}
+}
+
+func (l *ClientLimiter) remove(e *list.Element) {
+	delete(l.buckets, e.Value.(*clientBucket).key)
+	l.lru.Remove(e)
+}
+
+func refillDuration(rule LimiterBucketRule) time.Duration {
+	quantum := rule.Quantum
+	if quantum <= 0 {
+		quantum = 1
+	}
+
+	return time.Duration((rule.Capacity+quantum-1)/quantum) * rule.FillInterval
+}
@@ -0,0 +1,87 @@
+package limiter
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+var testRule = LimiterBucketRule{FillInterval: time.Hour, Capacity: 2, Quantum: 1}
+
+func TestClientLimiterGetBucket(t *testing.T) {
+	l := newClientLimiter(testRule, 10)
+	l.AddBuckets(LimiterBucketRule{Key: "/feed.rss", FillInterval: time.Hour, Capacity: 5, Quantum: 1})
+
+	a, _ := l.GetBucket("/articles 1.1.1.1")
+	if again, _ := l.GetBucket("/articles 1.1.1.1"); again != a {
+		t.Error("same client gets a different bucket")
+	}
+	if other, _ := l.GetBucket("/articles 2.2.2.2"); other == a {
+		t.Error("different clients share a bucket")
+	}
+	if got := a.Capacity(); got != 2 {
+		t.Errorf("default capacity = %d, want 2", got)
+	}
+	if feed, _ := l.GetBucket("/feed.rss 1.1.1.1"); feed.Capacity() != 5 {
+		t.Errorf("rule capacity = %d, want 5", feed.Capacity())
+	}
+}
+
+func TestClientLimiterEvictsLeastRecentlyUsed(t *testing.T) {
+	l := newClientLimiter(testRule, 3)
+	a, _ := l.GetBucket("/ a")
+	a.TakeAvailable(2)
+	b, _ := l.GetBucket("/ b")
+	l.GetBucket("/ c")
+	// a 最近使用过，超过上限时删除 b
+	l.GetBucket("/ a")
+	l.GetBucket("/ d")
+
+	if l.lru.Len() != 3 || len(l.buckets) != 3 {
+		t.Fatalf("buckets = %d/%d, want 3", l.lru.Len(), len(l.buckets))
+	}
+	if got, _ := l.GetBucket("/ a"); got != a || got.Available() != 0 {
+		t.Error("recently used bucket is evicted")
+	}
+	if got, _ := l.GetBucket("/ b"); got == b {
+		t.Error("least recently used bucket is kept")
+	}
+}
+
+func TestClientLimiterBounded(t *testing.T) {
+	l := newClientLimiter(testRule, 100)
+	for i := 0; i < 1000; i++ {
+		l.GetBucket(fmt.Sprintf("/ 10.0.%d.%d", i/256, i%256))
+	}
+	if l.lru.Len() != 100 || len(l.buckets) != 100 {
+		t.Errorf("buckets = %d/%d, want 100", l.lru.Len(), len(l.buckets))
+	}
+}
+
+func TestClientLimiterEvictsIdleBuckets(t *testing.T) {
+	l := newClientLimiter(LimiterBucketRule{FillInterval: time.Millisecond, Capacity: 2, Quantum: 1}, 10)
+	l.GetBucket("/ a")
+	l.GetBucket("/ b")
+	time.Sleep(5 * time.Millisecond)
+	l.GetBucket("/ c")
+
+	if l.lru.Len() != 1 || l.buckets["/ c"] == nil {
+		t.Errorf("buckets = %d, want only the new bucket", l.lru.Len())
+	}
+}
+
+func TestRefillDuration(t *testing.T) {
+	tests := []struct {
+		rule LimiterBucketRule
+		want time.Duration
+	}{
+		{LimiterBucketRule{FillInterval: time.Second, Capacity: 20, Quantum: 10}, 2 * time.Second},
+		{LimiterBucketRule{FillInterval: time.Second, Capacity: 5, Quantum: 2}, 3 * time.Second},
+		{LimiterBucketRule{FillInterval: time.Second, Capacity: 3, Quantum: 0}, 3 * time.Second},
+	}
+	for _, tt := range tests {
+		if got := refillDuration(tt.rule); got != tt.want {
+			t.Errorf("refillDuration(%+v) = %v, want %v", tt.rule, got, tt.want)
+		}
+	}
+}
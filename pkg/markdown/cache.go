@@ -0,0 +1,100 @@
+package markdown
+
+import (
+	"container/list"
+	"crypto/sha256"
+	"sync"
+)
+
+// 未调用 SetupCache 时缓存的渲染结果数量
+const defaultCacheSize = 1000
+
+var cache = NewCache(defaultCacheSize)
+
+// 按内容哈希缓存渲染结果，超出容量时淘汰最久未使用的结果
+// 相同内容的渲染结果总是相同的，因此不需要在内容修改时主动失效
+type Cache struct {
+	mu    sync.Mutex
+	size  int
+	ll    *list.List
+	items map[[sha256.Size]byte]*list.Element
+}
+
+type cacheEntry struct {
+	key    [sha256.Size]byte
+	result *Result
+}
+
+// size 不大于 0 时不缓存
+func NewCache(size int) *Cache {
+	return &Cache{
+		size:  size,
+		ll:    list.New(),
+		items: make(map[[sha256.Size]byte]*list.Element),
+	}
+}
+
+// 设置全局缓存的容量，已缓存的结果会被丢弃
+func SetupCache(size int) {
+	cache = NewCache(size)
+}
+
+// 使用全局缓存渲染，返回的结果会被多个调用方共享，不能修改
+func RenderCached(source string) (*Result, error) {
+	return cache.Render(source)
+}
+
+func (c *Cache) Render(source string) (*Result, error) {
+	if c.size <= 0 {
+		return Render(source)
+	}
+
+	key := sha256.Sum256([]byte(source))
+	if result, ok := c.get(key); ok {
+		return result, nil
+	}
+	// 渲染不加锁，并发渲染相同内容时结果相同，重复写入没有影响
+	result, err := Render(source)
+	if err != nil {
+		return nil, err
+	}
+	c.add(key, result)
+
+	return result, nil
+}
+
+func (c *Cache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.ll.Len()
+}
+
+func (c *Cache) get(key [sha256.Size]byte) (*Result, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	e, ok := c.items[key]
+	if !ok {
+		return nil, false
+	}
+	c.ll.MoveToFront(e)
+
+	return e.Value.(*cacheEntry).result, true
+}
+
+func (c *Cache) add(key [sha256.Size]byte, result *Result) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if e, ok := c.items[key]; ok {
+		c.ll.MoveToFront(e)
+		return
+	}
+	c.items[key] = c.ll.PushFront(&cacheEntry{key: key, result: result})
+	for c.ll.Len() > c.size {
+		oldest := c.ll.Back()
+		c.ll.Remove(oldest)
+		delete(c.items, oldest.Value.(*cacheEntry).key)
+	}
+}
@@ -0,0 +1,125 @@
+package markdown
+
+import (
+	"bytes"
+	"strconv"
+	"unicode"
+
+	chromahtml "github.com/alecthomas/chroma/formatters/html"
+	"github.com/yuin/goldmark"
+	highlighting "github.com/yuin/goldmark-highlighting"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/extension"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/text"
+)
+
+// 目录中的一个标题，ID 与渲染结果中标题的 id 一致，可以用作锚点
+type Heading struct {
+	Level int    `json:"level"`
+	Title string `json:"title"`
+	ID    string `json:"id"`
+}
+
+// 渲染结果，HTML 已按白名单过滤，可以直接插入页面
+type Result struct {
+	HTML string     `json:"html"`
+	TOC  []*Heading `json:"toc"`
+}
+
+// 支持 CommonMark 和 GFM 的表格、删除线、任务列表和自动链接
+// 代码块按语言高亮，使用 chroma 的 class 输出，样式由前端提供
+// 不渲染原始 HTML，过滤后的结果中也不会保留
+var md = goldmark.New(
+	goldmark.WithExtensions(
+		extension.NewTable(extension.WithTableCellAlignMethod(extension.TableCellAlignAttribute)),
+		extension.Strikethrough,
+		extension.TaskList,
+		extension.Linkify,
+		highlighting.NewHighlighting(
+			highlighting.WithFormatOptions(chromahtml.WithClasses(true)),
+		),
+	),
+	goldmark.WithParserOptions(parser.WithAutoHeadingID()),
+)
+
+// 将 Markdown 渲染为过滤后的 HTML，并提取标题目录
+func Render(source string) (*Result, error) {
+	src := []byte(source)
+	ctx := parser.NewContext(parser.WithIDs(newHeadingIDs()))
+	doc := md.Parser().Parse(text.NewReader(src), parser.WithContext(ctx))
+
+	var buf bytes.Buffer
+	if err := md.Renderer().Render(&buf, src, doc); err != nil {
+		return nil, err
+	}
+
+	return &Result{
+		HTML: sanitize(buf.Bytes()),
+		TOC:  tableOfContents(doc, src),
+	}, nil
+}
+
+func tableOfContents(doc ast.Node, source []byte) []*Heading {
+	toc := []*Heading{}
+	_ = ast.Walk(doc, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		heading, ok := n.(*ast.Heading)
+		if !ok || !entering {
+			return ast.WalkContinue, nil
+		}
+		id, _ := heading.AttributeString("id")
+		idBytes, _ := id.([]byte)
+		toc = append(toc, &Heading{
+			Level: heading.Level,
+			Title: string(heading.Text(source)),
+			ID:    string(idBytes),
+		})
+
+		return ast.WalkSkipChildren, nil
+	})
+
+	return toc
+}
+
+// 按标题文字生成锚点，保留中文等各类文字，重复的锚点追加序号
+type headingIDs struct {
+	values map[string]bool
+}
+
+func newHeadingIDs() *headingIDs {
+	return &headingIDs{values: make(map[string]bool)}
+}
+
+func (s *headingIDs) Generate(value []byte, kind ast.NodeKind) []byte {
+	var b bytes.Buffer
+	dash := false
+	for _, r := range string(bytes.TrimSpace(value)) {
+		switch {
+		case unicode.IsLetter(r) || unicode.IsNumber(r):
+			b.WriteRune(unicode.ToLower(r))
+			dash = false
+		case r == '-' || r == '_':
+			b.WriteRune(r)
+			dash = false
+		case unicode.IsSpace(r) && !dash && b.Len() > 0:
+			b.WriteByte('-')
+			dash = true
+		}
+	}
+	id := string(bytes.TrimRight(b.Bytes(), "-"))
+	if id == "" {
+		id = "heading"
+	}
+
+	unique := id
+	for i := 1; s.values[unique]; i++ {
+		unique = id + "-" + strconv.Itoa(i)
+	}
+	s.values[unique] = true
+
+	return []byte(unique)
+}
+
+func (s *headingIDs) Put(value []byte) {
+	s.values[string(value)] = true
+}
@@ -0,0 +1,206 @@
+package markdown
+
+import (
+	"crypto/sha256"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestRender(t *testing.T) {
+	tests := []struct {
+		name    string
+		source  string
+		want    []string
+		notWant []string
+	}{
+		{"javascript link", "[x](javascript:alert(1))", []string{"<p>x</p>"}, []string{"<a", "javascript"}},
+		{"data link", "[x](data:text/html;base64,PHNjcmlwdD4=)", []string{"<p>x</p>"}, []string{"<a", "data:"}},
+		{"data image", "![x](data:image/svg+xml;base64,PHN2Zz4=)", nil, []string{"src=", "data:"}},
+		{"raw script", "<script>alert(1)</script>", nil, []string{"<script", "alert"}},
+		{"raw img onerror", "<img src=x onerror=alert(1)>", nil, []string{"<img", "onerror"}},
+		{"raw event attribute", `<a href="/" onclick="alert(1)">x</a>`, []string{"x"}, []string{"<a", "onclick"}},
+		{"external link", "[x](https://example.com)", []string{`<a href="https://example.com" rel="nofollow">x</a>`}, nil},
+		{"relative link", "[x](/a)", []string{`href="/a"`}, nil},
+		{"image", `![a](x.png "t")`, []string{`<img src="x.png" alt="a" title="t">`}, nil},
+		{"autolink", "see https://go.dev", []string{`<a href="https://go.dev" rel="nofollow">https://go.dev</a>`}, nil},
+		{"strikethrough", "~~old~~", []string{"<del>old</del>"}, nil},
+		{
+			"table",
+			"| a | b |\n|:-|-:|\n| 1 | 2 |",
+			[]string{"<table>", "<thead>", `<th align="left">a</th>`, `<th align="right">b</th>`, "<tbody>", `<td align="left">1</td>`},
+			nil,
+		},
+		{
+			"task list",
+			"- [x] done\n- [ ] todo",
+			[]string{`<input checked="" disabled="" type="checkbox"> done`, `<input disabled="" type="checkbox"> todo`},
+			nil,
+		},
+		{
+			"highlighted code",
+			"```go\nfunc main() {}\n```",
+			[]string{`<pre class="chroma">`, `<span class="kd">func</span>`, `<span class="nf">main</span>`},
+			[]string{"style="},
+		},
+		{"escaped code", "`<script>`", []string{"<code>&lt;script&gt;</code>"}, []string{"<script"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := Render(tt.source)
+			if err != nil {
+				t.Fatalf("Render err: %v", err)
+			}
+			for _, s := range tt.want {
+				if !strings.Contains(result.HTML, s) {
+					t.Errorf("Render(%q) = %q, want it to contain %q", tt.source, result.HTML, s)
+				}
+			}
+			for _, s := range tt.notWant {
+				if strings.Contains(result.HTML, s) {
+					t.Errorf("Render(%q) = %q, want it not to contain %q", tt.source, result.HTML, s)
+				}
+			}
+		})
+	}
+}
+
+// 渲染器不输出原始 HTML，直接检查白名单本身
+func TestSanitize(t *testing.T) {
+	tests := []struct {
+		name string
+		html string
+		want string
+	}{
+		{"script", "<p>a<script>alert(1)</script></p>", "<p>a</p>"},
+		{"img onerror", `<img src="x.png" onerror="alert(1)">`, `<img src="x.png">`},
+		{"event attribute", `<p onclick="alert(1)">a</p>`, "<p>a</p>"},
+		{"javascript href", `<a href="javascript:alert(1)">a</a>`, "a"},
+		{"data src", `<img src="data:image/png;base64,AAAA">`, ""},
+		{"style", `<span style="color:red">a</span>`, "<span>a</span>"},
+		{"iframe", `<iframe src="https://example.com"></iframe>`, ""},
+		{"invalid heading id", `<h2 id="a&quot; onmouseover=&quot;x">a</h2>`, "<h2>a</h2>"},
+		{"invalid class", `<span class="a;b">a</span>`, "<span>a</span>"},
+		{"non checkbox input", `<input type="text" value="a">`, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := sanitize([]byte(tt.html)); got != tt.want {
+				t.Errorf("sanitize(%q) = %q, want %q", tt.html, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRenderHeadings(t *testing.T) {
+	tests := []struct {
+		name   string
+		source string
+		want   []*Heading
+	}{
+		{"simple", "# Hello World", []*Heading{{1, "Hello World", "hello-world"}}},
+		{
+			"duplicate",
+			"# Intro\n## Intro\n### Intro",
+			[]*Heading{{1, "Intro", "intro"}, {2, "Intro", "intro-1"}, {3, "Intro", "intro-2"}},
+		},
+		{"cjk", "## 中文 标题", []*Heading{{2, "中文 标题", "中文-标题"}}},
+		{"duplicate cjk", "# 简介\n# 简介", []*Heading{{1, "简介", "简介"}, {1, "简介", "简介-1"}}},
+		{"punctuation", "# Go, 1.17!", []*Heading{{1, "Go, 1.17!", "go-117"}}},
+		{"no letters", "# !!!\n# ???", []*Heading{{1, "!!!", "heading"}, {1, "???", "heading-1"}}},
+		{"inline markup", "# Use `go vet`", []*Heading{{1, "Use go vet", "use-go-vet"}}},
+		{"no headings", "text", []*Heading{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := Render(tt.source)
+			if err != nil {
+				t.Fatalf("Render err: %v", err)
+			}
+			if !reflect.DeepEqual(result.TOC, tt.want) {
+				t.Errorf("Render(%q).TOC = %v, want %v", tt.source, headings(result.TOC), headings(tt.want))
+			}
+			// 目录中的锚点需要保留在过滤后的 HTML 中
+			for _, h := range tt.want {
+				if !strings.Contains(result.HTML, `id="`+h.ID+`"`) {
+					t.Errorf("Render(%q) = %q, missing id %q", tt.source, result.HTML, h.ID)
+				}
+			}
+		})
+	}
+}
+
+// 每次渲染的锚点相互独立
+func TestRenderHeadingIDsPerDocument(t *testing.T) {
+	for i := 0; i < 2; i++ {
+		result, err := Render("# Intro")
+		if err != nil {
+			t.Fatalf("Render err: %v", err)
+		}
+		if result.TOC[0].ID != "intro" {
+			t.Errorf("render %d: ID = %q, want %q", i, result.TOC[0].ID, "intro")
+		}
+	}
+}
+
+func TestCache(t *testing.T) {
+	c := NewCache(2)
+	render := func(source string) *Result {
+		t.Helper()
+		result, err := c.Render(source)
+		if err != nil {
+			t.Fatalf("Render(%q) err: %v", source, err)
+		}
+		return result
+	}
+	cached := func(source string) bool {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		_, ok := c.items[sha256.Sum256([]byte(source))]
+		return ok
+	}
+
+	a := render("a")
+	if render("a") != a {
+		t.Error("Render(a) did not return the cached result")
+	}
+	render("b")
+	// 访问 a 后 b 成为最久未使用的结果
+	render("a")
+	render("c")
+	if c.Len() != 2 {
+		t.Errorf("Len() = %d, want 2", c.Len())
+	}
+	for source, want := range map[string]bool{"a": true, "b": false, "c": true} {
+		if got := cached(source); got != want {
+			t.Errorf("cached(%q) = %v, want %v", source, got, want)
+		}
+	}
+	if render("a") != a {
+		t.Error("Render(a) was evicted")
+	}
+}
+
+func TestCacheDisabled(t *testing.T) {
+	c := NewCache(0)
+	first, err := c.Render("a")
+	if err != nil {
+		t.Fatalf("Render err: %v", err)
+	}
+	second, err := c.Render("a")
+	if err != nil {
+		t.Fatalf("Render err: %v", err)
+	}
+	if first == second || c.Len() != 0 {
+		t.Errorf("NewCache(0) cached a result, Len() = %d", c.Len())
+	}
+}
+
+func headings(toc []*Heading) []Heading {
+	result := make([]Heading, 0, len(toc))
+	for _, h := range toc {
+		result = append(result, *h)
+	}
+
+	return result
+}
@@ -0,0 +1,126 @@
+package search
+
+import (
+	"html"
+	"strings"
+	"unicode/utf8"
+)
+
+// 摘要的长度和命中位置之前保留的长度，单位字符
+const (
+	snippetLength = 80
+	snippetLead   = 20
+)
+
+var whitespaceReplacer = strings.NewReplacer("\r\n", " ", "\n", " ", "\r", " ", "\t", " ")
+
+type span struct {
+	start int
+	end   int
+}
+
+// 使用 <em> 标记文本中命中查询词的部分，其余内容做 HTML 转义
+func Highlight(text string, terms []string) string {
+	return render(text, 0, len(text), matchSpans(text, terms))
+}
+
+// 优先从内容中截取第一个命中位置附近的摘要，内容没有命中时使用简述
+func snippet(doc *Document, terms []string) string {
+	for _, text := range []string{doc.Content, doc.Desc} {
+		spans := matchSpans(text, terms)
+		if len(spans) == 0 {
+			continue
+		}
+		start := backward(text, spans[0].start, snippetLead)
+		return render(text, start, forward(text, start, snippetLength), spans)
+	}
+
+	text := doc.Content
+	if text == "" {
+		text = doc.Desc
+	}
+
+	return render(text, 0, forward(text, 0, snippetLength), nil)
+}
+
+// 返回文本中命中查询词的字节区间，相邻或重叠的区间会合并
+func matchSpans(text string, terms []string) []span {
+	set := make(map[string]bool, len(terms))
+	for _, term := range terms {
+		set[term] = true
+	}
+
+	var spans []span
+	for _, token := range Tokenize(text) {
+		if !set[token.Term] {
+			continue
+		}
+		if n := len(spans); n > 0 && token.Start <= spans[n-1].end {
+			if token.End > spans[n-1].end {
+				spans[n-1].end = token.End
+			}
+			continue
+		}
+		spans = append(spans, span{start: token.Start, end: token.End})
+	}
+
+	return spans
+}
+
+// 输出 text[start:end]，截断时在两端加上省略号
+func render(text string, start, end int, spans []span) string {
+	var b strings.Builder
+	if start > 0 {
+		b.WriteString("…")
+	}
+	pos := start
+	for _, s := range spans {
+		if s.end <= pos || s.start >= end {
+			continue
+		}
+		if s.start > pos {
+			writeEscaped(&b, text[pos:s.start])
+			pos = s.start
+		}
+		spanEnd := s.end
+		if spanEnd > end {
+			spanEnd = end
+		}
+		b.WriteString("<em>")
+		writeEscaped(&b, text[pos:spanEnd])
+		b.WriteString("</em>")
+		pos = spanEnd
+	}
+	if pos < end {
+		writeEscaped(&b, text[pos:end])
+	}
+	if end < len(text) {
+		b.WriteString("…")
+	}
+
+	return b.String()
+}
+
+func writeEscaped(b *strings.Builder, s string) {
+	b.WriteString(html.EscapeString(whitespaceReplacer.Replace(s)))
+}
+
+// 从 pos 向前移动 n 个字符
+func backward(text string, pos, n int) int {
+	for ; n > 0 && pos > 0; n-- {
+		_, size := utf8.DecodeLastRuneInString(text[:pos])
+		pos -= size
+	}
+
+	return pos
+}
+
+// 从 pos 向后移动 n 个字符
+func forward(text string, pos, n int) int {
+	for ; n > 0 && pos < len(text); n-- {
+		_, size := utf8.DecodeRuneInString(text[pos:])
+		pos += size
+	}
+
+	return pos
+}
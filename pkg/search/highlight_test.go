@@ -0,0 +1,59 @@
+package search
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestHighlight(t *testing.T) {
+	tests := []struct {
+		name  string
+		text  string
+		query string
+		want  string
+	}{
+		{"no match", "hello world", "go", "hello world"},
+		{"case insensitive", "Go and go", "go", "<em>Go</em> and <em>go</em>"},
+		{"escapes html", `<b>Go</b> & "go"`, "go", `&lt;b&gt;<em>Go</em>&lt;/b&gt; &amp; &#34;<em>go</em>&#34;`},
+		{"escapes script", "<script>alert(1)</script>", "alert", "&lt;script&gt;<em>alert</em>(1)&lt;/script&gt;"},
+		{"cjk bigram", "学习Go语言", "语言", "学习Go<em>语言</em>"},
+		{"merges overlapping bigrams", "我爱编程语言", "编程语言", "我爱<em>编程语言</em>"},
+		{"single cjk", "语言", "语", "<em>语</em>言"},
+		{"collapses whitespace", "a\r\ngo\tb", "go", "a <em>go</em> b"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Highlight(tt.text, QueryTerms(tt.query)); got != tt.want {
+				t.Errorf("Highlight(%q, %q) = %q, want %q", tt.text, tt.query, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSnippet(t *testing.T) {
+	long := strings.Repeat("前文", 50) + "<Go>" + strings.Repeat("后文", 50)
+	tests := []struct {
+		name   string
+		doc    *Document
+		query  string
+		prefix string
+		suffix string
+		want   string
+	}{
+		{"match in content", &Document{Desc: "desc", Content: long}, "go", "…", "…", "&lt;<em>Go</em>&gt;"},
+		{"match in desc", &Document{Desc: "about <go>", Content: "content"}, "go", "about", "&gt;", "&lt;<em>go</em>&gt;"},
+		{"no match uses content", &Document{Desc: "desc", Content: "a & b"}, "go", "a", "b", "a &amp; b"},
+		{"no match uses desc", &Document{Desc: "a & b"}, "go", "a", "b", "a &amp; b"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := snippet(tt.doc, QueryTerms(tt.query))
+			if !strings.HasPrefix(got, tt.prefix) || !strings.HasSuffix(got, tt.suffix) || !strings.Contains(got, tt.want) {
+				t.Errorf("snippet = %q, want %q...%q containing %q", got, tt.prefix, tt.suffix, tt.want)
+			}
+			if strings.Count(got, "<em>") > 1 || strings.Contains(got, "<G") {
+				t.Errorf("snippet = %q, has unescaped html", got)
+			}
+		})
+	}
+}
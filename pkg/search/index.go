@@ -0,0 +1,238 @@
+package search
+
+import (
+	"math"
+	"sort"
+	"sync"
+)
+
+// 文档的字段，标题的权重最高，其次是简述和内容
+const (
+	FieldTitle = iota
+	FieldDesc
+	FieldContent
+	fieldCount
+)
+
+var fieldWeights = [fieldCount]float64{3, 2, 1}
+
+// BM25 的参数
+const (
+	bm25K1 = 1.2
+	bm25B  = 0.75
+)
+
+// 被索引的文档
+type Document struct {
+	ID      uint32
+	Title   string
+	Desc    string
+	Content string
+}
+
+func (d *Document) field(f int) string {
+	switch f {
+	case FieldTitle:
+		return d.Title
+	case FieldDesc:
+		return d.Desc
+	}
+
+	return d.Content
+}
+
+// 搜索结果，Title 和 Snippet 中命中的部分使用 <em> 标记，其余内容已做 HTML 转义
+type Hit struct {
+	ID      uint32  `json:"id"`
+	Score   float64 `json:"score"`
+	Title   string  `json:"title"`
+	Snippet string  `json:"snippet"`
+}
+
+type document struct {
+	*Document
+	length float64 // 按字段权重累加的词元数量
+	terms  []string
+}
+
+// 词元在一篇文档各个字段中出现的次数
+type posting [fieldCount]int
+
+// 进程内的倒排索引，可以并发读写
+type Index struct {
+	mu        sync.RWMutex
+	docs      map[uint32]*document
+	postings  map[string]map[uint32]*posting
+	totalSize float64
+}
+
+func NewIndex() *Index {
+	return &Index{
+		docs:     make(map[uint32]*document),
+		postings: make(map[string]map[uint32]*posting),
+	}
+}
+
+// 添加或替换文档
+func (idx *Index) Add(doc *Document) {
+	d := &document{Document: doc}
+	postings := make(map[string]*posting)
+	for f := 0; f < fieldCount; f++ {
+		tokens := Tokenize(doc.field(f))
+		d.length += fieldWeights[f] * float64(len(tokens))
+		for _, token := range tokens {
+			p, ok := postings[token.Term]
+			if !ok {
+				p = &posting{}
+				postings[token.Term] = p
+				d.terms = append(d.terms, token.Term)
+			}
+			p[f]++
+		}
+	}
+
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	idx.remove(doc.ID)
+	idx.docs[doc.ID] = d
+	idx.totalSize += d.length
+	for term, p := range postings {
+		if idx.postings[term] == nil {
+			idx.postings[term] = make(map[uint32]*posting)
+		}
+		idx.postings[term][doc.ID] = p
+	}
+}
+
+// 删除文档，文档不存在时不做任何处理
+func (idx *Index) Remove(id uint32) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	idx.remove(id)
+}
+
+// 使用 docs 替换索引中的全部文档
+func (idx *Index) Reset(docs []*Document) {
+	fresh := NewIndex()
+	for _, doc := range docs {
+		fresh.Add(doc)
+	}
+
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	idx.docs, idx.postings, idx.totalSize = fresh.docs, fresh.postings, fresh.totalSize
+}
+
+// 返回索引中的文档数量
+func (idx *Index) Len() int {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+
+	return len(idx.docs)
+}
+
+// 返回包含全部查询词的文档，按 BM25 得分从高到低排序，total 为命中的文档总数
+func (idx *Index) Search(query string, offset, limit int) (hits []*Hit, total int) {
+	terms := QueryTerms(query)
+	if len(terms) == 0 {
+		return []*Hit{}, 0
+	}
+
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+	type scored struct {
+		doc   *document
+		score float64
+	}
+	// 从包含文档最少的查询词开始求交集
+	var candidates map[uint32]*posting
+	for _, term := range terms {
+		docs := idx.postings[term]
+		if candidates == nil || len(docs) < len(candidates) {
+			candidates = docs
+		}
+	}
+	var results []scored
+	for id := range candidates {
+		if !idx.matchAll(id, terms) {
+			continue
+		}
+		doc := idx.docs[id]
+		results = append(results, scored{doc: doc, score: idx.score(doc, terms)})
+	}
+	sort.Slice(results, func(i, j int) bool {
+		if results[i].score != results[j].score {
+			return results[i].score > results[j].score
+		}
+		return results[i].doc.ID > results[j].doc.ID
+	})
+
+	total = len(results)
+	if offset >= total {
+		return []*Hit{}, total
+	}
+	end := total
+	if limit > 0 && offset+limit < total {
+		end = offset + limit
+	}
+	hits = make([]*Hit, 0, end-offset)
+	for _, r := range results[offset:end] {
+		hits = append(hits, &Hit{
+			ID:      r.doc.ID,
+			Score:   math.Round(r.score*1000) / 1000,
+			Title:   Highlight(r.doc.Title, terms),
+			Snippet: snippet(r.doc.Document, terms),
+		})
+	}
+
+	return hits, total
+}
+
+func (idx *Index) remove(id uint32) {
+	doc, ok := idx.docs[id]
+	if !ok {
+		return
+	}
+	for _, term := range doc.terms {
+		delete(idx.postings[term], id)
+		if len(idx.postings[term]) == 0 {
+			delete(idx.postings, term)
+		}
+	}
+	idx.totalSize -= doc.length
+	delete(idx.docs, id)
+}
+
+func (idx *Index) matchAll(id uint32, terms []string) bool {
+	for _, term := range terms {
+		if _, ok := idx.postings[term][id]; !ok {
+			return false
+		}
+	}
+
+	return true
+}
+
+// 按字段权重合并词频后计算 BM25 得分
+func (idx *Index) score(doc *document, terms []string) float64 {
+	n := float64(len(idx.docs))
+	avgLength := idx.totalSize / n
+	var score float64
+	for _, term := range terms {
+		docs := idx.postings[term]
+		p := docs[doc.ID]
+		var tf float64
+		for f := 0; f < fieldCount; f++ {
+			tf += fieldWeights[f] * float64(p[f])
+		}
+		df := float64(len(docs))
+		idf := math.Log(1 + (n-df+0.5)/(df+0.5))
+		norm := 1 - bm25B
+		if avgLength > 0 {
+			norm += bm25B * doc.length / avgLength
+		}
+		score += idf * tf * (bm25K1 + 1) / (tf + bm25K1*norm)
+	}
+
+	return score
+}
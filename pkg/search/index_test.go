@@ -0,0 +1,148 @@
+package search
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func hitIDs(hits []*Hit) []uint32 {
+	ids := []uint32{}
+	for _, hit := range hits {
+		ids = append(ids, hit.ID)
+	}
+
+	return ids
+}
+
+func testIndex() *Index {
+	idx := NewIndex()
+	idx.Reset([]*Document{
+		{ID: 1, Title: "Go 并发编程", Desc: "goroutine 和 channel", Content: "使用 Go 编写并发程序"},
+		{ID: 2, Title: "数据库索引", Desc: "B+ 树", Content: "索引的实现，示例代码使用 go 编写。" + strings.Repeat("填充内容", 50)},
+		{ID: 3, Title: "Rust 所有权", Desc: "借用检查", Content: "所有权和生命周期"},
+		{ID: 4, Title: "编程语言", Desc: "综述", Content: "Go、Rust 和其他编程语言"},
+	})
+
+	return idx
+}
+
+func TestIndexSearch(t *testing.T) {
+	idx := testIndex()
+	tests := []struct {
+		name  string
+		query string
+		want  []uint32
+	}{
+		{"empty query", "  ", []uint32{}},
+		{"no match", "python", []uint32{}},
+		{"title ranks first", "go", []uint32{1, 4, 2}},
+		{"all terms required", "go rust", []uint32{4}},
+		{"cjk phrase", "编程语言", []uint32{4}},
+		{"cjk single", "树", []uint32{2}},
+		{"cjk bigram across words", "程语", []uint32{4}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hits, total := idx.Search(tt.query, 0, 10)
+			if got := hitIDs(hits); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Search(%q) = %v, want %v", tt.query, got, tt.want)
+			}
+			if total != len(tt.want) {
+				t.Errorf("total = %d, want %d", total, len(tt.want))
+			}
+			for i := 1; i < len(hits); i++ {
+				if hits[i].Score > hits[i-1].Score {
+					t.Errorf("hits are not sorted by score: %v", hits)
+				}
+			}
+		})
+	}
+}
+
+func TestIndexSearchPaging(t *testing.T) {
+	idx := testIndex()
+	tests := []struct {
+		offset int
+		limit  int
+		want   []uint32
+	}{
+		{0, 2, []uint32{1, 4}},
+		{2, 2, []uint32{2}},
+		{3, 2, []uint32{}},
+		{1, 0, []uint32{4, 2}},
+	}
+	for _, tt := range tests {
+		hits, total := idx.Search("go", tt.offset, tt.limit)
+		if got := hitIDs(hits); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Search offset %d limit %d = %v, want %v", tt.offset, tt.limit, got, tt.want)
+		}
+		if total != 3 {
+			t.Errorf("total = %d, want 3", total)
+		}
+	}
+}
+
+func TestIndexBM25(t *testing.T) {
+	idx := NewIndex()
+	// 词频越高得分越高，文档越长得分越低
+	idx.Add(&Document{ID: 1, Content: "go go go"})
+	idx.Add(&Document{ID: 2, Content: "go"})
+	idx.Add(&Document{ID: 3, Content: "go " + strings.Repeat("filler ", 50)})
+	idx.Add(&Document{ID: 4, Content: "rust"})
+
+	hits, _ := idx.Search("go", 0, 10)
+	if got, want := hitIDs(hits), []uint32{1, 2, 3}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Search = %v, want %v", got, want)
+	}
+
+	// 出现在更少文档中的词权重更高
+	idx.Add(&Document{ID: 5, Content: "go rust"})
+	idx.Add(&Document{ID: 6, Content: "rust rust"})
+	rare, _ := idx.Search("go rust", 0, 10)
+	if len(rare) != 1 || rare[0].ID != 5 {
+		t.Fatalf("Search(go rust) = %v, want [5]", hitIDs(rare))
+	}
+}
+
+func TestIndexAddRemove(t *testing.T) {
+	idx := testIndex()
+
+	idx.Add(&Document{ID: 3, Title: "Go 与 Rust"})
+	if hits, _ := idx.Search("所有权", 0, 10); len(hits) != 0 {
+		t.Errorf("replaced document is still searchable by old content: %v", hitIDs(hits))
+	}
+	if hits, _ := idx.Search("go rust", 0, 10); !reflect.DeepEqual(hitIDs(hits), []uint32{3, 4}) {
+		t.Errorf("Search(go rust) = %v, want [3 4]", hitIDs(hits))
+	}
+
+	idx.Remove(3)
+	idx.Remove(100)
+	if idx.Len() != 3 {
+		t.Errorf("Len = %d, want 3", idx.Len())
+	}
+	if hits, _ := idx.Search("rust", 0, 10); !reflect.DeepEqual(hitIDs(hits), []uint32{4}) {
+		t.Errorf("Search(rust) = %v, want [4]", hitIDs(hits))
+	}
+
+	idx.Reset(nil)
+	if hits, total := idx.Search("go", 0, 10); len(hits) != 0 || total != 0 {
+		t.Errorf("Search after Reset = %v, %d", hitIDs(hits), total)
+	}
+}
+
+func TestIndexSearchEscapesResults(t *testing.T) {
+	idx := NewIndex()
+	idx.Add(&Document{ID: 1, Title: "<script>Go</script>", Content: `<img src=x onerror="go()">`})
+
+	hits, _ := idx.Search("go", 0, 10)
+	if len(hits) != 1 {
+		t.Fatalf("len(hits) = %d, want 1", len(hits))
+	}
+	if want := "&lt;script&gt;<em>Go</em>&lt;/script&gt;"; hits[0].Title != want {
+		t.Errorf("Title = %q, want %q", hits[0].Title, want)
+	}
+	if want := "&lt;img src=x onerror=&#34;<em>go</em>()&#34;&gt;"; hits[0].Snippet != want {
+		t.Errorf("Snippet = %q, want %q", hits[0].Snippet, want)
+	}
+}
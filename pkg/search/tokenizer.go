@@ -0,0 +1,109 @@
+package search
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// 单个词元的最大字节数，超过时截断，避免超长的无意义字符串进入索引
+const maxTermLength = 64
+
+// 分词结果，Start 和 End 为词元在原文中的字节区间，左闭右开
+type Token struct {
+	Term  string
+	Start int
+	End   int
+}
+
+// 建立索引时的分词：字母和数字按单词切分并转为小写
+// 中日韩文字没有空格分隔，同时输出单字和相邻两字组成的二元词，单字用于匹配单字查询
+func Tokenize(text string) []Token {
+	return tokenize(text, false)
+}
+
+// 查询时的分词：连续的中日韩文字只输出二元词，只有一个字时输出单字
+// 查询中的二元词全部命中即可保证原文中包含相同的连续文字
+func TokenizeQuery(text string) []Token {
+	return tokenize(text, true)
+}
+
+// 查询分词后去重的词元
+func QueryTerms(text string) []string {
+	var terms []string
+	seen := make(map[string]bool)
+	for _, token := range TokenizeQuery(text) {
+		if !seen[token.Term] {
+			seen[token.Term] = true
+			terms = append(terms, token.Term)
+		}
+	}
+
+	return terms
+}
+
+func tokenize(text string, query bool) []Token {
+	var tokens []Token
+	wordStart := -1
+	// 当前连续中日韩文字的起始位置
+	var cjk []int
+
+	flushWord := func(end int) {
+		if wordStart >= 0 {
+			term := strings.ToLower(text[wordStart:end])
+			if len(term) > maxTermLength {
+				n := maxTermLength
+				for n > 0 && !utf8.RuneStart(term[n]) {
+					n--
+				}
+				term = term[:n]
+			}
+			tokens = append(tokens, Token{Term: term, Start: wordStart, End: end})
+			wordStart = -1
+		}
+	}
+	flushCJK := func(end int) {
+		if len(cjk) == 0 {
+			return
+		}
+		cjk = append(cjk, end)
+		n := len(cjk) - 1
+		for i := 0; i < n; i++ {
+			if !query || n == 1 {
+				tokens = append(tokens, Token{Term: text[cjk[i]:cjk[i+1]], Start: cjk[i], End: cjk[i+1]})
+			}
+			if i+1 < n {
+				tokens = append(tokens, Token{Term: text[cjk[i]:cjk[i+2]], Start: cjk[i], End: cjk[i+2]})
+			}
+		}
+		cjk = cjk[:0]
+	}
+
+	for i, r := range text {
+		switch {
+		case r == utf8.RuneError:
+			flushWord(i)
+			flushCJK(i)
+		case isCJK(r):
+			flushWord(i)
+			cjk = append(cjk, i)
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			flushCJK(i)
+			if wordStart < 0 {
+				wordStart = i
+			}
+		default:
+			flushWord(i)
+			flushCJK(i)
+		}
+	}
+	flushWord(len(text))
+	flushCJK(len(text))
+
+	return tokens
+}
+
+func isCJK(r rune) bool {
+	return unicode.Is(unicode.Han, r) || unicode.Is(unicode.Hiragana, r) ||
+		unicode.Is(unicode.Katakana, r) || unicode.Is(unicode.Hangul, r)
+}
@@ -0,0 +1,77 @@
+package search
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func terms(tokens []Token) []string {
+	result := []string{}
+	for _, token := range tokens {
+		result = append(result, token.Term)
+	}
+
+	return result
+}
+
+func TestTokenize(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want []string
+	}{
+		{"empty", "", []string{}},
+		{"words", "Hello, World-42", []string{"hello", "world", "42"}},
+		{"single cjk", "语", []string{"语"}},
+		{"cjk unigrams and bigrams", "编程语言", []string{"编", "编程", "程", "程语", "语", "语言", "言"}},
+		{"mixed", "Go语言 入门", []string{"go", "语", "语言", "言", "入", "入门", "门"}},
+		{"kana and hangul", "カナ한글", []string{"カ", "カナ", "ナ", "ナ한", "한", "한글", "글"}},
+		{"invalid utf-8", "ab\xffcd", []string{"ab", "cd"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := terms(Tokenize(tt.text)); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Tokenize(%q) = %q, want %q", tt.text, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTokenizeOffsets(t *testing.T) {
+	text := "Go语言"
+	for _, token := range Tokenize(text) {
+		if got := strings.ToLower(text[token.Start:token.End]); got != token.Term {
+			t.Errorf("text[%d:%d] = %q, want %q", token.Start, token.End, got, token.Term)
+		}
+	}
+}
+
+func TestTokenizeTruncatesLongTerms(t *testing.T) {
+	tokens := Tokenize(strings.Repeat("a", 100) + strings.Repeat("é", 40))
+	if len(tokens) != 1 {
+		t.Fatalf("len(tokens) = %d, want 1", len(tokens))
+	}
+	if len(tokens[0].Term) > maxTermLength {
+		t.Errorf("len(term) = %d, want <= %d", len(tokens[0].Term), maxTermLength)
+	}
+}
+
+func TestQueryTerms(t *testing.T) {
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		{"", nil},
+		{"语", []string{"语"}},
+		{"语言", []string{"语言"}},
+		{"编程语言", []string{"编程", "程语", "语言"}},
+		{"Go go 语言", []string{"go", "语言"}},
+		{"语言 语言", []string{"语言"}},
+	}
+	for _, tt := range tests {
+		if got := QueryTerms(tt.query); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("QueryTerms(%q) = %q, want %q", tt.query, got, tt.want)
+		}
+	}
+}
@@ -9,18 +9,90 @@ type ServerSetting struct {
 	HttpPort     string
 	ReadTimeout  time.Duration
 	WriteTimeout time.Duration
+	// 退出时等待处理中的请求和后台任务完成的最长时间
+	ShutdownTimeout time.Duration
+	// 可信的反向代理的 IP 或网段，只采用这些代理设置的 X-Forwarded-For，为空时客户端 IP 为连接的对端地址
+	TrustedProxies []string
 }
 
 type AppSetting struct {
-	DefaultPageSize      int
-	MaxPageSize          int
-	LogSavePath          string
-	LogFileName          string
-	LogFileExt           string
-	UploadSavePath       string
-	UploadServerUrl      string
-	UploadImageMaxSize   int
-	UploadImageAllowExts []string
+	DefaultPageSize int
+	MaxPageSize     int
+	LogSavePath     string
+	LogFileName     string
+	LogFileExt      string
+	UploadStorage   string // 上传文件的存储后端：local 或 s3
+	UploadSavePath  string
+	UploadServerUrl string
+	// 私有文件签名地址的服务地址、签名密钥和默认有效期
+	UploadPrivateServerUrl string
+	UploadSignSecret       string
+	UploadSignedUrlExpire  time.Duration
+	UploadTypes            map[string]*UploadTypeSetting // 各类文件的上传规则，键为 image、document、audio 或 video
+	UploadImageMaxWidth    int                           // 图片的最大宽度，单位像素
+	UploadImageMaxHeight   int                           // 图片的最大高度，单位像素
+	UploadImageConcurrency int                           // 同时解码生成衍生图的图片数量，解码后的图片按像素占用内存
+	UploadChunkSize        int                           // 分片上传默认的分片大小，单位MB
+	// 分片上传会话的有效期，以及清理过期会话的间隔
+	UploadSessionExpire        time.Duration
+	UploadSessionCleanInterval time.Duration
+	// 未被文章引用的文件超过宽限期后才会删除，以及自动回收的间隔
+	UploadGCGracePeriod time.Duration
+	UploadGCInterval    time.Duration
+	// 上传图片时生成的衍生图，如缩略图和展示图
+	UploadImageDerivatives []ImageDerivativeSetting
+	// 每个用户的上传配额，可以按角色覆盖，以及整个服务的上传总配额
+	UploadUserQuota  UploadQuotaSetting
+	UploadRoleQuotas map[string]*UploadQuotaSetting
+	UploadTotalQuota UploadQuotaSetting
+	// 检查定时发布文章的间隔
+	ArticlePublishInterval time.Duration
+	// 从数据库重建搜索索引的间隔，索引只在进程内，定期重建以同步其他实例的修改
+	SearchIndexRebuildInterval time.Duration
+	// 缓存的文章渲染结果数量，为 0 时不缓存
+	MarkdownCacheSize int
+	// 订阅源的标题、描述、博客前端地址和本服务对外的地址
+	FeedTitle       string
+	FeedDescription string
+	FeedSiteUrl     string
+	FeedServerUrl   string
+	FeedSize        int           // 订阅源中的文章数量
+	FeedFullContent bool          // 是否输出渲染后的完整内容，否则只输出简述
+	FeedCacheTTL    time.Duration // 生成的订阅源在内存中缓存的时间
+}
+
+// 一类文件的上传规则
+type UploadTypeSetting struct {
+	AllowExts []string // 允许的文件后缀
+	MaxSize   int      // 允许的最大文件大小，单位MB
+	SavePath  string   // 保存的子目录
+}
+
+// 上传配额，为 0 时表示不限制
+type UploadQuotaSetting struct {
+	MaxSize  int // 已保存文件的总大小，单位MB
+	MaxFiles int // 已保存文件的数量
+}
+
+// 图片衍生图的配置，宽高为 0 时表示不限制该边，图片只会缩小不会放大
+type ImageDerivativeSetting struct {
+	Name    string // 衍生图名称，同时作为文件名后缀
+	Width   int
+	Height  int
+	Format  string // 输出格式：jpeg 或 png，为空时 JPEG 保持原格式，其余格式输出 png
+	Quality int    // JPEG 的压缩质量，为 0 时使用默认值
+}
+
+// S3 兼容对象存储的配置
+type S3SettingS struct {
+	Endpoint      string
+	AccessKey     string
+	SecretKey     string
+	Bucket        string // 保存公开文件，可以公开读取
+	PrivateBucket string // 保存私有文件、临时文件和上传分片，不能公开访问
+	Region        string
+	UseSSL        bool
+	PublicUrl     string // 文件对外访问地址，为空时使用 Endpoint/Bucket
 }
 
 type DatabaseSetting struct {
@@ -37,9 +109,24 @@ type DatabaseSetting struct {
 }
 
 type JWTSettingS struct {
-	Secret string
-	Issuer string
-	Expire time.Duration
+	Secret        string
+	Issuer        string
+	Expire        time.Duration
+	RefreshExpire time.Duration
+	// 未吊销状态在进程内缓存的时间，其他实例上的吊销最迟在该时间后生效
+	RevocationCacheTTL time.Duration
+	SigningKeyID       string          // 用于签发 Token 的密钥 ID，为空时使用 Secret 进行 HS256 签名
+	Keys               []JWTKeySetting // 所有有效的密钥，轮换期间旧密钥只用于验签
+	// 配置了 Keys 后仍接受没有 kid 的 HS256 令牌，仅用于从 Secret 迁移到非对称密钥的过渡期
+	AllowLegacyHS256 bool
+}
+
+// 非对称签名密钥，只配置公钥时该密钥仅用于验签
+type JWTKeySetting struct {
+	ID             string
+	Algorithm      string // RS256, ES256 或 EdDSA
+	PrivateKeyFile string
+	PublicKeyFile  string
 }
 
 type EmailSettingS struct {
@@ -0,0 +1,95 @@
+package upload
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"hash"
+	"io"
+	"strconv"
+)
+
+var (
+	ErrChunkCorrupted   = errors.New("upload chunk is missing or corrupted")
+	ErrChecksumMismatch = errors.New("file checksum does not match")
+)
+
+// 分片在存储中的文件名，按会话和起始偏移命名，同一位置重复上传时直接覆盖
+func GetChunkName(uploadID string, start int64) string {
+	return "chunks/" + uploadID + "/" + strconv.FormatInt(start, 10)
+}
+
+// 分片的存储位置、大小和 SHA-256
+type Chunk struct {
+	Name string
+	Size int64
+	Hash string
+}
+
+// 按顺序拼接存储中的分片，读取的同时校验每个分片以及整个文件的 SHA-256
+// 校验失败时返回错误而不是 io.EOF，使写入方放弃已读取的内容
+type ChunkReader struct {
+	ctx    context.Context
+	s      Storage
+	chunks []Chunk
+	hash   string
+	total  hash.Hash
+	cur    io.ReadCloser
+	hr     *HashReader
+	i      int
+}
+
+// hash 为整个文件的十六进制 SHA-256，为空时只校验分片
+func NewChunkReader(ctx context.Context, s Storage, chunks []Chunk, hash string) *ChunkReader {
+	return &ChunkReader{ctx: ctx, s: s, chunks: chunks, hash: hash, total: sha256.New()}
+}
+
+func (r *ChunkReader) Read(p []byte) (int, error) {
+	for {
+		if r.cur == nil {
+			if r.i >= len(r.chunks) {
+				if r.hash != "" && hex.EncodeToString(r.total.Sum(nil)) != r.hash {
+					return 0, ErrChecksumMismatch
+				}
+				return 0, io.EOF
+			}
+			rc, err := r.s.Get(r.ctx, r.chunks[r.i].Name)
+			if errors.Is(err, ErrObjectNotExist) {
+				return 0, ErrChunkCorrupted
+			}
+			if err != nil {
+				return 0, err
+			}
+			r.cur = rc
+			r.hr = NewHashReader(rc)
+		}
+
+		n, err := r.hr.Read(p)
+		r.total.Write(p[:n])
+		if err != io.EOF {
+			return n, err
+		}
+
+		chunk := r.chunks[r.i]
+		r.cur.Close()
+		r.cur = nil
+		r.i++
+		if r.hr.Size() != chunk.Size || r.hr.Sum() != chunk.Hash {
+			return n, ErrChunkCorrupted
+		}
+		if n > 0 {
+			return n, nil
+		}
+	}
+}
+
+func (r *ChunkReader) Close() error {
+	if r.cur == nil {
+		return nil
+	}
+	err := r.cur.Close()
+	r.cur = nil
+
+	return err
+}
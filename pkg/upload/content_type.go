@@ -0,0 +1,89 @@
+package upload
+
+import (
+	"bytes"
+	"net/http"
+	"strings"
+	"unicode/utf8"
+)
+
+// 文件后缀与真实内容类型的对应关系，配置中允许的后缀需要在这里有对应的类型
+var extContentTypes = map[string]string{
+	".jpg":      "image/jpeg",
+	".jpeg":     "image/jpeg",
+	".png":      "image/png",
+	".gif":      "image/gif",
+	".pdf":      "application/pdf",
+	".md":       "text/plain",
+	".markdown": "text/plain",
+	".mp3":      "audio/mpeg",
+	".wav":      "audio/wave",
+	".ogg":      "application/ogg",
+	".flac":     "audio/flac",
+	".mp4":      "video/mp4",
+	".webm":     "video/webm",
+}
+
+// 根据文件开头的魔数判断真实类型，最多使用前 512 个字节
+// 文本类后缀只要求内容是 UTF-8 文本，以 HTML 块开头的 Markdown 也按 text/plain 处理
+func DetectContentType(name string, head []byte) string {
+	if extContentTypes[strings.ToLower(GetFileExt(name))] == "text/plain" && isText(head[:len(head)-incompleteRuneSuffix(head)]) {
+		return "text/plain"
+	}
+	if contentType := detectAudioType(head); contentType != "" {
+		return contentType
+	}
+
+	contentType := http.DetectContentType(head)
+	if i := strings.Index(contentType, ";"); i >= 0 {
+		contentType = contentType[:i]
+	}
+
+	return contentType
+}
+
+// 补充 http.DetectContentType 无法识别的音频格式，无法识别时返回空字符串
+func detectAudioType(head []byte) string {
+	switch {
+	case bytes.HasPrefix(head, []byte("fLaC")):
+		return "audio/flac"
+	case len(head) >= 2 && head[0] == 0xFF && head[1]&0xE0 == 0xE0:
+		// 没有 ID3 标签的 MP3 以帧同步字开头，0xFF 不会出现在 UTF-8 文本中
+		return "audio/mpeg"
+	}
+
+	return ""
+}
+
+// 检查文件内容与后缀是否一致
+func CheckContentType(name, contentType string) bool {
+	expected, ok := extContentTypes[strings.ToLower(GetFileExt(name))]
+	return ok && expected == contentType
+}
+
+// 内容是有效的 UTF-8，并且不包含 http.DetectContentType 视为二进制数据的控制字符
+func isText(data []byte) bool {
+	for _, b := range data {
+		if b <= 0x08 || b == 0x0B || (b >= 0x0E && b <= 0x1A) || (b >= 0x1C && b <= 0x1F) {
+			return false
+		}
+	}
+
+	return utf8.Valid(data)
+}
+
+// 返回末尾被截断的不完整字符的字节数，分段读取时这部分需要和后续内容一起检查
+func incompleteRuneSuffix(data []byte) int {
+	for i := 1; i < utf8.UTFMax && i <= len(data); i++ {
+		tail := data[len(data)-i:]
+		if !utf8.RuneStart(tail[0]) {
+			continue
+		}
+		if !utf8.FullRune(tail) {
+			return i
+		}
+		break
+	}
+
+	return 0
+}
@@ -0,0 +1,50 @@
+package upload
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestDetectContentType(t *testing.T) {
+	pngHead := []byte(pngSignature + "\x00\x00\x00\x0dIHDR")
+	tests := []struct {
+		name     string
+		fileName string
+		head     []byte
+		want     string
+	}{
+		{"markdown", "a.md", []byte("# 标题\n\n正文"), "text/plain"},
+		{"markdown starting with html", "a.md", []byte("<div align=\"center\">\n\n# Title\n</div>"), "text/plain"},
+		{"markdown starting with html comment", "a.markdown", []byte("<!-- toc -->\n- item"), "text/plain"},
+		{"markdown head ends inside a rune", "a.md", []byte("中文")[:4], "text/plain"},
+		{"binary markdown", "a.md", []byte("\x00\x01\x02"), "application/octet-stream"},
+		{"png disguised as markdown", "a.md", pngHead, "image/png"},
+		{"html disguised as image", "a.png", []byte("<html><body>"), "text/html"},
+		{"png", "a.png", pngHead, "image/png"},
+		{"flac", "a.flac", []byte("fLaC\x00"), "audio/flac"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := DetectContentType(tt.fileName, tt.head)
+			if got != tt.want {
+				t.Errorf("DetectContentType(%q) = %q, want %q", tt.fileName, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheckContentType(t *testing.T) {
+	head := []byte("<details>\n<summary>Usage</summary>\n</details>")
+	if !CheckContentType("README.md", DetectContentType("README.md", head)) {
+		t.Error("markdown starting with an HTML block should be accepted")
+	}
+	if CheckContentType("a.png", DetectContentType("a.png", head)) {
+		t.Error("HTML content should not be accepted as an image")
+	}
+	if CheckContentType("a.md", DetectContentType("a.md", []byte("\x00\x00\x00\x18ftypmp42"))) {
+		t.Error("binary content should not be accepted as markdown")
+	}
+	if !isText(bytes.Repeat([]byte("\t\r\n\f\x1b"), 4)) {
+		t.Error("whitespace and escape characters should be text")
+	}
+}
@@ -0,0 +1,194 @@
+package upload
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/go-programming-tour/blog-service/global"
+	"github.com/go-programming-tour/blog-service/pkg/setting"
+	"golang.org/x/image/draw"
+)
+
+const defaultJPEGQuality = 85
+
+// 衍生图的文件名：在原文件名后追加衍生图名称，如 ab/abcd..._thumb.jpg
+func GetDerivativeName(name, contentType string, d setting.ImageDerivativeSetting) string {
+	ext := ".png"
+	if derivativeFormat(contentType, d) == "jpeg" {
+		ext = ".jpg"
+	}
+
+	return strings.TrimSuffix(name, path.Ext(name)) + "_" + d.Name + ext
+}
+
+// 返回图片文件的全部衍生图文件名，非图片文件返回 nil
+func GetDerivativeNames(name, contentType string) []string {
+	if !strings.HasPrefix(contentType, "image/") {
+		return nil
+	}
+
+	var names []string
+	for _, d := range global.AppSetting.UploadImageDerivatives {
+		names = append(names, GetDerivativeName(name, contentType, d))
+	}
+
+	return names
+}
+
+// 返回图片文件的全部衍生图访问地址，非图片文件返回 nil
+// 私有文件返回签名地址，参数与 GetFileURL 相同
+func GetDerivativeURLs(name, contentType string, private bool, userID uint32, expire time.Duration) map[string]string {
+	derivatives := global.AppSetting.UploadImageDerivatives
+	if !strings.HasPrefix(contentType, "image/") || len(derivatives) == 0 {
+		return nil
+	}
+
+	urls := make(map[string]string, len(derivatives))
+	for _, d := range derivatives {
+		urls[d.Name] = GetFileURL(GetDerivativeName(name, contentType, d), private, userID, expire)
+	}
+
+	return urls
+}
+
+// 按配置为图片生成衍生图，已存在的衍生图不会重复生成
+// 从 img.Name 解码图片，衍生图按 name 命名；只有缺少衍生图时才解码，解码受并发数量限制
+// 重新编码的衍生图不包含任何元数据
+func GenerateDerivatives(ctx context.Context, s Storage, name string, img *Image) error {
+	var missing []setting.ImageDerivativeSetting
+	for _, d := range global.AppSetting.UploadImageDerivatives {
+		_, err := s.Stat(ctx, GetDerivativeName(name, img.ContentType, d))
+		if err == nil {
+			continue
+		}
+		if !errors.Is(err, ErrObjectNotExist) {
+			return err
+		}
+		missing = append(missing, d)
+	}
+	if len(missing) == 0 {
+		return nil
+	}
+
+	if err := acquireImage(ctx); err != nil {
+		return err
+	}
+	defer releaseImage()
+
+	src, err := decodeImage(ctx, s, img.Name)
+	if err != nil {
+		return err
+	}
+	for _, d := range missing {
+		data, contentType, err := encodeDerivative(src, img.ContentType, img.Orientation, d)
+		if err != nil {
+			return err
+		}
+		derivativeName := GetDerivativeName(name, img.ContentType, d)
+		if err := s.Put(ctx, derivativeName, bytes.NewReader(data), int64(len(data)), contentType); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// 返回已生成的衍生图的总大小，非图片文件返回 0
+func GetDerivativesSize(ctx context.Context, s Storage, name, contentType string) (int64, error) {
+	var size int64
+	for _, derivativeName := range GetDerivativeNames(name, contentType) {
+		info, err := s.Stat(ctx, derivativeName)
+		if errors.Is(err, ErrObjectNotExist) {
+			continue
+		}
+		if err != nil {
+			return 0, err
+		}
+		size += info.Size
+	}
+
+	return size, nil
+}
+
+// 输出格式为 jpeg 或 png，未指定时 JPEG 保持原格式，其余格式输出 png 以保留透明度
+func derivativeFormat(contentType string, d setting.ImageDerivativeSetting) string {
+	switch strings.ToLower(d.Format) {
+	case "jpeg", "jpg":
+		return "jpeg"
+	case "png":
+		return "png"
+	}
+	if contentType == "image/jpeg" {
+		return "jpeg"
+	}
+
+	return "png"
+}
+
+// 先缩放再按方向校正，宽高限制针对校正后的显示方向
+func encodeDerivative(img image.Image, contentType string, orientation int, d setting.ImageDerivativeSetting) ([]byte, string, error) {
+	format := derivativeFormat(contentType, d)
+	b := img.Bounds()
+	var w, h int
+	if orientation >= 5 {
+		h, w = fitSize(b.Dy(), b.Dx(), d.Width, d.Height)
+	} else {
+		w, h = fitSize(b.Dx(), b.Dy(), d.Width, d.Height)
+	}
+	dst := image.NewRGBA(image.Rect(0, 0, w, h))
+	op := draw.Src
+	if format == "jpeg" {
+		// JPEG 不支持透明度，透明区域使用白色背景
+		draw.Draw(dst, dst.Bounds(), image.NewUniform(color.White), image.Point{}, draw.Src)
+		op = draw.Over
+	}
+	draw.CatmullRom.Scale(dst, dst.Bounds(), img, b, op, nil)
+	dst = orientImage(dst, orientation)
+
+	var buf bytes.Buffer
+	if format == "jpeg" {
+		quality := d.Quality
+		if quality <= 0 || quality > 100 {
+			quality = defaultJPEGQuality
+		}
+		if err := jpeg.Encode(&buf, dst, &jpeg.Options{Quality: quality}); err != nil {
+			return nil, "", err
+		}
+		return buf.Bytes(), "image/jpeg", nil
+	}
+	if err := png.Encode(&buf, dst); err != nil {
+		return nil, "", err
+	}
+
+	return buf.Bytes(), "image/png", nil
+}
+
+// 在保持宽高比的前提下缩放到 maxWidth x maxHeight 以内，不放大图片
+func fitSize(width, height, maxWidth, maxHeight int) (int, int) {
+	scale := 1.0
+	if maxWidth > 0 && width > maxWidth {
+		scale = float64(maxWidth) / float64(width)
+	}
+	if maxHeight > 0 && float64(height)*scale > float64(maxHeight) {
+		scale = float64(maxHeight) / float64(height)
+	}
+
+	w := int(float64(width)*scale + 0.5)
+	h := int(float64(height)*scale + 0.5)
+	if w < 1 {
+		w = 1
+	}
+	if h < 1 {
+		h = 1
+	}
+
+	return w, h
+}
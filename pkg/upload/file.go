@@ -3,69 +3,114 @@ package upload
 // 上传文件的工具库
 
 import (
-	"io"
-	"io/ioutil"
-	"mime/multipart"
-	"os"
+	"errors"
 	"path"
+	"regexp"
 	"strings"
 
 	"github.com/go-programming-tour/blog-service/global"
+	"github.com/go-programming-tour/blog-service/pkg/setting"
 	"github.com/go-programming-tour/blog-service/pkg/util"
 )
 
 type FileType int
 
-const TypeImage FileType = iota + 1
+const (
+	TypeImage FileType = iota + 1
+	TypeDocument
+	TypeAudio
+	TypeVideo
+)
+
+var ErrFileTypeUnsupported = errors.New("file type is not supported")
 
-func GetFileName(name string) string {
-	ext := GetFileExt(name)
-	fileName := strings.TrimSuffix(name, ext) // 去掉后缀
-	fileName = util.EncodeMD5(fileName)       // 经过MD5加密后返回新的文件名
+// 文件名中的内容哈希，原文件和衍生图的地址中都包含它
+var fileHashPattern = regexp.MustCompile(`[0-9a-f]{64}`)
 
-	return fileName + ext
+// 文件类型在配置 UploadTypes 中对应的键，各类型的上传规则均来自配置
+var fileTypeKeys = map[FileType]string{
+	TypeImage:    "image",
+	TypeDocument: "document",
+	TypeAudio:    "audio",
+	TypeVideo:    "video",
 }
 
-// 获取文件后缀名，返回的后缀包括点
-func GetFileExt(name string) string {
-	return path.Ext(name)
+// 返回文件类型的上传规则，类型未知或没有配置时返回 false
+func GetTypeSetting(t FileType) (*setting.UploadTypeSetting, bool) {
+	key, ok := fileTypeKeys[t]
+	if !ok {
+		return nil, false
+	}
+	typeSetting, ok := global.AppSetting.UploadTypes[key]
+	if !ok || typeSetting == nil {
+		return nil, false
+	}
+
+	return typeSetting, true
+}
+
+// 根据后缀返回允许该后缀的文件类型，没有时返回 0
+func GetFileTypeByExt(name string) FileType {
+	for t := TypeImage; t <= TypeVideo; t++ {
+		if CheckContainExt(t, name) {
+			return t
+		}
+	}
+
+	return 0
 }
 
-func GetSavePath() string {
-	return global.AppSetting.UploadSavePath
+// 检查文件类型是否受支持
+func CheckFileType(t FileType) bool {
+	_, ok := GetTypeSetting(t)
+	return ok
 }
 
-// 检查路径是否存在
-func CheckSavePath(dst string) bool {
-	_, err := os.Stat(dst) // 获取文件的描述信息
+// 按内容的 SHA-256 命名文件，相同内容只保存一份
+// 以哈希的前两位作为子目录，避免单个目录下文件过多，并放在文件类型对应的子目录下
+// 私有文件统一放在 private/ 下，只能通过签名地址访问
+func GetFileName(t FileType, hash, ext string, private bool) string {
+	name := hash[:2] + "/" + hash + strings.ToLower(ext)
+	if typeSetting, ok := GetTypeSetting(t); ok && typeSetting.SavePath != "" {
+		name = path.Join(typeSetting.SavePath, name)
+	}
+	if private {
+		name = PrivatePrefix + name
+	}
 
-	return os.IsNotExist(err)
+	return name
 }
 
-// 检查文件权限是否足够
-func CheckContainExt(t FileType, name string) bool {
-	ext := GetFileExt(name)
-	ext = strings.ToUpper(ext)
-	switch t {
-	case TypeImage:
-		for _, allowExt := range global.AppSetting.UploadImageAllowExts {
-			if strings.ToUpper(allowExt) == ext {
-				return true
-			}
-		}
+// 返回文本中引用的所有文件的内容哈希，不受访问地址前缀变化的影响
+func ExtractFileHashes(text string) []string {
+	return fileHashPattern.FindAllString(text, -1)
+}
 
+// 上传过程中使用的临时文件名，内容哈希计算完成后再移动到正式位置
+func GetTempFileName() (string, error) {
+	name, err := util.RandomString(16)
+	if err != nil {
+		return "", err
 	}
 
-	return false
+	return "tmp/" + name, nil
 }
 
-// 检查文件大小是否超过最大值
-func CheckMaxSize(t FileType, f multipart.File) bool {
-	content, _ := ioutil.ReadAll(f)
-	size := len(content)
-	switch t {
-	case TypeImage:
-		if size >= global.AppSetting.UploadImageMaxSize*1024*1024 {
+// 获取文件后缀名，返回的后缀包括点
+func GetFileExt(name string) string {
+	return path.Ext(name)
+}
+
+// 检查文件后缀是否被允许
+func CheckContainExt(t FileType, name string) bool {
+	typeSetting, ok := GetTypeSetting(t)
+	if !ok {
+		return false
+	}
+
+	ext := strings.ToUpper(GetFileExt(name))
+	for _, allowExt := range typeSetting.AllowExts {
+		if strings.ToUpper(allowExt) == ext {
 			return true
 		}
 	}
@@ -73,36 +118,35 @@ func CheckMaxSize(t FileType, f multipart.File) bool {
 	return false
 }
 
-// 检查文件权限
-func CheckPermission(dst string) bool {
-	_, err := os.Stat(dst)
-	return os.IsPermission(err)
-}
-
-// 创建保存上传的文件
-func CreateSavePath(dst string, perm os.FileMode) error {
-	err := os.MkdirAll(dst, perm)
-	if err != nil {
-		return err
+// 返回文件类型允许的最大字节数
+func GetMaxSize(t FileType) int64 {
+	typeSetting, ok := GetTypeSetting(t)
+	if !ok {
+		return 0
 	}
 
-	return nil
+	return int64(typeSetting.MaxSize) * 1024 * 1024
 }
 
-// 保存上传的文件
-func SaveFile(file *multipart.FileHeader, dst string) error {
-	src, err := file.Open() // 打开上传的文件
-	if err != nil {
-		return err
+// 返回所有文件类型中最大的字节数限制
+func GetMaxSizeOfAll() int64 {
+	var maxSize int64
+	for t := range fileTypeKeys {
+		if size := GetMaxSize(t); size > maxSize {
+			maxSize = size
+		}
 	}
-	defer src.Close()
 
-	out, err := os.Create(dst) // 创建目标地址的文件对象
-	if err != nil {
-		return err
+	return maxSize
+}
+
+// 返回上传请求体允许的最大字节数，在文件大小的基础上预留表单字段和 multipart 头部的空间
+// 文件类型未知时使用所有类型中最大的限制
+func GetMaxBodySize(t FileType) int64 {
+	maxSize := GetMaxSize(t)
+	if !CheckFileType(t) {
+		maxSize = GetMaxSizeOfAll()
 	}
-	defer out.Close()
 
-	_, err = io.Copy(out, src) // 文件拷贝
-	return err
+	return maxSize + 1024*1024
 }
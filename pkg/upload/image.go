@@ -0,0 +1,150 @@
+package upload
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"image"
+	"io"
+	"sync"
+
+	// 注册支持解码的图片格式
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+
+	"github.com/go-programming-tour/blog-service/global"
+)
+
+// 未配置时同时解码的图片数量
+const defaultImageConcurrency = 2
+
+var (
+	ErrFileTypeMismatch = errors.New("file content does not match its extension")
+	ErrImageInvalid     = errors.New("image cannot be decoded")
+	ErrImageDimension   = errors.New("image dimensions exceed the limit")
+)
+
+var (
+	imageSemOnce sync.Once
+	imageSem     chan struct{}
+)
+
+// 去除元数据后保存在存储中的上传图片
+type Image struct {
+	Name        string // 去除元数据后的图片在存储中的文件名
+	ContentType string
+	Hash        string // 去除元数据后内容的 SHA-256
+	Size        int64
+	Orientation int // JPEG 的 EXIF 方向，生成衍生图时按方向校正
+}
+
+// 检查存储中图片的尺寸，去除元数据后写入新的临时文件，全程流式处理，不把图片读入内存
+// 先只解析头部检查尺寸，超出限制的图片不会被解码
+// JPEG 的 EXIF 只保留方向标签，原图不需要为校正方向而重新编码
+func PrepareImage(ctx context.Context, s Storage, name, contentType string) (*Image, error) {
+	if err := checkImageDimension(ctx, s, name); err != nil {
+		return nil, err
+	}
+
+	rc, err := s.Get(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+
+	tmpName, err := GetTempFileName()
+	if err != nil {
+		return nil, err
+	}
+	type stripResult struct {
+		orientation int
+		err         error
+	}
+	pr, pw := io.Pipe()
+	done := make(chan stripResult, 1)
+	go func() {
+		orientation, err := StripMetadata(pw, bufio.NewReader(rc), contentType)
+		pw.CloseWithError(err)
+		done <- stripResult{orientation, err}
+	}()
+	hr := NewHashReader(pr)
+	err = s.Put(ctx, tmpName, hr, -1, contentType)
+	// 存储提前返回时让去除元数据的协程退出
+	pr.CloseWithError(io.ErrClosedPipe)
+	result := <-done
+	if result.err != nil && result.err != io.ErrClosedPipe {
+		err = result.err
+	}
+	if err != nil {
+		s.Delete(ctx, tmpName)
+		return nil, err
+	}
+
+	return &Image{
+		Name:        tmpName,
+		ContentType: contentType,
+		Hash:        hr.Sum(),
+		Size:        hr.Size(),
+		Orientation: result.orientation,
+	}, nil
+}
+
+func checkImageDimension(ctx context.Context, s Storage, name string) error {
+	rc, err := s.Get(ctx, name)
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+
+	config, _, err := image.DecodeConfig(bufio.NewReader(rc))
+	if err != nil {
+		return ErrImageInvalid
+	}
+	if config.Width <= 0 || config.Height <= 0 ||
+		config.Width > global.AppSetting.UploadImageMaxWidth ||
+		config.Height > global.AppSetting.UploadImageMaxHeight {
+		return ErrImageDimension
+	}
+
+	return nil
+}
+
+// 流式读取并完整解码存储中的图片
+// 解码后的图片按像素占用内存，同时解码的数量受 UploadImageConcurrency 限制，需要先获取名额
+func decodeImage(ctx context.Context, s Storage, name string) (image.Image, error) {
+	rc, err := s.Get(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+
+	img, _, err := image.Decode(bufio.NewReader(rc))
+	if err != nil {
+		return nil, ErrImageInvalid
+	}
+
+	return img, nil
+}
+
+// 获取解码图片的名额，ctx 结束时放弃等待，成功后需要调用 releaseImage 归还
+func acquireImage(ctx context.Context) error {
+	imageSemOnce.Do(func() {
+		n := global.AppSetting.UploadImageConcurrency
+		if n <= 0 {
+			n = defaultImageConcurrency
+		}
+		imageSem = make(chan struct{}, n)
+	})
+
+	select {
+	case imageSem <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func releaseImage() {
+	<-imageSem
+}
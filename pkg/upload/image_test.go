@@ -0,0 +1,123 @@
+package upload
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"image"
+	"io/ioutil"
+	"testing"
+
+	"github.com/go-programming-tour/blog-service/global"
+	"github.com/go-programming-tour/blog-service/pkg/setting"
+)
+
+func setupImageSetting(t *testing.T, derivatives ...setting.ImageDerivativeSetting) {
+	old := global.AppSetting
+	global.AppSetting = &setting.AppSetting{
+		UploadImageMaxWidth:    100,
+		UploadImageMaxHeight:   100,
+		UploadImageConcurrency: 1,
+		UploadImageDerivatives: derivatives,
+	}
+	t.Cleanup(func() { global.AppSetting = old })
+}
+
+func readObject(t *testing.T, s Storage, name string) []byte {
+	t.Helper()
+	rc, err := s.Get(context.Background(), name)
+	if err != nil {
+		t.Fatalf("Get %s err: %v", name, err)
+	}
+	defer rc.Close()
+	data, err := ioutil.ReadAll(rc)
+	if err != nil {
+		t.Fatalf("ReadAll %s err: %v", name, err)
+	}
+
+	return data
+}
+
+func TestPrepareImage(t *testing.T) {
+	setupImageSetting(t, setting.ImageDerivativeSetting{Name: "thumb", Width: 10, Height: 10})
+	s := NewLocalStorage(t.TempDir(), "")
+	ctx := context.Background()
+	data := testJPEG(t, 40, 20, binary.BigEndian, 6)
+	if err := s.Put(ctx, "tmp/raw", bytes.NewReader(data), -1, "image/jpeg"); err != nil {
+		t.Fatalf("Put err: %v", err)
+	}
+
+	img, err := PrepareImage(ctx, s, "tmp/raw", "image/jpeg")
+	if err != nil {
+		t.Fatalf("PrepareImage err: %v", err)
+	}
+	if img.Orientation != 6 {
+		t.Errorf("Orientation = %d, want 6", img.Orientation)
+	}
+	stripped := readObject(t, s, img.Name)
+	assertNoMetadata(t, stripped)
+	if img.Size != int64(len(stripped)) {
+		t.Errorf("Size = %d, want %d", img.Size, len(stripped))
+	}
+	assertObject(t, s, "tmp/raw", string(data))
+
+	// 衍生图按方向校正，40x20 的图片顺时针旋转后缩放为 5x10
+	if err := GenerateDerivatives(ctx, s, "images/a.jpg", img); err != nil {
+		t.Fatalf("GenerateDerivatives err: %v", err)
+	}
+	thumb := readObject(t, s, "images/a_thumb.jpg")
+	assertNoMetadata(t, thumb)
+	if bytes.Contains(thumb, []byte(exifHeader)) {
+		t.Error("derivative should not contain EXIF")
+	}
+	config, _, err := image.DecodeConfig(bytes.NewReader(thumb))
+	if err != nil {
+		t.Fatalf("DecodeConfig err: %v", err)
+	}
+	if config.Width != 5 || config.Height != 10 {
+		t.Errorf("derivative size = %dx%d, want 5x10", config.Width, config.Height)
+	}
+}
+
+func TestPrepareImageRejectsLargeImages(t *testing.T) {
+	setupImageSetting(t)
+	s := NewLocalStorage(t.TempDir(), "")
+	ctx := context.Background()
+	data := testPNG(t, 101, 10)
+	if err := s.Put(ctx, "tmp/raw", bytes.NewReader(data), -1, "image/png"); err != nil {
+		t.Fatalf("Put err: %v", err)
+	}
+
+	if _, err := PrepareImage(ctx, s, "tmp/raw", "image/png"); !errors.Is(err, ErrImageDimension) {
+		t.Errorf("err = %v, want ErrImageDimension", err)
+	}
+}
+
+func TestGenerateDerivativesRespectsConcurrency(t *testing.T) {
+	setupImageSetting(t, setting.ImageDerivativeSetting{Name: "thumb", Width: 10})
+	s := NewLocalStorage(t.TempDir(), "")
+	ctx := context.Background()
+	if err := s.Put(ctx, "images/a.png", bytes.NewReader(testPNG(t, 20, 20)), -1, "image/png"); err != nil {
+		t.Fatalf("Put err: %v", err)
+	}
+	img := &Image{Name: "images/a.png", ContentType: "image/png", Orientation: 1}
+
+	// 名额用完时等待，ctx 结束后放弃生成
+	if err := acquireImage(ctx); err != nil {
+		t.Fatalf("acquireImage err: %v", err)
+	}
+	canceled, cancel := context.WithCancel(ctx)
+	cancel()
+	if err := GenerateDerivatives(canceled, s, "images/a.png", img); !errors.Is(err, context.Canceled) {
+		t.Errorf("err = %v, want context.Canceled", err)
+	}
+	releaseImage()
+
+	if err := GenerateDerivatives(ctx, s, "images/a.png", img); err != nil {
+		t.Fatalf("GenerateDerivatives err: %v", err)
+	}
+	if _, err := s.Stat(ctx, "images/a_thumb.png"); err != nil {
+		t.Errorf("Stat derivative err: %v", err)
+	}
+}
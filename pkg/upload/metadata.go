@@ -0,0 +1,247 @@
+package upload
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"io"
+	"io/ioutil"
+)
+
+const (
+	pngSignature = "\x89PNG\r\n\x1a\n"
+	// PNG 规范规定的块长度上限
+	pngMaxChunkLength = 1<<31 - 1
+	exifHeader        = "Exif\x00\x00"
+)
+
+// PNG 中可能携带拍摄信息或隐私内容的辅助块
+var pngMetadataChunks = map[string]bool{
+	"eXIf": true,
+	"tEXt": true,
+	"zTXt": true,
+	"iTXt": true,
+}
+
+// 流式去除图片中的 EXIF（包括 GPS 位置）、XMP、IPTC 等元数据，不重新编码图片
+// JPEG 的 EXIF 替换为只包含方向标签的最小 EXIF，返回原图的方向
+func StripMetadata(w io.Writer, r io.Reader, contentType string) (int, error) {
+	switch contentType {
+	case "image/jpeg":
+		return stripJPEGMetadata(w, r)
+	case "image/png":
+		return 1, stripPNGMetadata(w, r)
+	}
+
+	_, err := io.Copy(w, r)
+	return 1, err
+}
+
+// JPEG 由一系列段组成，去除 APP1（EXIF/XMP）、APP13（IPTC）和注释段
+// SOS 之后是压缩数据，原样保留，每次只需要缓存一个段
+func stripJPEGMetadata(w io.Writer, r io.Reader) (int, error) {
+	var marker [4]byte
+	if _, err := io.ReadFull(r, marker[:2]); err != nil || marker[0] != 0xFF || marker[1] != 0xD8 {
+		return 0, ErrImageInvalid
+	}
+	if _, err := w.Write(marker[:2]); err != nil {
+		return 0, err
+	}
+
+	orientation := 1
+	segment := make([]byte, 0, 4+1<<16)
+	for {
+		if _, err := io.ReadFull(r, marker[1:2]); err != nil {
+			return 0, ErrImageInvalid
+		}
+		if marker[1] != 0xFF {
+			return 0, ErrImageInvalid
+		}
+		// 段之间可能有多个填充字节
+		for marker[1] == 0xFF {
+			if _, err := io.ReadFull(r, marker[1:2]); err != nil {
+				return 0, ErrImageInvalid
+			}
+		}
+
+		switch m := marker[1]; {
+		case m == 0x01 || (m >= 0xD0 && m <= 0xD7):
+			// 没有长度字段的独立标记
+			if _, err := w.Write(marker[:2]); err != nil {
+				return 0, err
+			}
+			continue
+		case m == 0xDA || m == 0xD9:
+			if _, err := w.Write(marker[:2]); err != nil {
+				return 0, err
+			}
+			_, err := io.Copy(w, r)
+			return orientation, err
+		}
+
+		if _, err := io.ReadFull(r, marker[2:4]); err != nil {
+			return 0, ErrImageInvalid
+		}
+		length := int(binary.BigEndian.Uint16(marker[2:4]))
+		if length < 2 {
+			return 0, ErrImageInvalid
+		}
+		segment = append(segment[:0], marker[:]...)
+		segment = segment[:length+2]
+		if _, err := io.ReadFull(r, segment[4:]); err != nil {
+			return 0, ErrImageInvalid
+		}
+
+		switch marker[1] {
+		case 0xE1:
+			if !bytes.HasPrefix(segment[4:], []byte(exifHeader)) {
+				continue
+			}
+			orientation = exifOrientation(segment[4+len(exifHeader):])
+			if orientation > 1 {
+				if _, err := w.Write(orientationSegment(orientation)); err != nil {
+					return 0, err
+				}
+			}
+		case 0xED, 0xFE:
+		default:
+			if _, err := w.Write(segment); err != nil {
+				return 0, err
+			}
+		}
+	}
+}
+
+// 只包含 IFD0 方向标签的 APP1 段，图片的显示方向不受去除元数据影响
+func orientationSegment(orientation int) []byte {
+	segment := []byte{0xFF, 0xE1, 0, 0}
+	segment = append(segment, exifHeader...)
+	// 大端序的 TIFF 头，IFD0 紧跟在头部之后
+	segment = append(segment, 'M', 'M', 0, 0x2A, 0, 0, 0, 8)
+	// 一个条目：标签 0x0112，类型 SHORT，数量 1，值左对齐后补零，之后没有下一个 IFD
+	segment = append(segment, 0, 1, 0x01, 0x12, 0, 3, 0, 0, 0, 1, 0, byte(orientation), 0, 0, 0, 0, 0, 0)
+	binary.BigEndian.PutUint16(segment[2:], uint16(len(segment)-2))
+
+	return segment
+}
+
+// 从 EXIF 的 TIFF 数据中读取 IFD0 的方向标签（0x0112）
+func exifOrientation(tiff []byte) int {
+	if len(tiff) < 8 {
+		return 1
+	}
+	var order binary.ByteOrder
+	switch string(tiff[:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return 1
+	}
+
+	offset := int64(order.Uint32(tiff[4:8]))
+	if offset+2 > int64(len(tiff)) {
+		return 1
+	}
+	count := int64(order.Uint16(tiff[offset:]))
+	for i := int64(0); i < count; i++ {
+		p := offset + 2 + i*12
+		if p+12 > int64(len(tiff)) {
+			break
+		}
+		if order.Uint16(tiff[p:]) != 0x0112 {
+			continue
+		}
+		if o := int(order.Uint16(tiff[p+8:])); o >= 1 && o <= 8 {
+			return o
+		}
+		break
+	}
+
+	return 1
+}
+
+// PNG 由一系列块组成，去除元数据块后其余块原样保留，无需重新计算 CRC
+func stripPNGMetadata(w io.Writer, r io.Reader) error {
+	var header [8]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil || string(header[:]) != pngSignature {
+		return ErrImageInvalid
+	}
+	if _, err := w.Write(header[:]); err != nil {
+		return err
+	}
+
+	for {
+		// 块的长度和类型
+		if _, err := io.ReadFull(r, header[:]); err != nil {
+			return ErrImageInvalid
+		}
+		length := binary.BigEndian.Uint32(header[:4])
+		if length > pngMaxChunkLength {
+			return ErrImageInvalid
+		}
+		chunkType := string(header[4:])
+
+		dst := ioutil.Discard
+		if !pngMetadataChunks[chunkType] {
+			dst = w
+			if _, err := w.Write(header[:]); err != nil {
+				return err
+			}
+		}
+		// 块的数据和 CRC
+		n, err := io.CopyN(dst, r, int64(length)+4)
+		if err != nil {
+			if n < int64(length)+4 {
+				return ErrImageInvalid
+			}
+			return err
+		}
+		if chunkType == "IEND" {
+			return nil
+		}
+	}
+}
+
+// 按 EXIF 方向旋转或翻转图片，使其以正确的方向显示
+// 只用于缩放后的衍生图，避免为原图再分配一份完整的像素
+func orientImage(src *image.RGBA, orientation int) *image.RGBA {
+	if orientation < 2 || orientation > 8 {
+		return src
+	}
+
+	b := src.Bounds()
+	w, h := b.Dx(), b.Dy()
+	dw, dh := w, h
+	if orientation >= 5 {
+		dw, dh = h, w
+	}
+	dst := image.NewRGBA(image.Rect(0, 0, dw, dh))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			var dx, dy int
+			switch orientation {
+			case 2: // 水平翻转
+				dx, dy = w-1-x, y
+			case 3: // 旋转 180 度
+				dx, dy = w-1-x, h-1-y
+			case 4: // 垂直翻转
+				dx, dy = x, h-1-y
+			case 5: // 沿左上-右下对角线翻转
+				dx, dy = y, x
+			case 6: // 顺时针旋转 90 度
+				dx, dy = h-1-y, x
+			case 7: // 沿右上-左下对角线翻转
+				dx, dy = h-1-y, w-1-x
+			case 8: // 逆时针旋转 90 度
+				dx, dy = y, w-1-x
+			}
+			s := src.PixOffset(b.Min.X+x, b.Min.Y+y)
+			d := dst.PixOffset(dx, dy)
+			copy(dst.Pix[d:d+4], src.Pix[s:s+4])
+		}
+	}
+
+	return dst
+}
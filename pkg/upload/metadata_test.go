@@ -0,0 +1,270 @@
+package upload
+
+import (
+	"bytes"
+	"encoding/binary"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"testing"
+)
+
+// 写入测试图片的 GPS 信息，去除后不应再出现
+const gpsDatum = "GPS-DATUM-SECRET"
+
+// 生成带 EXIF 的 APP1 段：IFD0 包含方向和指向 GPS IFD 的标签，GPS IFD 包含纬度和测地基准
+func exifSegment(order binary.ByteOrder, orientation uint16) []byte {
+	var tiff bytes.Buffer
+	if order == binary.BigEndian {
+		tiff.WriteString("MM")
+	} else {
+		tiff.WriteString("II")
+	}
+	write := func(v interface{}) { binary.Write(&tiff, order, v) }
+	write(uint16(0x2A))
+	write(uint32(8))
+
+	// IFD0：两个条目，偏移 8 + 2 + 2*12 + 4 = 38 处为 GPS IFD
+	const gpsIFD = 38
+	write(uint16(2))
+	write([]uint16{0x0112, 3})
+	write(uint32(1))
+	write([]uint16{orientation, 0})
+	write([]uint16{0x8825, 4})
+	write(uint32(1))
+	write(uint32(gpsIFD))
+	write(uint32(0))
+
+	// GPS IFD：纬度参考、纬度和测地基准，数据放在 IFD 之后
+	const gpsData = gpsIFD + 2 + 3*12 + 4
+	write(uint16(3))
+	write([]uint16{0x0001, 2})
+	write(uint32(2))
+	tiff.Write([]byte{'N', 0, 0, 0})
+	write([]uint16{0x0002, 5})
+	write(uint32(3))
+	write(uint32(gpsData))
+	write([]uint16{0x0012, 2})
+	write(uint32(len(gpsDatum) + 1))
+	write(uint32(gpsData + 24))
+	write(uint32(0))
+	write([]uint32{39, 1, 54, 1, 2736, 100})
+	tiff.WriteString(gpsDatum + "\x00")
+
+	return jpegSegment(0xE1, append([]byte(exifHeader), tiff.Bytes()...))
+}
+
+func jpegSegment(marker byte, data []byte) []byte {
+	segment := []byte{0xFF, marker, 0, 0}
+	binary.BigEndian.PutUint16(segment[2:], uint16(len(data)+2))
+
+	return append(segment, data...)
+}
+
+func testImage(width, height int) image.Image {
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			img.Set(x, y, color.RGBA{uint8(x * 255 / width), uint8(y * 255 / height), 128, 255})
+		}
+	}
+
+	return img
+}
+
+// 在 SOI 之后插入 EXIF、XMP 和注释段
+func testJPEG(t *testing.T, width, height int, order binary.ByteOrder, orientation uint16) []byte {
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, testImage(width, height), nil); err != nil {
+		t.Fatalf("jpeg.Encode err: %v", err)
+	}
+	data := buf.Bytes()
+
+	out := append([]byte{}, data[:2]...)
+	out = append(out, exifSegment(order, orientation)...)
+	out = append(out, jpegSegment(0xE1, []byte("http://ns.adobe.com/xap/1.0/\x00<x:xmpmeta>"+gpsDatum+"</x:xmpmeta>"))...)
+	out = append(out, jpegSegment(0xFE, []byte("comment "+gpsDatum))...)
+
+	return append(out, data[2:]...)
+}
+
+func pngChunk(chunkType string, data []byte) []byte {
+	chunk := make([]byte, 8, 12+len(data))
+	binary.BigEndian.PutUint32(chunk, uint32(len(data)))
+	copy(chunk[4:], chunkType)
+	chunk = append(chunk, data...)
+	var crc [4]byte
+	binary.BigEndian.PutUint32(crc[:], crc32.ChecksumIEEE(chunk[4:]))
+
+	return append(chunk, crc[:]...)
+}
+
+// 在 IHDR 之后插入 eXIf、tEXt、zTXt 和 iTXt 块
+func testPNG(t *testing.T, width, height int) []byte {
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, testImage(width, height)); err != nil {
+		t.Fatalf("png.Encode err: %v", err)
+	}
+	data := buf.Bytes()
+
+	// 签名 8 字节，IHDR 块 25 字节
+	ihdrEnd := len(pngSignature) + 25
+	exif := exifSegment(binary.LittleEndian, 6)[4+len(exifHeader):]
+	out := append([]byte{}, data[:ihdrEnd]...)
+	out = append(out, pngChunk("eXIf", exif)...)
+	out = append(out, pngChunk("tEXt", []byte("Comment\x00"+gpsDatum))...)
+	out = append(out, pngChunk("zTXt", []byte("Comment\x00\x00"+gpsDatum))...)
+	out = append(out, pngChunk("iTXt", []byte("Comment\x00\x00\x00\x00\x00"+gpsDatum))...)
+
+	return append(out, data[ihdrEnd:]...)
+}
+
+func stripMetadata(t *testing.T, data []byte, contentType string) ([]byte, int) {
+	var out bytes.Buffer
+	orientation, err := StripMetadata(&out, bytes.NewReader(data), contentType)
+	if err != nil {
+		t.Fatalf("StripMetadata err: %v", err)
+	}
+
+	return out.Bytes(), orientation
+}
+
+func assertNoMetadata(t *testing.T, data []byte) {
+	t.Helper()
+	if bytes.Contains(data, []byte(gpsDatum)) {
+		t.Error("metadata is not stripped")
+	}
+	// GPS IFD 标签 0x8825 以任一字节序出现都说明 GPS 信息还在
+	if bytes.Contains(data, []byte{0x88, 0x25, 0, 4}) || bytes.Contains(data, []byte{0x25, 0x88, 4, 0}) {
+		t.Error("GPS IFD is not stripped")
+	}
+}
+
+func assertDecodes(t *testing.T, data []byte, width, height int) {
+	t.Helper()
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("image.Decode err: %v", err)
+	}
+	if b := img.Bounds(); b.Dx() != width || b.Dy() != height {
+		t.Errorf("size = %dx%d, want %dx%d", b.Dx(), b.Dy(), width, height)
+	}
+}
+
+func TestStripJPEGMetadata(t *testing.T) {
+	tests := []struct {
+		name        string
+		order       binary.ByteOrder
+		orientation uint16
+	}{
+		{"big endian", binary.BigEndian, 1},
+		{"little endian", binary.LittleEndian, 1},
+		{"rotated", binary.BigEndian, 6},
+		{"mirrored little endian", binary.LittleEndian, 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data := testJPEG(t, 40, 20, tt.order, tt.orientation)
+			if !bytes.Contains(data, []byte(gpsDatum)) {
+				t.Fatal("fixture does not contain GPS data")
+			}
+
+			out, orientation := stripMetadata(t, data, "image/jpeg")
+			if orientation != int(tt.orientation) {
+				t.Errorf("orientation = %d, want %d", orientation, tt.orientation)
+			}
+			assertNoMetadata(t, out)
+			assertDecodes(t, out, 40, 20)
+
+			// 只保留方向标签，方向为 1 时不保留 EXIF
+			exif := bytes.Contains(out, []byte(exifHeader))
+			if exif != (tt.orientation > 1) {
+				t.Errorf("EXIF kept = %v, want %v", exif, tt.orientation > 1)
+			}
+			if exif {
+				_, kept := stripMetadata(t, out, "image/jpeg")
+				if kept != int(tt.orientation) {
+					t.Errorf("kept orientation = %d, want %d", kept, tt.orientation)
+				}
+			}
+		})
+	}
+}
+
+func TestStripPNGMetadata(t *testing.T) {
+	data := testPNG(t, 40, 20)
+	if !bytes.Contains(data, []byte(gpsDatum)) {
+		t.Fatal("fixture does not contain GPS data")
+	}
+
+	out, orientation := stripMetadata(t, data, "image/png")
+	if orientation != 1 {
+		t.Errorf("orientation = %d, want 1", orientation)
+	}
+	assertNoMetadata(t, out)
+	for _, chunkType := range []string{"eXIf", "tEXt", "zTXt", "iTXt"} {
+		if bytes.Contains(out, []byte(chunkType)) {
+			t.Errorf("%s chunk is not stripped", chunkType)
+		}
+	}
+	assertDecodes(t, out, 40, 20)
+}
+
+func TestStripMetadataRejectsInvalidImages(t *testing.T) {
+	jpegData := testJPEG(t, 8, 8, binary.BigEndian, 1)
+	pngData := testPNG(t, 8, 8)
+	tests := []struct {
+		name        string
+		data        []byte
+		contentType string
+	}{
+		{"jpeg without SOI", pngData, "image/jpeg"},
+		{"truncated jpeg segment", jpegData[:10], "image/jpeg"},
+		{"png without signature", jpegData, "image/png"},
+		{"truncated png chunk", pngData[:40], "image/png"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+			if _, err := StripMetadata(&out, bytes.NewReader(tt.data), tt.contentType); err != ErrImageInvalid {
+				t.Errorf("err = %v, want ErrImageInvalid", err)
+			}
+		})
+	}
+}
+
+func TestOrientImage(t *testing.T) {
+	// 2x1 的图片，左边红色，右边蓝色
+	red, blue := color.RGBA{255, 0, 0, 255}, color.RGBA{0, 0, 255, 255}
+	src := image.NewRGBA(image.Rect(0, 0, 2, 1))
+	src.Set(0, 0, red)
+	src.Set(1, 0, blue)
+
+	tests := []struct {
+		orientation int
+		width       int
+		height      int
+		red         image.Point
+	}{
+		{1, 2, 1, image.Pt(0, 0)},
+		{2, 2, 1, image.Pt(1, 0)},
+		{3, 2, 1, image.Pt(1, 0)},
+		{4, 2, 1, image.Pt(0, 0)},
+		{5, 1, 2, image.Pt(0, 0)},
+		{6, 1, 2, image.Pt(0, 0)},
+		{7, 1, 2, image.Pt(0, 1)},
+		{8, 1, 2, image.Pt(0, 1)},
+	}
+	for _, tt := range tests {
+		dst := orientImage(src, tt.orientation)
+		if b := dst.Bounds(); b.Dx() != tt.width || b.Dy() != tt.height {
+			t.Errorf("orientation %d: size = %dx%d, want %dx%d", tt.orientation, b.Dx(), b.Dy(), tt.width, tt.height)
+			continue
+		}
+		if dst.RGBAAt(tt.red.X, tt.red.Y) != red {
+			t.Errorf("orientation %d: red pixel is not at %v", tt.orientation, tt.red)
+		}
+	}
+}
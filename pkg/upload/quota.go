@@ -0,0 +1,46 @@
+package upload
+
+import (
+	"errors"
+
+	"github.com/go-programming-tour/blog-service/global"
+	"github.com/go-programming-tour/blog-service/pkg/setting"
+)
+
+var ErrQuotaExceeded = errors.New("upload quota exceeded")
+
+// 上传配额，为 0 时表示不限制
+type Quota struct {
+	MaxSize  int64 // 单位字节
+	MaxFiles int
+}
+
+// 返回指定角色的用户配额，没有按角色配置时使用默认的用户配额
+func GetUserQuota(role string) Quota {
+	if roleQuota, ok := global.AppSetting.UploadRoleQuotas[role]; ok && roleQuota != nil {
+		return newQuota(*roleQuota)
+	}
+
+	return newQuota(global.AppSetting.UploadUserQuota)
+}
+
+// 返回整个服务的总配额
+func GetTotalQuota() Quota {
+	return newQuota(global.AppSetting.UploadTotalQuota)
+}
+
+// 检查在已使用 usedSize 字节、usedFiles 个文件的基础上能否再保存一个 size 字节的文件
+func (q Quota) Allow(usedSize int64, usedFiles int, size int64) bool {
+	if q.MaxSize > 0 && usedSize+size > q.MaxSize {
+		return false
+	}
+	if q.MaxFiles > 0 && usedFiles+1 > q.MaxFiles {
+		return false
+	}
+
+	return true
+}
+
+func newQuota(s setting.UploadQuotaSetting) Quota {
+	return Quota{MaxSize: int64(s.MaxSize) * 1024 * 1024, MaxFiles: s.MaxFiles}
+}
@@ -0,0 +1,56 @@
+package upload
+
+import (
+	"testing"
+
+	"github.com/go-programming-tour/blog-service/global"
+	"github.com/go-programming-tour/blog-service/pkg/setting"
+)
+
+func TestQuotaAllow(t *testing.T) {
+	tests := []struct {
+		name      string
+		quota     Quota
+		usedSize  int64
+		usedFiles int
+		size      int64
+		want      bool
+	}{
+		{"unlimited", Quota{}, 1 << 40, 1 << 20, 1 << 30, true},
+		{"within size", Quota{MaxSize: 100}, 60, 0, 40, true},
+		{"exceeds size", Quota{MaxSize: 100}, 60, 0, 41, false},
+		{"size already used up", Quota{MaxSize: 100}, 100, 0, 0, true},
+		{"within files", Quota{MaxFiles: 2}, 0, 1, 10, true},
+		{"exceeds files", Quota{MaxFiles: 2}, 0, 2, 10, false},
+	}
+	for _, tt := range tests {
+		if got := tt.quota.Allow(tt.usedSize, tt.usedFiles, tt.size); got != tt.want {
+			t.Errorf("%s: Allow = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestGetQuota(t *testing.T) {
+	old := global.AppSetting
+	global.AppSetting = &setting.AppSetting{
+		UploadUserQuota:  setting.UploadQuotaSetting{MaxSize: 1, MaxFiles: 10},
+		UploadRoleQuotas: map[string]*setting.UploadQuotaSetting{"admin": {}},
+		UploadTotalQuota: setting.UploadQuotaSetting{MaxSize: 2, MaxFiles: 5},
+	}
+	t.Cleanup(func() { global.AppSetting = old })
+
+	tests := []struct {
+		name string
+		got  Quota
+		want Quota
+	}{
+		{"default user", GetUserQuota("author"), Quota{MaxSize: 1 << 20, MaxFiles: 10}},
+		{"role override", GetUserQuota("admin"), Quota{}},
+		{"total", GetTotalQuota(), Quota{MaxSize: 2 << 20, MaxFiles: 5}},
+	}
+	for _, tt := range tests {
+		if tt.got != tt.want {
+			t.Errorf("%s: quota = %+v, want %+v", tt.name, tt.got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,108 @@
+package upload
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"hash"
+	"io"
+	"strings"
+)
+
+var ErrFileTooLarge = errors.New("exceeded maximum file limit")
+
+// http.MaxBytesReader 超限时返回的错误信息，当前的 Go 版本没有导出对应的错误类型
+const maxBytesReaderMessage = "http: request body too large"
+
+// 判断错误是否因为文件或请求体超出大小限制，所有超限的判断都应通过它进行
+func IsTooLarge(err error) bool {
+	if err == nil {
+		return false
+	}
+
+	return errors.Is(err, ErrFileTooLarge) || strings.Contains(err.Error(), maxBytesReaderMessage)
+}
+
+// 限制可读取的字节数，超出时返回 ErrFileTooLarge
+// 与 io.LimitReader 不同，超出限制不会被静默截断
+type SizeLimitReader struct {
+	r     io.Reader
+	limit int64
+	read  int64
+}
+
+func NewSizeLimitReader(r io.Reader, limit int64) *SizeLimitReader {
+	return &SizeLimitReader{r: r, limit: limit}
+}
+
+func (l *SizeLimitReader) Read(p []byte) (int, error) {
+	// 多读一个字节用于判断是否超出限制
+	if max := l.limit - l.read + 1; int64(len(p)) > max {
+		p = p[:max]
+	}
+	n, err := l.r.Read(p)
+	l.read += int64(n)
+	if l.read > l.limit {
+		return n - int(l.read-l.limit), ErrFileTooLarge
+	}
+
+	return n, err
+}
+
+// 读取的同时计算内容的 SHA-256 和长度
+type HashReader struct {
+	r    io.Reader
+	hash hash.Hash
+	size int64
+}
+
+func NewHashReader(r io.Reader) *HashReader {
+	return &HashReader{r: r, hash: sha256.New()}
+}
+
+func (h *HashReader) Read(p []byte) (int, error) {
+	n, err := h.r.Read(p)
+	h.hash.Write(p[:n])
+	h.size += int64(n)
+
+	return n, err
+}
+
+// 返回已读取内容的十六进制 SHA-256
+func (h *HashReader) Sum() string {
+	return hex.EncodeToString(h.hash.Sum(nil))
+}
+
+// 返回已读取的字节数
+func (h *HashReader) Size() int64 {
+	return h.size
+}
+
+// 检查读取的内容都是 UTF-8 文本，否则返回 ErrFileTypeMismatch
+// 文件开头的类型检测只使用前 512 个字节，文本文件的其余内容由它检查
+type TextReader struct {
+	r       io.Reader
+	pending []byte // 上次读取末尾不完整的字符
+}
+
+func NewTextReader(r io.Reader) *TextReader {
+	return &TextReader{r: r}
+}
+
+func (t *TextReader) Read(p []byte) (int, error) {
+	n, err := t.r.Read(p)
+	data := p[:n]
+	if len(t.pending) > 0 {
+		data = append(t.pending, data...)
+	}
+	keep := 0
+	if err == nil {
+		keep = incompleteRuneSuffix(data)
+	}
+	if !isText(data[:len(data)-keep]) {
+		return 0, ErrFileTypeMismatch
+	}
+	t.pending = append(t.pending[:0:0], data[len(data)-keep:]...)
+
+	return n, err
+}
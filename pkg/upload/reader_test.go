@@ -0,0 +1,125 @@
+package upload
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestSizeLimitReader(t *testing.T) {
+	tests := []struct {
+		data    string
+		limit   int64
+		wantErr error
+	}{
+		{"", 4, nil},
+		{"abcd", 4, nil},
+		{"abcde", 4, ErrFileTooLarge},
+	}
+	for _, tt := range tests {
+		got, err := ioutil.ReadAll(NewSizeLimitReader(strings.NewReader(tt.data), tt.limit))
+		if err != tt.wantErr {
+			t.Errorf("read %q with limit %d err = %v, want %v", tt.data, tt.limit, err, tt.wantErr)
+		}
+		if int64(len(got)) > tt.limit {
+			t.Errorf("read %q with limit %d returned %d bytes", tt.data, tt.limit, len(got))
+		}
+	}
+}
+
+func TestHashReader(t *testing.T) {
+	hr := NewHashReader(strings.NewReader("hello"))
+	if _, err := io.Copy(ioutil.Discard, hr); err != nil {
+		t.Fatal(err)
+	}
+	if hr.Size() != 5 || hr.Sum() != "2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824" {
+		t.Errorf("Size() = %d, Sum() = %s", hr.Size(), hr.Sum())
+	}
+}
+
+// 上传接口依赖 IsTooLarge 识别 http.MaxBytesReader 的超限错误，Go 升级后错误信息变化时该测试会失败
+func TestIsTooLargeWithMaxBytesReader(t *testing.T) {
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	fw, _ := mw.CreateFormFile("file", "a.png")
+	fw.Write(bytes.Repeat([]byte("x"), 4096))
+	mw.Close()
+
+	req := httptest.NewRequest(http.MethodPost, "/upload/file", &body)
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	req.Body = http.MaxBytesReader(httptest.NewRecorder(), req.Body, 1024)
+	reader, err := req.MultipartReader()
+	if err != nil {
+		t.Fatal(err)
+	}
+	part, err := reader.NextPart()
+	if err == nil {
+		_, err = io.Copy(ioutil.Discard, part)
+	}
+	if !IsTooLarge(err) {
+		t.Fatalf("IsTooLarge(%v) = false, want true", err)
+	}
+}
+
+func TestIsTooLarge(t *testing.T) {
+	tests := []struct {
+		err  error
+		want bool
+	}{
+		{nil, false},
+		{io.ErrUnexpectedEOF, false},
+		{ErrFileTypeMismatch, false},
+		{ErrFileTooLarge, true},
+		{fmt.Errorf("put object: %w", ErrFileTooLarge), true},
+		{errors.New("multipart: NextPart: http: request body too large"), true},
+	}
+	for _, tt := range tests {
+		if got := IsTooLarge(tt.err); got != tt.want {
+			t.Errorf("IsTooLarge(%v) = %v, want %v", tt.err, got, tt.want)
+		}
+	}
+}
+
+// 每次只返回一个字节，检查多字节字符跨越多次读取的情况
+type byteReader struct {
+	data []byte
+}
+
+func (r *byteReader) Read(p []byte) (int, error) {
+	if len(r.data) == 0 {
+		return 0, io.EOF
+	}
+	p[0] = r.data[0]
+	r.data = r.data[1:]
+
+	return 1, nil
+}
+
+func TestTextReader(t *testing.T) {
+	tests := []struct {
+		name string
+		r    io.Reader
+		want error
+	}{
+		{"text", strings.NewReader("# 标题\n<div>正文</div>\n"), nil},
+		{"runes split across reads", &byteReader{[]byte("中文 Markdown")}, nil},
+		{"invalid utf-8", strings.NewReader("abc\xff"), ErrFileTypeMismatch},
+		{"nul byte", strings.NewReader("abc\x00def"), ErrFileTypeMismatch},
+		{"truncated rune at eof", &byteReader{[]byte("中文")[:5]}, ErrFileTypeMismatch},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ioutil.ReadAll(NewTextReader(tt.r))
+			if err != tt.want {
+				t.Errorf("err = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,101 @@
+package upload
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-programming-tour/blog-service/global"
+)
+
+// 私有文件在存储中的前缀
+const PrivatePrefix = "private/"
+
+var ErrSignatureInvalid = errors.New("signature is invalid or expired")
+
+// 上传过程中使用的内部文件，不允许直接访问
+var internalPrefixes = []string{"tmp/", "chunks/"}
+
+// 检查文件是否为私有文件
+func IsPrivate(name string) bool {
+	return strings.HasPrefix(name, PrivatePrefix)
+}
+
+// 检查文件是否为上传过程中使用的内部文件
+func IsInternal(name string) bool {
+	for _, prefix := range internalPrefixes {
+		if strings.HasPrefix(name, prefix) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// 返回文件的访问地址，私有文件返回签名地址
+// userID 不为 0 时签名地址只允许该用户访问，expire 为 0 时使用配置的有效期
+func GetFileURL(name string, private bool, userID uint32, expire time.Duration) string {
+	if !private {
+		return GetStorage().URL(name)
+	}
+	if expire <= 0 {
+		expire = global.AppSetting.UploadSignedUrlExpire
+	}
+
+	return SignURL(name, userID, time.Now().Add(expire))
+}
+
+// 生成私有文件的签名地址，签名覆盖文件名、过期时间和绑定的用户
+func SignURL(name string, userID uint32, expiresAt time.Time) string {
+	expires := expiresAt.Unix()
+	query := url.Values{}
+	query.Set("expires", strconv.FormatInt(expires, 10))
+	if userID != 0 {
+		query.Set("uid", strconv.FormatUint(uint64(userID), 10))
+	}
+	query.Set("sig", sign(name, expires, userID))
+
+	return privateServerURL() + "/" + name + "?" + query.Encode()
+}
+
+// 校验签名地址的参数，返回绑定的用户 ID，未绑定用户时返回 0
+func VerifySignature(name, expires, uid, sig string) (uint32, error) {
+	expiresAt, err := strconv.ParseInt(expires, 10, 64)
+	if err != nil || expiresAt < time.Now().Unix() {
+		return 0, ErrSignatureInvalid
+	}
+	var userID uint64
+	if uid != "" {
+		if userID, err = strconv.ParseUint(uid, 10, 32); err != nil {
+			return 0, ErrSignatureInvalid
+		}
+	}
+	if !hmac.Equal([]byte(sign(name, expiresAt, uint32(userID))), []byte(sig)) {
+		return 0, ErrSignatureInvalid
+	}
+
+	return uint32(userID), nil
+}
+
+func sign(name string, expires int64, userID uint32) string {
+	mac := hmac.New(sha256.New, []byte(global.AppSetting.UploadSignSecret))
+	fmt.Fprintf(mac, "%s\n%d\n%d", name, expires, userID)
+
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// 私有文件由服务自身提供访问，未配置时与本地存储的访问地址相同
+func privateServerURL() string {
+	serverURL := global.AppSetting.UploadPrivateServerUrl
+	if serverURL == "" {
+		serverURL = global.AppSetting.UploadServerUrl
+	}
+
+	return strings.TrimSuffix(serverURL, "/")
+}
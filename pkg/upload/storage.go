@@ -0,0 +1,72 @@
+package upload
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/go-programming-tour/blog-service/pkg/setting"
+)
+
+// 支持的存储后端
+const (
+	StorageLocal = "local"
+	StorageS3    = "s3"
+)
+
+var ErrObjectNotExist = errors.New("object does not exist")
+
+// 文件的元信息
+type ObjectInfo struct {
+	Name        string
+	Size        int64
+	ContentType string
+	ModTime     time.Time
+}
+
+// 上传文件的存储后端，name 为相对路径，使用 / 分隔
+type Storage interface {
+	// 保存文件，size 未知时传 -1
+	Put(ctx context.Context, name string, r io.Reader, size int64, contentType string) error
+	// 读取文件，文件不存在时返回 ErrObjectNotExist
+	Get(ctx context.Context, name string) (io.ReadCloser, error)
+	// 删除文件，文件不存在时不返回错误
+	Delete(ctx context.Context, name string) error
+	// 返回文件的元信息，文件不存在时返回 ErrObjectNotExist
+	Stat(ctx context.Context, name string) (*ObjectInfo, error)
+	// 移动文件，目标已存在时覆盖
+	Move(ctx context.Context, src, dst string) error
+	// 返回文件的访问地址
+	URL(name string) string
+}
+
+var storage Storage
+
+// 根据配置创建存储后端，启动时调用
+func SetupStorage(appSetting *setting.AppSetting, s3Setting *setting.S3SettingS) error {
+	if appSetting.UploadSignSecret == "" {
+		return errors.New("UploadSignSecret is required for signing private file urls")
+	}
+
+	switch appSetting.UploadStorage {
+	case "", StorageLocal:
+		storage = NewLocalStorage(appSetting.UploadSavePath, appSetting.UploadServerUrl)
+	case StorageS3:
+		s, err := NewS3Storage(s3Setting)
+		if err != nil {
+			return err
+		}
+		storage = s
+	default:
+		return fmt.Errorf("unsupported upload storage %q", appSetting.UploadStorage)
+	}
+
+	return nil
+}
+
+// 返回当前使用的存储后端
+func GetStorage() Storage {
+	return storage
+}
@@ -0,0 +1,60 @@
+package upload
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLocalStorage(t *testing.T) {
+	testStorage(t, NewLocalStorage(t.TempDir(), "http://127.0.0.1:8000/static/"))
+}
+
+func TestLocalStoragePutIsAtomic(t *testing.T) {
+	root := t.TempDir()
+	s := NewLocalStorage(root, "")
+	ctx := context.Background()
+	if err := s.Put(ctx, "a/b.txt", strings.NewReader("old"), -1, "text/plain"); err != nil {
+		t.Fatalf("Put err: %v", err)
+	}
+
+	// 读取失败时保留原有内容，并且不留下临时文件
+	err := s.Put(ctx, "a/b.txt", &failingReader{}, -1, "text/plain")
+	if err == nil {
+		t.Fatal("Put with a failing reader should return an error")
+	}
+	assertObject(t, s, "a/b.txt", "old")
+	entries, err := os.ReadDir(filepath.Join(root, "a"))
+	if err != nil {
+		t.Fatalf("ReadDir err: %v", err)
+	}
+	if len(entries) != 1 {
+		t.Errorf("temporary files left behind: %v", entries)
+	}
+}
+
+func TestLocalStorageRejectsInvalidNames(t *testing.T) {
+	s := NewLocalStorage(t.TempDir(), "")
+	ctx := context.Background()
+	for _, name := range []string{"", "/abs", "../escape", "a/../../escape", "a//b", "a/./b"} {
+		if err := s.Put(ctx, name, strings.NewReader("x"), 1, ""); err == nil {
+			t.Errorf("Put(%q) should be rejected", name)
+		}
+		if _, err := s.Get(ctx, name); err == nil {
+			t.Errorf("Get(%q) should be rejected", name)
+		}
+	}
+}
+
+type failingReader struct{ read bool }
+
+func (r *failingReader) Read(p []byte) (int, error) {
+	if r.read {
+		return 0, errors.New("read failed")
+	}
+	r.read = true
+	return copy(p, "partial"), nil
+}
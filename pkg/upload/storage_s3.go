@@ -0,0 +1,126 @@
+package upload
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+
+	"github.com/go-programming-tour/blog-service/pkg/setting"
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
+)
+
+// S3 兼容的对象存储，可以对接 AWS S3、MinIO 等服务
+// 公开文件保存在可以公开读取的 Bucket 中，私有文件、临时文件和分片保存在不公开的 PrivateBucket 中
+// 私有文件只能通过服务端校验签名后读取，签名过期后无法绕过服务端直接访问
+type S3Storage struct {
+	client        *minio.Client
+	bucket        string
+	privateBucket string
+	publicUrl     string
+}
+
+func NewS3Storage(s *setting.S3SettingS) (*S3Storage, error) {
+	if s == nil || s.Endpoint == "" || s.Bucket == "" {
+		return nil, errors.New("s3 endpoint and bucket must be configured")
+	}
+	if s.PrivateBucket == "" || s.PrivateBucket == s.Bucket {
+		return nil, errors.New("s3 private bucket must be configured and differ from the public bucket")
+	}
+
+	client, err := minio.New(s.Endpoint, &minio.Options{
+		Creds:  credentials.NewStaticV4(s.AccessKey, s.SecretKey, ""),
+		Secure: s.UseSSL,
+		Region: s.Region,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	publicUrl := strings.TrimSuffix(s.PublicUrl, "/")
+	if publicUrl == "" {
+		// 未配置访问地址时使用 path-style 的存储桶地址
+		publicUrl = strings.TrimSuffix(client.EndpointURL().String(), "/") + "/" + s.Bucket
+	}
+
+	return &S3Storage{client: client, bucket: s.Bucket, privateBucket: s.PrivateBucket, publicUrl: publicUrl}, nil
+}
+
+// 返回文件所在的存储桶，私有文件、临时文件和分片不能放在公开的存储桶中
+func (s *S3Storage) bucketFor(name string) string {
+	if IsPrivate(name) || IsInternal(name) {
+		return s.privateBucket
+	}
+
+	return s.bucket
+}
+
+// 大小未知时按 5MB 分片上传，避免 minio 按最大对象大小分配分片缓冲
+func (s *S3Storage) Put(ctx context.Context, name string, r io.Reader, size int64, contentType string) error {
+	opts := minio.PutObjectOptions{ContentType: contentType}
+	if size < 0 {
+		opts.PartSize = 5 * 1024 * 1024
+	}
+	_, err := s.client.PutObject(ctx, s.bucketFor(name), name, r, size, opts)
+	return err
+}
+
+// minio 的 GetObject 是惰性的，先 Stat 一次以便返回 ErrObjectNotExist
+func (s *S3Storage) Get(ctx context.Context, name string) (io.ReadCloser, error) {
+	obj, err := s.client.GetObject(ctx, s.bucketFor(name), name, minio.GetObjectOptions{})
+	if err != nil {
+		return nil, convertS3Error(err)
+	}
+	if _, err := obj.Stat(); err != nil {
+		obj.Close()
+		return nil, convertS3Error(err)
+	}
+
+	return obj, nil
+}
+
+func (s *S3Storage) Delete(ctx context.Context, name string) error {
+	return s.client.RemoveObject(ctx, s.bucketFor(name), name, minio.RemoveObjectOptions{})
+}
+
+func (s *S3Storage) Stat(ctx context.Context, name string) (*ObjectInfo, error) {
+	info, err := s.client.StatObject(ctx, s.bucketFor(name), name, minio.StatObjectOptions{})
+	if err != nil {
+		return nil, convertS3Error(err)
+	}
+
+	return &ObjectInfo{
+		Name:        name,
+		Size:        info.Size,
+		ContentType: info.ContentType,
+		ModTime:     info.LastModified,
+	}, nil
+}
+
+// 服务端复制后删除源文件，源和目标可以在不同的存储桶中
+func (s *S3Storage) Move(ctx context.Context, src, dst string) error {
+	_, err := s.client.CopyObject(ctx,
+		minio.CopyDestOptions{Bucket: s.bucketFor(dst), Object: dst},
+		minio.CopySrcOptions{Bucket: s.bucketFor(src), Object: src},
+	)
+	if err != nil {
+		return convertS3Error(err)
+	}
+
+	return s.client.RemoveObject(ctx, s.bucketFor(src), src, minio.RemoveObjectOptions{})
+}
+
+// 公开存储桶中的访问地址，私有文件需要使用 GetFileURL 返回的签名地址
+func (s *S3Storage) URL(name string) string {
+	return s.publicUrl + "/" + name
+}
+
+func convertS3Error(err error) error {
+	switch minio.ToErrorResponse(err).Code {
+	case "NoSuchKey", "NotFound":
+		return ErrObjectNotExist
+	}
+
+	return err
+}
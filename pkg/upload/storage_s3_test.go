@@ -0,0 +1,279 @@
+package upload
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-programming-tour/blog-service/pkg/setting"
+)
+
+// 设置 S3_TEST_ENDPOINT 等环境变量时对真实的 MinIO 运行，否则使用进程内的 S3 替身
+func newTestS3Storage(t *testing.T) *S3Storage {
+	s := &setting.S3SettingS{
+		Endpoint:      os.Getenv("S3_TEST_ENDPOINT"),
+		AccessKey:     os.Getenv("S3_TEST_ACCESS_KEY"),
+		SecretKey:     os.Getenv("S3_TEST_SECRET_KEY"),
+		Bucket:        os.Getenv("S3_TEST_BUCKET"),
+		PrivateBucket: os.Getenv("S3_TEST_PRIVATE_BUCKET"),
+		Region:        "us-east-1",
+	}
+	if s.Endpoint == "" {
+		server := httptest.NewServer(newFakeS3())
+		t.Cleanup(server.Close)
+		s.Endpoint = strings.TrimPrefix(server.URL, "http://")
+		s.AccessKey, s.SecretKey = "minioadmin", "minioadmin"
+		s.Bucket, s.PrivateBucket = "blog-service", "blog-service-private"
+	}
+
+	storage, err := NewS3Storage(s)
+	if err != nil {
+		t.Fatalf("NewS3Storage err: %v", err)
+	}
+
+	return storage
+}
+
+func TestS3Storage(t *testing.T) {
+	testStorage(t, newTestS3Storage(t))
+}
+
+// 大小未知且超过一个分片时使用分片上传
+func TestS3StorageMultipartPut(t *testing.T) {
+	s := newTestS3Storage(t)
+	data := bytes.Repeat([]byte("0123456789"), 600*1024)
+	if err := s.Put(context.Background(), "videos/large.mp4", bytes.NewReader(data), -1, "video/mp4"); err != nil {
+		t.Fatalf("Put err: %v", err)
+	}
+	assertObject(t, s, "videos/large.mp4", string(data))
+}
+
+// 私有文件、临时文件和分片不在公开的存储桶中，无法通过公开地址读取
+func TestS3StorageKeepsPrivateObjectsOutOfPublicBucket(t *testing.T) {
+	s := newTestS3Storage(t)
+	ctx := context.Background()
+	for _, name := range []string{"private/images/a.png", "tmp/abc", GetChunkName("abc", 0)} {
+		if err := s.Put(ctx, name, strings.NewReader("secret"), -1, "image/png"); err != nil {
+			t.Fatalf("Put %s err: %v", name, err)
+		}
+		assertObject(t, s, name, "secret")
+
+		resp, err := http.Get(s.URL(name))
+		if err != nil {
+			t.Fatalf("http.Get %s err: %v", name, err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode == http.StatusOK {
+			t.Errorf("%s is readable from the public bucket", name)
+		}
+	}
+
+	// 临时文件移动到正式位置时跨存储桶复制
+	if err := s.Move(ctx, "tmp/abc", "images/ab/abc.png"); err != nil {
+		t.Fatalf("Move err: %v", err)
+	}
+	assertObject(t, s, "images/ab/abc.png", "secret")
+	if _, err := s.Stat(ctx, "tmp/abc"); err != ErrObjectNotExist {
+		t.Errorf("Stat moved object err = %v, want ErrObjectNotExist", err)
+	}
+	if err := s.Move(ctx, "images/ab/abc.png", "private/images/ab/abc.png"); err != nil {
+		t.Fatalf("Move err: %v", err)
+	}
+	assertObject(t, s, "private/images/ab/abc.png", "secret")
+}
+
+func TestNewS3StorageRequiresPrivateBucket(t *testing.T) {
+	for _, privateBucket := range []string{"", "blog-service"} {
+		_, err := NewS3Storage(&setting.S3SettingS{Endpoint: "127.0.0.1:9000", Bucket: "blog-service", PrivateBucket: privateBucket})
+		if err == nil {
+			t.Errorf("NewS3Storage with private bucket %q should fail", privateBucket)
+		}
+	}
+}
+
+// 只实现 S3Storage 用到的请求：对象的增删查、复制和分片上传，使用 path-style 地址
+type fakeS3 struct {
+	mu      sync.Mutex
+	objects map[string]*fakeObject
+	uploads map[string]*fakeUpload
+	nextID  int
+}
+
+type fakeUpload struct {
+	parts       map[int][]byte
+	contentType string
+}
+
+type fakeObject struct {
+	data        []byte
+	contentType string
+	modTime     time.Time
+}
+
+func newFakeS3() *fakeS3 {
+	return &fakeS3{objects: map[string]*fakeObject{}, uploads: map[string]*fakeUpload{}}
+}
+
+func (f *fakeS3) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	parts := strings.SplitN(strings.TrimPrefix(r.URL.Path, "/"), "/", 2)
+	if len(parts) != 2 || parts[1] == "" {
+		writeS3Error(w, http.StatusNotImplemented, "NotImplemented")
+		return
+	}
+	// 对象按存储桶分别保存
+	key := parts[0] + "/" + parts[1]
+	query := r.URL.Query()
+
+	switch {
+	case r.Method == http.MethodPost && query.Has("uploads"):
+		f.nextID++
+		uploadID := strconv.Itoa(f.nextID)
+		f.uploads[uploadID] = &fakeUpload{parts: map[int][]byte{}, contentType: r.Header.Get("Content-Type")}
+		writeXML(w, struct {
+			XMLName  xml.Name `xml:"InitiateMultipartUploadResult"`
+			Bucket   string
+			Key      string
+			UploadId string
+		}{Bucket: parts[0], Key: parts[1], UploadId: uploadID})
+	case r.Method == http.MethodPut && query.Has("uploadId"):
+		upload, ok := f.uploads[query.Get("uploadId")]
+		if !ok {
+			writeS3Error(w, http.StatusNotFound, "NoSuchUpload")
+			return
+		}
+		data, _ := readS3Body(r)
+		number, _ := strconv.Atoi(query.Get("partNumber"))
+		upload.parts[number] = data
+		w.Header().Set("ETag", etag(data))
+	case r.Method == http.MethodPost && query.Has("uploadId"):
+		upload, ok := f.uploads[query.Get("uploadId")]
+		if !ok {
+			writeS3Error(w, http.StatusNotFound, "NoSuchUpload")
+			return
+		}
+		numbers := make([]int, 0, len(upload.parts))
+		for number := range upload.parts {
+			numbers = append(numbers, number)
+		}
+		sort.Ints(numbers)
+		var data []byte
+		for _, number := range numbers {
+			data = append(data, upload.parts[number]...)
+		}
+		delete(f.uploads, query.Get("uploadId"))
+		f.objects[key] = &fakeObject{data: data, contentType: upload.contentType, modTime: time.Now()}
+		writeXML(w, struct {
+			XMLName xml.Name `xml:"CompleteMultipartUploadResult"`
+			Bucket  string
+			Key     string
+			ETag    string
+		}{Bucket: parts[0], Key: parts[1], ETag: etag(data)})
+	case r.Method == http.MethodDelete && query.Has("uploadId"):
+		delete(f.uploads, query.Get("uploadId"))
+		w.WriteHeader(http.StatusNoContent)
+	case r.Method == http.MethodPut && r.Header.Get("X-Amz-Copy-Source") != "":
+		source, _ := url.PathUnescape(r.Header.Get("X-Amz-Copy-Source"))
+		obj, ok := f.objects[strings.TrimPrefix(source, "/")]
+		if !ok {
+			writeS3Error(w, http.StatusNotFound, "NoSuchKey")
+			return
+		}
+		copied := *obj
+		copied.modTime = time.Now()
+		f.objects[key] = &copied
+		writeXML(w, struct {
+			XMLName      xml.Name `xml:"CopyObjectResult"`
+			ETag         string
+			LastModified string
+		}{ETag: etag(obj.data), LastModified: copied.modTime.UTC().Format(time.RFC3339)})
+	case r.Method == http.MethodPut:
+		data, err := readS3Body(r)
+		if err != nil {
+			writeS3Error(w, http.StatusBadRequest, "IncompleteBody")
+			return
+		}
+		f.objects[key] = &fakeObject{data: data, contentType: r.Header.Get("Content-Type"), modTime: time.Now()}
+		w.Header().Set("ETag", etag(data))
+	case r.Method == http.MethodGet || r.Method == http.MethodHead:
+		obj, ok := f.objects[key]
+		if !ok {
+			writeS3Error(w, http.StatusNotFound, "NoSuchKey")
+			return
+		}
+		w.Header().Set("Content-Type", obj.contentType)
+		w.Header().Set("Content-Length", strconv.Itoa(len(obj.data)))
+		w.Header().Set("Last-Modified", obj.modTime.UTC().Format(http.TimeFormat))
+		w.Header().Set("ETag", etag(obj.data))
+		if r.Method == http.MethodGet {
+			w.Write(obj.data)
+		}
+	case r.Method == http.MethodDelete:
+		delete(f.objects, key)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		writeS3Error(w, http.StatusNotImplemented, "NotImplemented")
+	}
+}
+
+// 客户端通过 HTTP 上传时使用 aws-chunked 编码的流式签名
+func readS3Body(r *http.Request) ([]byte, error) {
+	if r.Header.Get("X-Amz-Content-Sha256") != "STREAMING-AWS4-HMAC-SHA256-PAYLOAD" {
+		return ioutil.ReadAll(r.Body)
+	}
+
+	var data []byte
+	br := bufio.NewReader(r.Body)
+	for {
+		line, err := br.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+		size, err := strconv.ParseInt(strings.SplitN(strings.TrimSpace(line), ";", 2)[0], 16, 64)
+		if err != nil {
+			return nil, err
+		}
+		if size == 0 {
+			return data, nil
+		}
+		chunk := make([]byte, size+2)
+		if _, err := io.ReadFull(br, chunk); err != nil {
+			return nil, err
+		}
+		data = append(data, chunk[:size]...)
+	}
+}
+
+func writeXML(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/xml")
+	xml.NewEncoder(w).Encode(v)
+}
+
+func writeS3Error(w http.ResponseWriter, status int, code string) {
+	w.Header().Set("Content-Type", "application/xml")
+	w.WriteHeader(status)
+	fmt.Fprintf(w, "<Error><Code>%s</Code><Message>%s</Message></Error>", code, code)
+}
+
+func etag(data []byte) string {
+	sum := md5.Sum(data)
+	return `"` + hex.EncodeToString(sum[:]) + `"`
+}
@@ -0,0 +1,75 @@
+package upload
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"strings"
+	"testing"
+)
+
+// 各存储后端共同的行为
+func testStorage(t *testing.T, s Storage) {
+	ctx := context.Background()
+	name := "images/ab/abcdef.png"
+
+	if _, err := s.Get(ctx, name); !errors.Is(err, ErrObjectNotExist) {
+		t.Fatalf("Get missing object err = %v, want ErrObjectNotExist", err)
+	}
+	if _, err := s.Stat(ctx, name); !errors.Is(err, ErrObjectNotExist) {
+		t.Fatalf("Stat missing object err = %v, want ErrObjectNotExist", err)
+	}
+
+	if err := s.Put(ctx, name, strings.NewReader("first"), 5, "image/png"); err != nil {
+		t.Fatalf("Put err: %v", err)
+	}
+	assertObject(t, s, name, "first")
+	info, err := s.Stat(ctx, name)
+	if err != nil {
+		t.Fatalf("Stat err: %v", err)
+	}
+	if info.Name != name || info.Size != 5 || info.ContentType != "image/png" || info.ModTime.IsZero() {
+		t.Errorf("Stat = %+v", info)
+	}
+
+	// 大小未知时同样可以保存，已存在时覆盖
+	if err := s.Put(ctx, name, strings.NewReader("second content"), -1, "image/png"); err != nil {
+		t.Fatalf("Put with unknown size err: %v", err)
+	}
+	assertObject(t, s, name, "second content")
+
+	moved := "images/cd/moved.png"
+	if err := s.Move(ctx, name, moved); err != nil {
+		t.Fatalf("Move err: %v", err)
+	}
+	assertObject(t, s, moved, "second content")
+	if _, err := s.Stat(ctx, name); !errors.Is(err, ErrObjectNotExist) {
+		t.Errorf("Stat moved source err = %v, want ErrObjectNotExist", err)
+	}
+
+	if err := s.Delete(ctx, moved); err != nil {
+		t.Fatalf("Delete err: %v", err)
+	}
+	if _, err := s.Get(ctx, moved); !errors.Is(err, ErrObjectNotExist) {
+		t.Errorf("Get deleted object err = %v, want ErrObjectNotExist", err)
+	}
+	if err := s.Delete(ctx, moved); err != nil {
+		t.Errorf("Delete missing object err: %v", err)
+	}
+}
+
+func assertObject(t *testing.T, s Storage, name, want string) {
+	t.Helper()
+	rc, err := s.Get(context.Background(), name)
+	if err != nil {
+		t.Fatalf("Get(%q) err: %v", name, err)
+	}
+	defer rc.Close()
+	got, err := ioutil.ReadAll(rc)
+	if err != nil {
+		t.Fatalf("read %q err: %v", name, err)
+	}
+	if string(got) != want {
+		t.Errorf("Get(%q) = %q, want %q", name, got, want)
+	}
+}
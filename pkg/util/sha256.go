@@ -0,0 +1,14 @@
+package util
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+)
+
+// 用于保存令牌等敏感信息的摘要，数据库中不保存原文
+func EncodeSHA256(value string) string {
+	m := sha256.New()
+	m.Write([]byte(value))
+
+	return hex.EncodeToString(m.Sum(nil))
+}
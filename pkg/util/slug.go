@@ -0,0 +1,53 @@
+package util
+
+import (
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// 别名中由标题转换而来部分的最大长度
+const slugMaxLength = 64
+
+// 由标题生成 URL 中使用的别名，只保留小写字母和数字，其余字符作为分隔符，带变音符号的拉丁字母去掉变音符号
+// 标题中有中文等无法转换为 ASCII 的文字时追加标题的短哈希，避免只有英文部分相同的标题生成相同的别名
+func Slugify(title string) string {
+	var b strings.Builder
+	dash, dropped := false, false
+	for _, r := range norm.NFD.String(strings.ToLower(title)) {
+		switch {
+		case unicode.Is(unicode.Mn, r):
+			continue
+		case r < unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r)):
+			if dash && b.Len() > 0 {
+				b.WriteByte('-')
+			}
+			b.WriteRune(r)
+			dash = false
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			dropped = true
+			dash = true
+		default:
+			dash = true
+		}
+	}
+
+	slug := b.String()
+	if len(slug) > slugMaxLength {
+		slug = slug[:slugMaxLength]
+		if i := strings.LastIndexByte(slug, '-'); i > 0 {
+			slug = slug[:i]
+		}
+		slug = strings.TrimRight(slug, "-")
+	}
+	if dropped || slug == "" {
+		hash := EncodeSHA256(title)[:8]
+		if slug == "" {
+			return hash
+		}
+		slug += "-" + hash
+	}
+
+	return slug
+}
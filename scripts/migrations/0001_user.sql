@@ -0,0 +1,18 @@
+-- 用户表，用户名唯一，注册时依赖唯一索引拒绝重复的用户名
+-- 已删除用户的用户名同样占用唯一索引，不能被重新注册
+CREATE TABLE `blog_user` (
+    `id` int(10) unsigned NOT NULL AUTO_INCREMENT,
+    `username` varchar(50) NOT NULL DEFAULT '' COMMENT '用户名',
+    `password` varchar(100) NOT NULL DEFAULT '' COMMENT 'bcrypt 密码哈希',
+    `nickname` varchar(50) NOT NULL DEFAULT '' COMMENT '昵称',
+    `role` varchar(20) NOT NULL DEFAULT 'reader' COMMENT '角色：admin、editor、author 或 reader',
+    `state` tinyint(3) unsigned NOT NULL DEFAULT '1' COMMENT '状态：0 禁用，1 启用',
+    `created_on` int(10) unsigned DEFAULT '0',
+    `created_by` varchar(100) DEFAULT '',
+    `modified_on` int(10) unsigned DEFAULT '0',
+    `modified_by` varchar(100) DEFAULT '',
+    `deleted_on` int(10) unsigned DEFAULT '0',
+    `is_del` tinyint(3) unsigned DEFAULT '0',
+    PRIMARY KEY (`id`),
+    UNIQUE KEY `uk_username` (`username`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='用户';
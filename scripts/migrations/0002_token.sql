@@ -0,0 +1,38 @@
+-- 刷新令牌表，只保存令牌的 SHA-256 摘要
+-- 轮换和吊销按 family_id 批量更新整个 family
+CREATE TABLE `blog_refresh_token` (
+    `id` int(10) unsigned NOT NULL AUTO_INCREMENT,
+    `user_id` int(10) unsigned NOT NULL DEFAULT '0' COMMENT '用户 ID',
+    `family_id` varchar(32) NOT NULL DEFAULT '' COMMENT '同一次登录轮换出的令牌共用的 family',
+    `token_hash` char(64) NOT NULL DEFAULT '' COMMENT '令牌的 SHA-256 摘要',
+    `expires_on` int(10) unsigned NOT NULL DEFAULT '0' COMMENT '过期时间',
+    `used_on` int(10) unsigned NOT NULL DEFAULT '0' COMMENT '被轮换的时间，0 表示未使用',
+    `revoked_on` int(10) unsigned NOT NULL DEFAULT '0' COMMENT '被吊销的时间，0 表示未吊销',
+    `created_on` int(10) unsigned DEFAULT '0',
+    `created_by` varchar(100) DEFAULT '',
+    `modified_on` int(10) unsigned DEFAULT '0',
+    `modified_by` varchar(100) DEFAULT '',
+    `deleted_on` int(10) unsigned DEFAULT '0',
+    `is_del` tinyint(3) unsigned DEFAULT '0',
+    PRIMARY KEY (`id`),
+    UNIQUE KEY `uk_token_hash` (`token_hash`),
+    KEY `idx_family_id` (`family_id`),
+    KEY `idx_user_id` (`user_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='刷新令牌';
+
+-- 已吊销的访问令牌，每次请求按 jti 查询
+-- 并发退出可能重复写入同一个 jti，因此只建普通索引
+CREATE TABLE `blog_revoked_token` (
+    `id` int(10) unsigned NOT NULL AUTO_INCREMENT,
+    `jti` varchar(32) NOT NULL DEFAULT '' COMMENT '访问令牌的 JWT ID',
+    `expires_on` int(10) unsigned NOT NULL DEFAULT '0' COMMENT '访问令牌的过期时间，过期后记录可以清理',
+    `created_on` int(10) unsigned DEFAULT '0',
+    `created_by` varchar(100) DEFAULT '',
+    `modified_on` int(10) unsigned DEFAULT '0',
+    `modified_by` varchar(100) DEFAULT '',
+    `deleted_on` int(10) unsigned DEFAULT '0',
+    `is_del` tinyint(3) unsigned DEFAULT '0',
+    PRIMARY KEY (`id`),
+    KEY `idx_jti` (`jti`),
+    KEY `idx_expires_on` (`expires_on`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='已吊销的访问令牌';
@@ -0,0 +1,24 @@
+-- 上传文件表，文件按内容的 SHA-256 保存，相同内容的记录共用同一个 path
+-- 每个上传者对相同内容只有一条文件记录，并发上传相同内容时依赖唯一索引去重
+-- deleted_on 使软删除的记录不影响重新上传
+CREATE TABLE `blog_file` (
+    `id` int(10) unsigned NOT NULL AUTO_INCREMENT,
+    `hash` char(64) NOT NULL DEFAULT '' COMMENT '内容的 SHA-256',
+    `path` varchar(255) NOT NULL DEFAULT '' COMMENT '在存储后端中的文件名',
+    `original_name` varchar(255) NOT NULL DEFAULT '' COMMENT '上传时的文件名',
+    `mime_type` varchar(100) NOT NULL DEFAULT '' COMMENT '按内容识别的类型',
+    `size` bigint(20) NOT NULL DEFAULT '0' COMMENT '文件大小',
+    `ref_count` int(10) unsigned NOT NULL DEFAULT '0' COMMENT '引用文件的文章和历史版本数量',
+    `uploader_id` int(10) unsigned NOT NULL DEFAULT '0' COMMENT '上传者 ID',
+    `orphaned_on` int(10) unsigned NOT NULL DEFAULT '0' COMMENT '首次发现没有被引用的时间，0 表示正在使用',
+    `private` tinyint(3) unsigned NOT NULL DEFAULT '0' COMMENT '为 1 时只能通过签名地址访问',
+    `created_on` int(10) unsigned DEFAULT '0',
+    `created_by` varchar(100) DEFAULT '',
+    `modified_on` int(10) unsigned DEFAULT '0',
+    `modified_by` varchar(100) DEFAULT '',
+    `deleted_on` int(10) unsigned DEFAULT '0',
+    `is_del` tinyint(3) unsigned DEFAULT '0',
+    PRIMARY KEY (`id`),
+    UNIQUE KEY `uk_hash_owner` (`hash`, `private`, `uploader_id`, `deleted_on`),
+    KEY `idx_path` (`path`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='上传文件';
@@ -0,0 +1,41 @@
+-- 分片上传会话表，upload_id 为对外使用的随机 ID
+CREATE TABLE `blog_upload_session` (
+    `id` int(10) unsigned NOT NULL AUTO_INCREMENT,
+    `upload_id` varchar(32) NOT NULL DEFAULT '' COMMENT '对外使用的随机 ID',
+    `file_type` int(10) NOT NULL DEFAULT '0' COMMENT '文件类型',
+    `file_name` varchar(255) NOT NULL DEFAULT '' COMMENT '上传时的文件名',
+    `size` bigint(20) NOT NULL DEFAULT '0' COMMENT '文件大小',
+    `chunk_size` bigint(20) NOT NULL DEFAULT '0' COMMENT '每个分片的大小，tus 上传时为 0',
+    `hash` varchar(64) NOT NULL DEFAULT '' COMMENT '客户端提供的 SHA-256，为空时不校验',
+    `uploader_id` int(10) unsigned NOT NULL DEFAULT '0' COMMENT '上传者 ID',
+    `expires_on` int(10) unsigned NOT NULL DEFAULT '0' COMMENT '过期时间',
+    `file_id` int(10) unsigned NOT NULL DEFAULT '0' COMMENT '合并完成后生成的文件',
+    `private` tinyint(3) unsigned NOT NULL DEFAULT '0' COMMENT '合并后的文件是否为私有文件',
+    `state` tinyint(3) unsigned NOT NULL DEFAULT '0' COMMENT '状态：0 上传中，1 已完成',
+    `created_on` int(10) unsigned DEFAULT '0',
+    `created_by` varchar(100) DEFAULT '',
+    `modified_on` int(10) unsigned DEFAULT '0',
+    `modified_by` varchar(100) DEFAULT '',
+    `deleted_on` int(10) unsigned DEFAULT '0',
+    `is_del` tinyint(3) unsigned DEFAULT '0',
+    PRIMARY KEY (`id`),
+    UNIQUE KEY `uk_upload_id` (`upload_id`),
+    KEY `idx_expires_on` (`expires_on`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='分片上传会话';
+
+-- 已接收的分片，同一位置重复上传时替换原有记录
+CREATE TABLE `blog_upload_chunk` (
+    `id` int(10) unsigned NOT NULL AUTO_INCREMENT,
+    `session_id` int(10) unsigned NOT NULL DEFAULT '0' COMMENT '上传会话 ID',
+    `start` bigint(20) NOT NULL DEFAULT '0' COMMENT '分片在文件中的起始偏移',
+    `size` bigint(20) NOT NULL DEFAULT '0' COMMENT '分片大小',
+    `hash` char(64) NOT NULL DEFAULT '' COMMENT '分片内容的 SHA-256',
+    `created_on` int(10) unsigned DEFAULT '0',
+    `created_by` varchar(100) DEFAULT '',
+    `modified_on` int(10) unsigned DEFAULT '0',
+    `modified_by` varchar(100) DEFAULT '',
+    `deleted_on` int(10) unsigned DEFAULT '0',
+    `is_del` tinyint(3) unsigned DEFAULT '0',
+    PRIMARY KEY (`id`),
+    UNIQUE KEY `uk_session_start` (`session_id`, `start`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='上传分片';
@@ -0,0 +1,31 @@
+-- 上传用量的计数，与文件记录和上传会话在同一个事务中更新
+-- 计数可能因释放而暂时减少，使用有符号整数
+CREATE TABLE `blog_upload_usage` (
+    `id` int(10) unsigned NOT NULL AUTO_INCREMENT,
+    `owner` varchar(100) NOT NULL DEFAULT '' COMMENT 'user:{用户 ID}，或整个服务的合计 total',
+    `size` bigint(20) NOT NULL DEFAULT '0' COMMENT '已保存文件及其衍生图的总大小',
+    `files` int(11) NOT NULL DEFAULT '0' COMMENT '已保存文件的数量',
+    `staged_size` bigint(20) NOT NULL DEFAULT '0' COMMENT '未完成的分片上传预留的大小',
+    `created_on` int(10) unsigned DEFAULT '0',
+    `created_by` varchar(100) DEFAULT '',
+    `modified_on` int(10) unsigned DEFAULT '0',
+    `modified_by` varchar(100) DEFAULT '',
+    `deleted_on` int(10) unsigned DEFAULT '0',
+    `is_del` tinyint(3) unsigned DEFAULT '0',
+    PRIMARY KEY (`id`),
+    UNIQUE KEY `uk_owner` (`owner`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='上传用量';
+
+ALTER TABLE `blog_file`
+    ADD COLUMN `derivative_size` bigint(20) NOT NULL DEFAULT '0' COMMENT '衍生图的总大小' AFTER `size`;
+
+ALTER TABLE `blog_upload_session`
+    ADD COLUMN `reserved_size` bigint(20) NOT NULL DEFAULT '0' COMMENT '在用量中预留的大小' AFTER `state`;
+
+-- 按已有的文件初始化计数，已有的衍生图没有记录大小，不计入
+INSERT INTO `blog_upload_usage` (`owner`, `size`, `files`, `created_on`, `modified_on`)
+SELECT CONCAT('user:', `uploader_id`), SUM(`size`), COUNT(*), UNIX_TIMESTAMP(), UNIX_TIMESTAMP()
+FROM `blog_file` WHERE `is_del` = 0 GROUP BY `uploader_id`;
+INSERT INTO `blog_upload_usage` (`owner`, `size`, `files`, `created_on`, `modified_on`)
+SELECT 'total', IFNULL(SUM(`size`), 0), COUNT(*), UNIX_TIMESTAMP(), UNIX_TIMESTAMP()
+FROM `blog_file` WHERE `is_del` = 0;
@@ -0,0 +1,18 @@
+-- 文章状态变更记录，created_by 和 created_on 为操作人和操作时间
+-- blog_article.state 沿用原有字段：0 草稿，1 已发布，2 审核中，3 已归档
+CREATE TABLE `blog_article_transition` (
+    `id` int(10) unsigned NOT NULL AUTO_INCREMENT,
+    `article_id` int(10) unsigned NOT NULL DEFAULT '0' COMMENT '文章 ID',
+    `from_state` tinyint(3) unsigned NOT NULL DEFAULT '0' COMMENT '变更前的状态',
+    `to_state` tinyint(3) unsigned NOT NULL DEFAULT '0' COMMENT '变更后的状态',
+    `operator_id` int(10) unsigned NOT NULL DEFAULT '0' COMMENT '操作人 ID',
+    `comment` varchar(255) NOT NULL DEFAULT '' COMMENT '审核意见等说明',
+    `created_on` int(10) unsigned DEFAULT '0',
+    `created_by` varchar(100) DEFAULT '',
+    `modified_on` int(10) unsigned DEFAULT '0',
+    `modified_by` varchar(100) DEFAULT '',
+    `deleted_on` int(10) unsigned DEFAULT '0',
+    `is_del` tinyint(3) unsigned DEFAULT '0',
+    PRIMARY KEY (`id`),
+    KEY `idx_article_id` (`article_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='文章状态变更记录';
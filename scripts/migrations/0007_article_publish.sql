@@ -0,0 +1,25 @@
+-- 后台任务的租约，多个实例同时运行时只有持有租约的实例执行任务
+-- name 唯一，第一次使用时并发创建只有一个实例成功
+CREATE TABLE `blog_lease` (
+    `id` int(10) unsigned NOT NULL AUTO_INCREMENT,
+    `name` varchar(100) NOT NULL DEFAULT '' COMMENT '任务名称',
+    `holder` varchar(100) NOT NULL DEFAULT '' COMMENT '持有租约的实例',
+    `expires_on` int(10) unsigned NOT NULL DEFAULT '0' COMMENT '租约的过期时间',
+    `created_on` int(10) unsigned DEFAULT '0',
+    `created_by` varchar(100) DEFAULT '',
+    `modified_on` int(10) unsigned DEFAULT '0',
+    `modified_by` varchar(100) DEFAULT '',
+    `deleted_on` int(10) unsigned DEFAULT '0',
+    `is_del` tinyint(3) unsigned DEFAULT '0',
+    PRIMARY KEY (`id`),
+    UNIQUE KEY `uk_name` (`name`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='后台任务租约';
+
+-- 文章的发布时间，审核中的文章设置了未来的时间时到期自动发布
+-- 列表按 (state, publish_on) 排序，定时发布按 (state, publish_on) 查询到期的文章
+ALTER TABLE `blog_article`
+    ADD COLUMN `publish_on` int(10) unsigned NOT NULL DEFAULT '0' COMMENT '发布时间' AFTER `state`,
+    ADD KEY `idx_state_publish_on` (`state`, `publish_on`);
+
+-- 已发布的文章没有记录发布时间，以创建时间代替
+UPDATE `blog_article` SET `publish_on` = `created_on` WHERE `state` = 1;
@@ -0,0 +1,3 @@
+-- 设置定时发布时记录审核人和计划的发布时间，文章状态不变
+ALTER TABLE `blog_article_transition`
+    ADD COLUMN `publish_on` int(10) unsigned NOT NULL DEFAULT '0' COMMENT '定时发布的计划发布时间' AFTER `comment`;
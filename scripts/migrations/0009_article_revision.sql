@@ -0,0 +1,21 @@
+-- 文章的历史版本，每次修改标题、简述、内容或封面后保存一份快照
+-- 已有的文章没有历史版本，第一次修改时以修改前的内容补记为版本 1
+-- 同一篇文章的版本号唯一，修改文章时锁定文章行分配版本号，唯一索引防止并发写入重复的版本号
+CREATE TABLE `blog_article_revision` (
+    `id` int(10) unsigned NOT NULL AUTO_INCREMENT,
+    `article_id` int(10) unsigned NOT NULL DEFAULT '0' COMMENT '文章 ID',
+    `revision` int(10) unsigned NOT NULL DEFAULT '0' COMMENT '同一篇文章内从 1 开始递增的版本号',
+    `title` varchar(100) DEFAULT '' COMMENT '文章标题',
+    `desc` varchar(255) DEFAULT '' COMMENT '文章简述',
+    `content` longtext COMMENT '文章内容',
+    `cover_image_url` varchar(255) DEFAULT '' COMMENT '封面图片地址',
+    `restored_from` int(10) unsigned NOT NULL DEFAULT '0' COMMENT '由哪个版本恢复而来，0 表示普通修改',
+    `created_on` int(10) unsigned DEFAULT '0',
+    `created_by` varchar(100) DEFAULT '',
+    `modified_on` int(10) unsigned DEFAULT '0',
+    `modified_by` varchar(100) DEFAULT '',
+    `deleted_on` int(10) unsigned DEFAULT '0',
+    `is_del` tinyint(3) unsigned DEFAULT '0',
+    PRIMARY KEY (`id`),
+    UNIQUE KEY `uk_article_revision` (`article_id`, `revision`, `deleted_on`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='文章历史版本';
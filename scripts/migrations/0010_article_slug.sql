@@ -0,0 +1,20 @@
+-- 文章的别名和旧别名，别名在所有文章（包括已删除的文章）中唯一
+-- 已有文章的别名为 NULL，唯一索引允许多个 NULL，服务启动时为其生成别名，不修改 modified_on
+ALTER TABLE `blog_article`
+    ADD COLUMN `slug` varchar(100) DEFAULT NULL COMMENT 'URL 中使用的别名' AFTER `title`,
+    ADD UNIQUE KEY `uk_slug` (`slug`);
+
+CREATE TABLE `blog_article_slug` (
+    `id` int(10) unsigned NOT NULL AUTO_INCREMENT,
+    `slug` varchar(100) NOT NULL DEFAULT '' COMMENT '文章使用过的旧别名',
+    `article_id` int(10) unsigned NOT NULL DEFAULT '0',
+    `created_on` int(10) unsigned DEFAULT '0',
+    `created_by` varchar(100) DEFAULT '',
+    `modified_on` int(10) unsigned DEFAULT '0',
+    `modified_by` varchar(100) DEFAULT '',
+    `deleted_on` int(10) unsigned DEFAULT '0',
+    `is_del` tinyint(3) unsigned DEFAULT '0',
+    PRIMARY KEY (`id`),
+    UNIQUE KEY `uk_slug` (`slug`),
+    KEY `idx_article_id` (`article_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='文章的旧别名';